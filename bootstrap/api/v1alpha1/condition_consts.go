@@ -39,10 +39,27 @@ const (
 	//
 	// NOTE: Having the cluster infrastructure ready is a pre-condition for starting to create machines;
 	WaitingForClusterInfrastructureReason string = "WaitingForClusterInfrastructure"
+
+	// FileContentNotFoundReason (Severity=Warning) documents a bootstrap secret generation process
+	// waiting for a Secret or ConfigMap referenced by a file ContentFrom field, or for the referenced key in it.
+	FileContentNotFoundReason string = "FileContentNotFound"
+
+	// PrivateRegistrySecretInvalidReason (Severity=Warning) documents a bootstrap secret generation process
+	// failing because a TLS or auth Secret of the private registries configuration is missing, or is missing entries.
+	PrivateRegistrySecretInvalidReason string = "PrivateRegistrySecretInvalid"
 )
 
 const (
 	CertificatesAvailableCondition clusterv1.ConditionType = "CertificatesAvailable"
 
 	CertificatesGenerationFailedReason string = "CertificateGenerationFailed"
+
+	// CertificatesInvalidReason (Severity=Error) documents a certificate provided by the user, e.g. an intermediate CA
+	// issued by an enterprise PKI, that can't be used: the private key does not match, the certificate is not a CA
+	// allowed to sign certificates, or it is expired.
+	CertificatesInvalidReason string = "CertificatesInvalid"
+
+	// CertificatesCorruptedReason (Severity=Error) documents a RKE2Config controller failing to read the cluster
+	// certificates stored as secrets, e.g. because a secret is badly formatted.
+	CertificatesCorruptedReason string = "CertificatesCorrupted"
 )
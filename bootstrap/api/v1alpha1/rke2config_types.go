@@ -43,6 +43,11 @@ type RKE2ConfigSpec struct {
 	// PrivateRegistriesConfig defines the containerd configuration for private registries and local registry mirrors.
 	//+optional
 	PrivateRegistriesConfig Registry `json:"privateRegistriesConfig,omitempty"`
+
+	// Format specifies the output format of the bootstrap data, either cloud-config or ignition (default: cloud-config).
+	// +kubebuilder:validation:Enum=cloud-config;ignition
+	//+optional
+	Format Format `json:"format,omitempty"`
 }
 
 // RKE2CommonNodeConfig describes some attributes that are common to agent and server nodes
@@ -59,7 +64,9 @@ type RKE2AgentConfig struct {
 	//+optional
 	NodeTaints []string `json:"nodeTaints,omitempty"`
 
-	// NodeNamePrefix Prefix to the Node Name that CAPI will generate.
+	// NodeNamePrefix Prefix to the Node Name that CAPI will generate, the Node Name being the prefix followed by the Machine name.
+	// A cloud-init jinja expression, e.g. "{{ ds.meta_data.local_hostname }}", is used as the Node Name instead,
+	// which is not supported with the ignition format.
 	//+optional
 	NodeNamePrefix string `json:"nodeName,omitempty"`
 
@@ -208,6 +215,17 @@ const (
 	CIS1_23 CISProfile = "cis-1.23"
 )
 
+// Format specifies the output format of the bootstrap data.
+type Format string
+
+const (
+	// CloudConfig make the bootstrap data to be of cloud-config format.
+	CloudConfig Format = "cloud-config"
+
+	// Ignition make the bootstrap data to be of Ignition format.
+	Ignition Format = "ignition"
+)
+
 // Encoding specifies the cloud-init file encoding.
 type Encoding string
 
@@ -252,7 +270,12 @@ type File struct {
 // sources of data for target systems should add them here.
 type FileSource struct {
 	// Secret represents a secret that should populate this file.
-	Secret SecretFileSource `json:"secret"`
+	//+optional
+	Secret *SecretFileSource `json:"secret,omitempty"`
+
+	// ConfigMap represents a config map that should populate this file.
+	//+optional
+	ConfigMap *ConfigMapFileSource `json:"configMap,omitempty"`
 }
 
 // Adapts a Secret into a FileSource.
@@ -267,6 +290,18 @@ type SecretFileSource struct {
 	Key string `json:"key"`
 }
 
+// Adapts a ConfigMap into a FileSource.
+//
+// The contents of the target ConfigMap's Data field will be presented
+// as files using the keys in the Data field as the file names.
+type ConfigMapFileSource struct {
+	// Name of the config map in the RKE2BootstrapConfig's namespace to use.
+	Name string `json:"name"`
+
+	// Key is the key in the config map's data map for this value.
+	Key string `json:"key"`
+}
+
 // Registry is registry settings including mirrors, TLS, and credentials.
 type Registry struct {
 	// Mirrors are namespace to mirror mapping for all namespaces.
@@ -298,7 +333,9 @@ type Mirror struct {
 // RegistryConfig contains configuration used to communicate with the registry.
 type RegistryConfig struct {
 	// Auth si a reference to a Secret containing information to authenticate to the registry.
-	// The Secret must provite a username and a password data entry.
+	// The Secret must provite a username and a password data entry, or an identity-token data entry.
+	// A Secret of type `kubernetes.io/dockerconfigjson` may be used instead, its auths entry matching the registry host being used.
+	// No authentication is configured when unset.
 	//+optional
 	AuthSecret corev1.ObjectReference `json:"authSecret,omitempty"`
 	// TLS is a pair of CA/Cert/Key which then are used when creating the transport
@@ -311,6 +348,7 @@ type RegistryConfig struct {
 type TLSConfig struct {
 	// TLSConfigSecret is a reference to a secret of type `kubernetes.io/tls` thich has up to 3 entries: tls.crt, tls.key and ca.crt
 	// which describe the TLS configuration necessary to connect to the registry.
+	// The ca.crt entry may be provided alone to only trust the registry CA, tls.crt and tls.key must be provided together.
 	// +optional
 	TLSConfigSecret corev1.ObjectReference `json:"tlsConfigSecret,omitempty"`
 
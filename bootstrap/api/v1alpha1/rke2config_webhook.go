@@ -17,7 +17,11 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -50,14 +54,14 @@ var _ webhook.Validator = &RKE2Config{}
 func (r *RKE2Config) ValidateCreate() error {
 	rke2configlog.Info("validate create", "name", r.Name)
 
-	return nil
+	return r.validate()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *RKE2Config) ValidateUpdate(old runtime.Object) error {
 	rke2configlog.Info("validate update", "name", r.Name)
 
-	return nil
+	return r.validate()
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -66,3 +70,68 @@ func (r *RKE2Config) ValidateDelete() error {
 
 	return nil
 }
+
+func (r *RKE2Config) validate() error {
+	allErrs := ValidateRKE2ConfigSpec(field.NewPath("spec"), &r.Spec)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("RKE2Config").GroupKind(), r.Name, allErrs)
+}
+
+// ValidateRKE2ConfigSpec validates a RKE2ConfigSpec, with pathPrefix being the path of the spec in the validated object.
+func ValidateRKE2ConfigSpec(pathPrefix *field.Path, spec *RKE2ConfigSpec) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, file := range spec.Files {
+		if file.ContentFrom == nil {
+			continue
+		}
+		if file.Content != "" {
+			allErrs = append(allErrs, field.Invalid(pathPrefix.Child("files").Index(i), file, "only one of content or contentFrom may be specified"))
+		}
+		allErrs = append(allErrs, validateFileSource(pathPrefix.Child("files").Index(i).Child("contentFrom"), file.ContentFrom)...)
+	}
+
+	// Jinja expressions are rendered by cloud-init, they would be used verbatim as the node name with ignition.
+	if spec.Format == Ignition && IsJinjaExpression(spec.AgentConfig.NodeNamePrefix) {
+		allErrs = append(allErrs, field.Invalid(pathPrefix.Child("agentConfig", "nodeName"), spec.AgentConfig.NodeNamePrefix,
+			"jinja expressions are only supported with the cloud-config format"))
+	}
+
+	return allErrs
+}
+
+// IsJinjaExpression returns true if the string contains a jinja expression, rendered by cloud-init on the node.
+func IsJinjaExpression(s string) bool {
+	return strings.Contains(s, "{{") && strings.Contains(s, "}}")
+}
+
+func validateFileSource(path *field.Path, source *FileSource) field.ErrorList {
+	var allErrs field.ErrorList
+
+	sources := 0
+	if source.Secret != nil {
+		sources++
+		if source.Secret.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("secret", "name"), "secret name must be set"))
+		}
+		if source.Secret.Key == "" {
+			allErrs = append(allErrs, field.Required(path.Child("secret", "key"), "secret key must be set"))
+		}
+	}
+	if source.ConfigMap != nil {
+		sources++
+		if source.ConfigMap.Name == "" {
+			allErrs = append(allErrs, field.Required(path.Child("configMap", "name"), "config map name must be set"))
+		}
+		if source.ConfigMap.Key == "" {
+			allErrs = append(allErrs, field.Required(path.Child("configMap", "key"), "config map key must be set"))
+		}
+	}
+	if sources != 1 {
+		allErrs = append(allErrs, field.Invalid(path, source, "exactly one of secret or configMap must be specified"))
+	}
+
+	return allErrs
+}
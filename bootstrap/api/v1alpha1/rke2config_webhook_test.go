@@ -0,0 +1,87 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("RKE2Config files validation", func() {
+	withFiles := func(files ...File) *RKE2Config {
+		return &RKE2Config{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"},
+			Spec:       RKE2ConfigSpec{Files: files},
+		}
+	}
+
+	secret := &SecretFileSource{Name: "files", Key: "config"}
+	configMap := &ConfigMapFileSource{Name: "files", Key: "config"}
+
+	It("should accept files with a single content source", func() {
+		Expect(withFiles(
+			File{Path: "/etc/inline", Content: "content"},
+			File{Path: "/etc/secret", ContentFrom: &FileSource{Secret: secret}},
+			File{Path: "/etc/config-map", ContentFrom: &FileSource{ConfigMap: configMap}},
+		).ValidateCreate()).To(Succeed())
+	})
+
+	It("should reject files without exactly one content source", func() {
+		for _, file := range []File{
+			{Path: "/etc/both", ContentFrom: &FileSource{Secret: secret, ConfigMap: configMap}},
+			{Path: "/etc/none", ContentFrom: &FileSource{}},
+			{Path: "/etc/content", Content: "content", ContentFrom: &FileSource{ConfigMap: configMap}},
+		} {
+			err := withFiles(file).ValidateCreate()
+			Expect(apierrors.IsInvalid(err)).To(BeTrue(), file.Path)
+			Expect(withFiles(file).ValidateUpdate(withFiles())).ToNot(Succeed(), file.Path)
+		}
+
+		err := withFiles(File{Path: "/etc/both", ContentFrom: &FileSource{Secret: secret, ConfigMap: configMap}}).ValidateCreate()
+		Expect(err).To(MatchError(ContainSubstring("exactly one of secret or configMap must be specified")))
+	})
+
+	It("should reject incomplete content sources", func() {
+		err := withFiles(File{Path: "/etc/config-map", ContentFrom: &FileSource{ConfigMap: &ConfigMapFileSource{Name: "files"}}}).ValidateCreate()
+		Expect(err).To(MatchError(ContainSubstring("spec.files[0].contentFrom.configMap.key")))
+		err = withFiles(File{Path: "/etc/secret", ContentFrom: &FileSource{Secret: &SecretFileSource{Key: "config"}}}).ValidateCreate()
+		Expect(err).To(MatchError(ContainSubstring("spec.files[0].contentFrom.secret.name")))
+	})
+})
+
+var _ = Describe("RKE2Config node name validation", func() {
+	withNodeName := func(format Format, nodeName string) *RKE2Config {
+		return &RKE2Config{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"},
+			Spec: RKE2ConfigSpec{
+				Format:      format,
+				AgentConfig: RKE2AgentConfig{NodeNamePrefix: nodeName},
+			},
+		}
+	}
+
+	It("should only accept jinja expressions with cloud-config", func() {
+		Expect(withNodeName("", "{{ ds.meta_data.local_hostname }}").ValidateCreate()).To(Succeed())
+		Expect(withNodeName(CloudConfig, "{{ ds.meta_data.local_hostname }}").ValidateCreate()).To(Succeed())
+		Expect(withNodeName(Ignition, "prefix-").ValidateCreate()).To(Succeed())
+
+		err := withNodeName(Ignition, "{{ ds.meta_data.local_hostname }}").ValidateCreate()
+		Expect(err).To(MatchError(ContainSubstring("spec.agentConfig.nodeName")))
+	})
+})
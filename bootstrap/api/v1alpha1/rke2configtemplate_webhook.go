@@ -17,7 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -50,14 +52,14 @@ var _ webhook.Validator = &RKE2ConfigTemplate{}
 func (r *RKE2ConfigTemplate) ValidateCreate() error {
 	RKE2configtemplatelog.Info("validate create", "name", r.Name)
 
-	return nil
+	return r.validate()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *RKE2ConfigTemplate) ValidateUpdate(old runtime.Object) error {
 	RKE2configtemplatelog.Info("validate update", "name", r.Name)
 
-	return nil
+	return r.validate()
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -66,3 +68,11 @@ func (r *RKE2ConfigTemplate) ValidateDelete() error {
 
 	return nil
 }
+
+func (r *RKE2ConfigTemplate) validate() error {
+	allErrs := ValidateRKE2ConfigSpec(field.NewPath("spec", "template", "spec"), &r.Spec.Template.Spec)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("RKE2ConfigTemplate").GroupKind(), r.Name, allErrs)
+}
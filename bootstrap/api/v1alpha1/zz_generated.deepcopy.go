@@ -61,13 +61,28 @@ func (in *ComponentConfig) DeepCopy() *ComponentConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapFileSource) DeepCopyInto(out *ConfigMapFileSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapFileSource.
+func (in *ConfigMapFileSource) DeepCopy() *ConfigMapFileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapFileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *File) DeepCopyInto(out *File) {
 	*out = *in
 	if in.ContentFrom != nil {
 		in, out := &in.ContentFrom, &out.ContentFrom
 		*out = new(FileSource)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -84,7 +99,16 @@ func (in *File) DeepCopy() *File {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FileSource) DeepCopyInto(out *FileSource) {
 	*out = *in
-	out.Secret = in.Secret
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(SecretFileSource)
+		**out = **in
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ConfigMapFileSource)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileSource.
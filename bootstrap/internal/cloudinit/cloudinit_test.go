@@ -19,6 +19,8 @@ package cloudinit
 import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/secret"
 )
 
 var _ = Describe("WorkerAirGappedCloudInitTest", func() {
@@ -120,3 +122,30 @@ runcmd:
 `))
 	})
 })
+
+var _ = Describe("ControlPlaneJoinCertificatesTest", func() {
+	var input *ControlPlaneInput
+
+	BeforeEach(func() {
+		certificates := secret.NewCertificatesForInitialControlPlane()
+		Expect(certificates.Generate()).To(Succeed())
+		// A CA generated by RKE2 itself, not stored in the management cluster.
+		certificates.GetByPurpose(secret.RequestHeaderCA).KeyPair = nil
+
+		input = &ControlPlaneInput{
+			BaseUserData: BaseUserData{RKE2Version: "v1.25.6+rke2r1"},
+			Certificates: certificates,
+		}
+	})
+
+	It("Should write the cluster CAs available on joining servers", func() {
+		userData, err := NewJoinControlPlane(input)
+		Expect(err).ToNot(HaveOccurred())
+
+		userDataString := string(userData)
+		Expect(userDataString).To(ContainSubstring("path: /var/lib/rancher/rke2/server/tls/server-ca.key"))
+		Expect(userDataString).To(ContainSubstring("path: /var/lib/rancher/rke2/server/tls/etcd/peer-ca.crt"))
+		Expect(userDataString).To(ContainSubstring("path: /var/lib/rancher/rke2/server/tls/service.key"))
+		Expect(userDataString).ToNot(ContainSubstring("request-header-ca"))
+	})
+})
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"time"
 
@@ -32,7 +33,9 @@ import (
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	kubeyaml "sigs.k8s.io/yaml"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -43,6 +46,7 @@ import (
 
 	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
 	"github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/internal/cloudinit"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/internal/ignition"
 	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
 	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/locking"
 	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
@@ -62,16 +66,29 @@ type RKE2ConfigReconciler struct {
 	RKE2InitLock RKE2InitLock
 	client.Client
 	Scheme *runtime.Scheme
+	// InitLockTTL is how long the first control plane machine has to get a node before another one can initialize the cluster.
+	InitLockTTL time.Duration
 }
 
 const (
 	DefaultManifestDirectory string = "/var/lib/rancher/rke2/server/manifests"
+
+	// fileSecretNameField indexes the RKE2Configs by the Secrets referenced in their files ContentFrom field.
+	fileSecretNameField string = "spec.files.contentFrom.secret.name"
+	// fileConfigMapNameField indexes the RKE2Configs by the ConfigMaps referenced in their files ContentFrom field.
+	fileConfigMapNameField string = "spec.files.contentFrom.configMap.name"
 )
 
+// fileContentNotFoundError is returned when the Secret, ConfigMap or key referenced by a file does not exist.
+type fileContentNotFoundError struct {
+	error
+}
+
 //+kubebuilder:rbac:groups=bootstrap.cluster.x-k8s.io,resources=rke2configs;rke2configs/status;rke2configs/finalizers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=rke2controlplanes;rke2controlplanes/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status;machinesets;machines;machines/status;machinepools;machinepools/status,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets;events;configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -247,13 +264,117 @@ type Scope struct {
 func (r *RKE2ConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	if r.RKE2InitLock == nil {
-		r.RKE2InitLock = locking.NewControlPlaneInitMutex(mgr.GetClient())
+		if r.InitLockTTL == 0 {
+			r.InitLockTTL = locking.DefaultInitLockTTL
+		}
+		r.RKE2InitLock = locking.NewControlPlaneInitLease(mgr.GetClient(), mgr.GetEventRecorderFor("rke2-bootstrap-controller"), r.InitLockTTL)
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &bootstrapv1.RKE2Config{}, fileSecretNameField,
+		fileSourceNames(func(source *bootstrapv1.FileSource) string {
+			if source.Secret == nil {
+				return ""
+			}
+			return source.Secret.Name
+		})); err != nil {
+		return errors.Wrap(err, "failed to index RKE2Configs by file secret")
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &bootstrapv1.RKE2Config{}, fileConfigMapNameField,
+		fileSourceNames(func(source *bootstrapv1.FileSource) string {
+			if source.ConfigMap == nil {
+				return ""
+			}
+			return source.ConfigMap.Name
+		})); err != nil {
+		return errors.Wrap(err, "failed to index RKE2Configs by file config map")
 	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&bootstrapv1.RKE2Config{}).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.secretToRKE2Configs),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.configMapToRKE2Configs),
+		).
 		Complete(r)
 }
 
+// secretToRKE2Configs maps a Secret to the RKE2Configs, not yet rendered, which reference it in a file ContentFrom field.
+func (r *RKE2ConfigReconciler) secretToRKE2Configs(o client.Object) []ctrl.Request {
+	s, ok := o.(*corev1.Secret)
+	if !ok {
+		ctrl.Log.Error(nil, fmt.Sprintf("Expected a Secret but got a %T", o))
+		return nil
+	}
+
+	return r.fileSourceToRKE2Configs(s.Namespace, fileSecretNameField, s.Name, func(source *bootstrapv1.FileSource) bool {
+		return source.Secret != nil && source.Secret.Name == s.Name
+	})
+}
+
+// configMapToRKE2Configs maps a ConfigMap to the RKE2Configs, not yet rendered, which reference it in a file ContentFrom field.
+func (r *RKE2ConfigReconciler) configMapToRKE2Configs(o client.Object) []ctrl.Request {
+	cm, ok := o.(*corev1.ConfigMap)
+	if !ok {
+		ctrl.Log.Error(nil, fmt.Sprintf("Expected a ConfigMap but got a %T", o))
+		return nil
+	}
+
+	return r.fileSourceToRKE2Configs(cm.Namespace, fileConfigMapNameField, cm.Name, func(source *bootstrapv1.FileSource) bool {
+		return source.ConfigMap != nil && source.ConfigMap.Name == cm.Name
+	})
+}
+
+// fileSourceToRKE2Configs returns the RKE2Configs of the namespace, not yet rendered, with a file source matching the given function.
+// The RKE2Configs are listed with the given index field, so only the ones referencing the source are considered.
+func (r *RKE2ConfigReconciler) fileSourceToRKE2Configs(namespace, field, name string, matches func(*bootstrapv1.FileSource) bool) []ctrl.Request {
+	configs := &bootstrapv1.RKE2ConfigList{}
+	if err := r.Client.List(context.TODO(), configs, client.InNamespace(namespace), client.MatchingFields{field: name}); err != nil {
+		ctrl.Log.Error(err, "Failed to list RKE2Configs", "namespace", namespace)
+		return nil
+	}
+
+	requests := []ctrl.Request{}
+	for _, config := range configs.Items {
+		if config.Status.Ready {
+			continue
+		}
+		for _, file := range config.Spec.Files {
+			if file.ContentFrom != nil && matches(file.ContentFrom) {
+				requests = append(requests, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&config)})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// fileSourceNames returns an index function, indexing the RKE2Configs by the names returned by the given function
+// for the sources of their files.
+func fileSourceNames(name func(*bootstrapv1.FileSource) string) client.IndexerFunc {
+	return func(o client.Object) []string {
+		config, ok := o.(*bootstrapv1.RKE2Config)
+		if !ok {
+			return nil
+		}
+
+		names := []string{}
+		for _, file := range config.Spec.Files {
+			if file.ContentFrom == nil {
+				continue
+			}
+			if n := name(file.ContentFrom); n != "" {
+				names = append(names, n)
+			}
+		}
+		return names
+	}
+}
+
 // TODO: Implement these functions
 
 // handleClusterNotInitialized handles the first control plane node
@@ -284,6 +405,10 @@ func (r *RKE2ConfigReconciler) handleClusterNotInitialized(ctx context.Context,
 		util.ObjectKey(scope.Cluster),
 		*metav1.NewControllerRef(scope.Config, bootstrapv1.GroupVersion.WithKind("RKE2Config")),
 	); err != nil {
+		if errors.Is(err, secret.ErrInvalidExternalCertificate) {
+			conditions.MarkFalse(scope.Config, bootstrapv1.CertificatesAvailableCondition, bootstrapv1.CertificatesInvalidReason, clusterv1.ConditionSeverityError, err.Error())
+			return ctrl.Result{}, err
+		}
 		conditions.MarkFalse(scope.Config, bootstrapv1.CertificatesAvailableCondition, bootstrapv1.CertificatesGenerationFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
 		return ctrl.Result{}, err
 	}
@@ -304,6 +429,7 @@ func (r *RKE2ConfigReconciler) handleClusterNotInitialized(ctx context.Context,
 			ServerURL:            fmt.Sprintf(serverURLFormat, scope.Cluster.Spec.ControlPlaneEndpoint.Host, registrationPort),
 			ServerConfig:         scope.ControlPlane.Spec.ServerConfig,
 			AgentConfig:          scope.Config.Spec.AgentConfig,
+			MachineName:          scope.Machine.Name,
 			Ctx:                  ctx,
 			Client:               r.Client,
 		})
@@ -361,12 +487,17 @@ func (r *RKE2ConfigReconciler) handleClusterNotInitialized(ctx context.Context,
 		Certificates: certificates,
 	}
 
-	cloudInitData, err := cloudinit.NewInitControlPlane(cpinput)
+	var userData []byte
+	if scope.Config.Spec.Format == bootstrapv1.Ignition {
+		userData, err = ignition.NewInitControlPlane(cpinput)
+	} else {
+		userData, err = cloudinit.NewInitControlPlane(cpinput)
+	}
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	if err := r.storeBootstrapData(ctx, scope, cloudInitData); err != nil {
+	if err := r.storeBootstrapData(ctx, scope, userData); err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -378,14 +509,16 @@ func (r *RKE2ConfigReconciler) handleClusterNotInitialized(ctx context.Context,
 // and the files fields provided in the RKE2Config
 func (r *RKE2ConfigReconciler) generateFileListIncludingRegistries(ctx context.Context, scope *Scope, configFiles []bootstrapv1.File) ([]bootstrapv1.File, error) {
 	registries, registryFiles, err := rke2.GenerateRegistries(rke2.RKE2ConfigRegistry{
-		Registry: scope.Config.Spec.PrivateRegistriesConfig,
-		Client:   r.Client,
-		Ctx:      ctx,
-		Logger:   scope.Logger,
+		Registry:              scope.Config.Spec.PrivateRegistriesConfig,
+		SystemDefaultRegistry: systemDefaultRegistry(scope),
+		Client:                r.Client,
+		Ctx:                   ctx,
+		Logger:                scope.Logger,
 	})
 
 	if err != nil {
-		scope.Logger.Error(err, "unable to generate registries.yaml for Init Control Plane node")
+		scope.Logger.Error(err, "unable to generate registries.yaml")
+		conditions.MarkFalse(scope.Config, bootstrapv1.DataSecretAvailableCondition, bootstrapv1.PrivateRegistrySecretInvalidReason, clusterv1.ConditionSeverityWarning, err.Error())
 		return nil, err
 	}
 
@@ -403,12 +536,111 @@ func (r *RKE2ConfigReconciler) generateFileListIncludingRegistries(ctx context.C
 		Permissions: filePermissions,
 	}
 
+	additionalFiles, err := r.resolveFiles(ctx, scope.Config)
+	if err != nil {
+		scope.Logger.Error(err, "unable to resolve the content of files")
+		// Missing sources are reported to the user, the request is requeued when they are created;
+		// other errors are only returned so the request is retried.
+		var notFound *fileContentNotFoundError
+		if errors.As(err, &notFound) {
+			conditions.MarkFalse(scope.Config, bootstrapv1.DataSecretAvailableCondition, bootstrapv1.FileContentNotFoundReason, clusterv1.ConditionSeverityWarning, err.Error())
+		}
+		return nil, err
+	}
+
 	files := append(configFiles, registryFiles...)
 	files = append(files, initRegistriesFile)
-	files = append(files, scope.Config.Spec.Files...)
+	files = append(files, additionalFiles...)
 	return files, nil
 }
 
+// resolveFiles returns the files of the RKE2Config, with the content of files using ContentFrom inlined.
+func (r *RKE2ConfigReconciler) resolveFiles(ctx context.Context, config *bootstrapv1.RKE2Config) ([]bootstrapv1.File, error) {
+	files := make([]bootstrapv1.File, 0, len(config.Spec.Files))
+	for i := range config.Spec.Files {
+		file := config.Spec.Files[i]
+		if file.ContentFrom != nil {
+			var data []byte
+			var err error
+			switch {
+			case file.ContentFrom.Secret != nil:
+				data, err = r.resolveSecretFileContent(ctx, config.Namespace, file)
+			case file.ContentFrom.ConfigMap != nil:
+				data, err = r.resolveConfigMapFileContent(ctx, config.Namespace, file)
+			default:
+				err = errors.Errorf("no content source set in contentFrom for file %s", file.Path)
+			}
+			if err != nil {
+				return nil, err
+			}
+			file.ContentFrom = nil
+			file.Content = encodeFileContent(data, file.Encoding)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// resolveSecretFileContent returns the content of the Secret key referenced by the file.
+func (r *RKE2ConfigReconciler) resolveSecretFileContent(ctx context.Context, namespace string, file bootstrapv1.File) ([]byte, error) {
+	source := file.ContentFrom.Secret
+
+	s := &corev1.Secret{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: source.Name}, s); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, &fileContentNotFoundError{errors.Wrapf(err, "secret not found for file %s: %s/%s", file.Path, namespace, source.Name)}
+		}
+		return nil, errors.Wrapf(err, "failed to retrieve secret for file %s: %s/%s", file.Path, namespace, source.Name)
+	}
+
+	data, ok := s.Data[source.Key]
+	if !ok {
+		return nil, &fileContentNotFoundError{errors.Errorf("secret references non-existent key %q for file %s: %s/%s", source.Key, file.Path, namespace, source.Name)}
+	}
+	return data, nil
+}
+
+// resolveConfigMapFileContent returns the content of the ConfigMap key referenced by the file.
+func (r *RKE2ConfigReconciler) resolveConfigMapFileContent(ctx context.Context, namespace string, file bootstrapv1.File) ([]byte, error) {
+	source := file.ContentFrom.ConfigMap
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: source.Name}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, &fileContentNotFoundError{errors.Wrapf(err, "config map not found for file %s: %s/%s", file.Path, namespace, source.Name)}
+		}
+		return nil, errors.Wrapf(err, "failed to retrieve config map for file %s: %s/%s", file.Path, namespace, source.Name)
+	}
+
+	if data, ok := cm.Data[source.Key]; ok {
+		return []byte(data), nil
+	}
+	if data, ok := cm.BinaryData[source.Key]; ok {
+		return data, nil
+	}
+	return nil, &fileContentNotFoundError{errors.Errorf("config map references non-existent key %q for file %s: %s/%s", source.Key, file.Path, namespace, source.Name)}
+}
+
+// encodeFileContent returns the raw content of a referenced source as expected by the file encoding:
+// it is base64 encoded when the file encoding expects it.
+func encodeFileContent(data []byte, encoding bootstrapv1.Encoding) string {
+	switch encoding {
+	case bootstrapv1.Base64, bootstrapv1.GzipBase64:
+		return base64.StdEncoding.EncodeToString(data)
+	default:
+		return string(data)
+	}
+}
+
+// systemDefaultRegistry returns the registry used for system images by the node.
+// Workers use the same registry as the control plane, unless configured otherwise.
+func systemDefaultRegistry(scope *Scope) string {
+	if scope.Config.Spec.AgentConfig.SystemDefaultRegistry != "" || scope.ControlPlane == nil {
+		return scope.Config.Spec.AgentConfig.SystemDefaultRegistry
+	}
+	return scope.ControlPlane.Spec.AgentConfig.SystemDefaultRegistry
+}
+
 type RKE2InitLock interface {
 	Unlock(ctx context.Context, cluster *clusterv1.Cluster) bool
 	Lock(ctx context.Context, cluster *clusterv1.Cluster, machine *clusterv1.Machine) bool
@@ -432,6 +664,19 @@ func (r *RKE2ConfigReconciler) joinControlplane(ctx context.Context, scope *Scop
 		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
 
+	// Write the cluster CAs on every server, so they can be restored from the management cluster. Only the CAs
+	// stored as cluster secrets are written; the ones generated by RKE2 itself are left untouched.
+	certificates := secret.NewCertificatesForInitialControlPlane()
+	if err := certificates.Lookup(ctx, r.Client, util.ObjectKey(scope.Cluster)); err != nil {
+		reason := bootstrapv1.CertificatesCorruptedReason
+		if errors.Is(err, secret.ErrInvalidExternalCertificate) {
+			reason = bootstrapv1.CertificatesInvalidReason
+		}
+		conditions.MarkFalse(scope.Config, bootstrapv1.CertificatesAvailableCondition, reason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, err
+	}
+	conditions.MarkTrue(scope.Config, bootstrapv1.CertificatesAvailableCondition)
+
 	configStruct, configFiles, err := rke2.GenerateJoinControlPlaneConfig(
 		rke2.RKE2ServerConfigOpts{
 			Cluster:              *scope.Cluster,
@@ -440,6 +685,7 @@ func (r *RKE2ConfigReconciler) joinControlplane(ctx context.Context, scope *Scop
 			ServerURL:            fmt.Sprintf(serverURLFormat, scope.ControlPlane.Status.AvailableServerIPs[0], registrationPort),
 			ServerConfig:         scope.ControlPlane.Spec.ServerConfig,
 			AgentConfig:          scope.Config.Spec.AgentConfig,
+			MachineName:          scope.Machine.Name,
 			Ctx:                  ctx,
 			Client:               r.Client,
 		},
@@ -498,14 +744,20 @@ func (r *RKE2ConfigReconciler) joinControlplane(ctx context.Context, scope *Scop
 			WriteFiles:       files,
 			NTPServers:       ntpServers,
 		},
+		Certificates: certificates,
 	}
 
-	cloudInitData, err := cloudinit.NewJoinControlPlane(cpinput)
+	var userData []byte
+	if scope.Config.Spec.Format == bootstrapv1.Ignition {
+		userData, err = ignition.NewJoinControlPlane(cpinput)
+	} else {
+		userData, err = cloudinit.NewJoinControlPlane(cpinput)
+	}
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	if err := r.storeBootstrapData(ctx, scope, cloudInitData); err != nil {
+	if err := r.storeBootstrapData(ctx, scope, userData); err != nil {
 		return ctrl.Result{}, err
 	}
 	return ctrl.Result{}, nil
@@ -527,11 +779,15 @@ func (r *RKE2ConfigReconciler) joinWorker(ctx context.Context, scope *Scope) (re
 		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
 
+	agentConfig := scope.Config.Spec.AgentConfig
+	agentConfig.SystemDefaultRegistry = systemDefaultRegistry(scope)
+
 	configStruct, configFiles, err := rke2.GenerateWorkerConfig(
 		rke2.RKE2AgentConfigOpts{
 			ServerURL:              fmt.Sprintf(serverURLFormat, scope.ControlPlane.Status.AvailableServerIPs[0], registrationPort),
 			Token:                  token,
-			AgentConfig:            scope.Config.Spec.AgentConfig,
+			AgentConfig:            agentConfig,
+			MachineName:            scope.Machine.Name,
 			Ctx:                    ctx,
 			Client:                 r.Client,
 			CloudProviderName:      scope.ControlPlane.Spec.ServerConfig.CloudProviderName,
@@ -577,12 +833,17 @@ func (r *RKE2ConfigReconciler) joinWorker(ctx context.Context, scope *Scope) (re
 			NTPServers:       ntpServers,
 		}
 
-	cloudInitData, err := cloudinit.NewJoinWorker(wkInput)
+	var userData []byte
+	if scope.Config.Spec.Format == bootstrapv1.Ignition {
+		userData, err = ignition.NewJoinWorker(wkInput)
+	} else {
+		userData, err = cloudinit.NewJoinWorker(wkInput)
+	}
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	if err := r.storeBootstrapData(ctx, scope, cloudInitData); err != nil {
+	if err := r.storeBootstrapData(ctx, scope, userData); err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -629,7 +890,13 @@ func (r *RKE2ConfigReconciler) generateAndStoreToken(ctx context.Context, scope
 
 // storeBootstrapData creates a new secret with the data passed in as input,
 // sets the reference in the configuration status and ready to true.
+// The format of the data is recorded under the "format" key, as expected by Cluster API.
 func (r *RKE2ConfigReconciler) storeBootstrapData(ctx context.Context, scope *Scope, data []byte) error {
+	format := scope.Config.Spec.Format
+	if format == "" {
+		format = bootstrapv1.CloudConfig
+	}
+
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      scope.Config.Name,
@@ -648,7 +915,8 @@ func (r *RKE2ConfigReconciler) storeBootstrapData(ctx context.Context, scope *Sc
 			},
 		},
 		Data: map[string][]byte{
-			"value": data,
+			"value":  data,
+			"format": []byte(format),
 		},
 		Type: clusterv1.ClusterSecretType,
 	}
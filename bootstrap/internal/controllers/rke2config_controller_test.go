@@ -0,0 +1,239 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+)
+
+var _ = Describe("RKE2Config file sources", func() {
+	var (
+		ctx context.Context
+		r   *RKE2ConfigReconciler
+	)
+
+	newConfig := func(name string, files ...bootstrapv1.File) *bootstrapv1.RKE2Config {
+		return &bootstrapv1.RKE2Config{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       bootstrapv1.RKE2ConfigSpec{Files: files},
+		}
+	}
+
+	secretFile := func(path, name, key string) bootstrapv1.File {
+		return bootstrapv1.File{
+			Path:        path,
+			ContentFrom: &bootstrapv1.FileSource{Secret: &bootstrapv1.SecretFileSource{Name: name, Key: key}},
+		}
+	}
+
+	configMapFile := func(path, name, key string) bootstrapv1.File {
+		return bootstrapv1.File{
+			Path:        path,
+			ContentFrom: &bootstrapv1.FileSource{ConfigMap: &bootstrapv1.ConfigMapFileSource{Name: name, Key: key}},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+
+		r = &RKE2ConfigReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "files", Namespace: "default"},
+					Data:       map[string][]byte{"config": []byte("secret content")},
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "files", Namespace: "default"},
+					Data:       map[string]string{"config": "config map content"},
+					BinaryData: map[string][]byte{"binary": {0xca, 0xfe}},
+				},
+			).Build(),
+		}
+	})
+
+	It("should resolve the content of the files from secrets", func() {
+		inline := bootstrapv1.File{Path: "/etc/inline", Content: "inline content"}
+		encoded := secretFile("/etc/encoded", "files", "config")
+		encoded.Encoding = bootstrapv1.Base64
+
+		files, err := r.resolveFiles(ctx, newConfig("config", inline, secretFile("/etc/plain", "files", "config"), encoded))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]bootstrapv1.File{
+			inline,
+			{Path: "/etc/plain", Content: "secret content"},
+			{Path: "/etc/encoded", Encoding: bootstrapv1.Base64, Content: "c2VjcmV0IGNvbnRlbnQ="},
+		}))
+	})
+
+	It("should fail to resolve missing secrets and keys", func() {
+		for _, tt := range []struct {
+			file     bootstrapv1.File
+			expected string
+		}{
+			{file: secretFile("/etc/missing", "missing", "config"), expected: "secret not found for file /etc/missing: default/missing"},
+			{file: secretFile("/etc/missing", "files", "missing"), expected: `secret references non-existent key "missing" for file /etc/missing: default/files`},
+			{file: bootstrapv1.File{Path: "/etc/empty", ContentFrom: &bootstrapv1.FileSource{}}, expected: "no content source set in contentFrom for file /etc/empty"},
+		} {
+			_, err := r.resolveFiles(ctx, newConfig("config", tt.file))
+			Expect(err).To(MatchError(ContainSubstring(tt.expected)))
+		}
+	})
+
+	It("should resolve the content of the files from config maps", func() {
+		binary := configMapFile("/etc/binary", "files", "binary")
+		binary.Encoding = bootstrapv1.Base64
+
+		files, err := r.resolveFiles(ctx, newConfig("config", configMapFile("/etc/plain", "files", "config"), binary))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(Equal([]bootstrapv1.File{
+			{Path: "/etc/plain", Content: "config map content"},
+			{Path: "/etc/binary", Encoding: bootstrapv1.Base64, Content: "yv4="},
+		}))
+	})
+
+	It("should fail to resolve missing config maps and keys", func() {
+		_, err := r.resolveFiles(ctx, newConfig("config", configMapFile("/etc/missing", "missing", "config")))
+		Expect(err).To(MatchError(ContainSubstring("config map not found for file /etc/missing: default/missing")))
+		_, err = r.resolveFiles(ctx, newConfig("config", configMapFile("/etc/missing", "files", "missing")))
+		Expect(err).To(MatchError(ContainSubstring(`config map references non-existent key "missing" for file /etc/missing: default/files`)))
+	})
+
+	It("should report the files whose content can't be resolved", func() {
+		scope := &Scope{
+			Logger: logr.Discard(),
+			Config: newConfig("config", secretFile("/etc/missing", "missing", "config")),
+		}
+
+		_, err := r.generateFileListIncludingRegistries(ctx, scope, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(conditions.IsFalse(scope.Config, bootstrapv1.DataSecretAvailableCondition)).To(BeTrue())
+		Expect(conditions.GetReason(scope.Config, bootstrapv1.DataSecretAvailableCondition)).To(Equal(bootstrapv1.FileContentNotFoundReason))
+		Expect(conditions.GetSeverity(scope.Config, bootstrapv1.DataSecretAvailableCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityWarning)))
+	})
+
+	It("should only report the files whose content doesn't exist", func() {
+		r.Client = &failingGetClient{Client: r.Client}
+		scope := &Scope{
+			Logger: logr.Discard(),
+			Config: newConfig("config", secretFile("/etc/file", "files", "config")),
+		}
+
+		_, err := r.generateFileListIncludingRegistries(ctx, scope, nil)
+		Expect(err).To(MatchError(ContainSubstring("failed to retrieve secret for file /etc/file: default/files")))
+		Expect(conditions.Has(scope.Config, bootstrapv1.DataSecretAvailableCondition)).To(BeFalse())
+	})
+
+	It("should index the configs by the sources of their files", func() {
+		config := newConfig("config",
+			secretFile("/etc/a", "a", "config"),
+			configMapFile("/etc/b", "b", "config"),
+			bootstrapv1.File{Path: "/etc/inline", Content: "inline content"},
+			secretFile("/etc/c", "c", "config"),
+		)
+
+		Expect(fileSourceNames(func(source *bootstrapv1.FileSource) string {
+			if source.Secret == nil {
+				return ""
+			}
+			return source.Secret.Name
+		})(config)).To(Equal([]string{"a", "c"}))
+	})
+
+	It("should requeue the configs not rendered yet when a referenced secret changes", func() {
+		ready := newConfig("ready", secretFile("/etc/file", "files", "config"))
+		ready.Status.Ready = true
+		otherNamespace := newConfig("other-namespace", secretFile("/etc/file", "files", "config"))
+		otherNamespace.Namespace = "other"
+		for _, config := range []*bootstrapv1.RKE2Config{
+			newConfig("waiting", secretFile("/etc/file", "files", "config")),
+			newConfig("other-secret", secretFile("/etc/file", "other", "config")),
+			newConfig("no-files"),
+			ready,
+			otherNamespace,
+		} {
+			Expect(r.Client.Create(ctx, config)).To(Succeed())
+		}
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "files", Namespace: "default"}}
+		Expect(r.secretToRKE2Configs(secret)).To(ConsistOf(
+			ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "default", Name: "waiting"}},
+		))
+		Expect(r.secretToRKE2Configs(&corev1.ConfigMap{})).To(BeEmpty())
+	})
+})
+
+var _ = Describe("RKE2Config config map watch", func() {
+	It("should requeue the configs not rendered yet when a referenced config map changes", func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+
+		newConfig := func(name string, source *bootstrapv1.FileSource) *bootstrapv1.RKE2Config {
+			return &bootstrapv1.RKE2Config{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec: bootstrapv1.RKE2ConfigSpec{
+					Files: []bootstrapv1.File{{Path: "/etc/file", ContentFrom: source}},
+				},
+			}
+		}
+		ready := newConfig("ready", &bootstrapv1.FileSource{ConfigMap: &bootstrapv1.ConfigMapFileSource{Name: "files", Key: "config"}})
+		ready.Status.Ready = true
+		r := &RKE2ConfigReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				newConfig("waiting", &bootstrapv1.FileSource{ConfigMap: &bootstrapv1.ConfigMapFileSource{Name: "files", Key: "config"}}),
+				newConfig("other-config-map", &bootstrapv1.FileSource{ConfigMap: &bootstrapv1.ConfigMapFileSource{Name: "other", Key: "config"}}),
+				// A Secret with the same name as the ConfigMap.
+				newConfig("secret", &bootstrapv1.FileSource{Secret: &bootstrapv1.SecretFileSource{Name: "files", Key: "config"}}),
+				ready,
+			).Build(),
+		}
+
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "files", Namespace: "default"}}
+		Expect(r.configMapToRKE2Configs(configMap)).To(ConsistOf(
+			ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "default", Name: "waiting"}},
+		))
+		Expect(r.configMapToRKE2Configs(&corev1.Secret{})).To(BeEmpty())
+	})
+})
+
+// failingGetClient is a client failing to get any object.
+type failingGetClient struct {
+	client.Client
+}
+
+func (c *failingGetClient) Get(_ context.Context, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	return apierrors.NewServiceUnavailable("unavailable")
+}
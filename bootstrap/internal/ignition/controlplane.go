@@ -0,0 +1,52 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+import (
+	"fmt"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/internal/cloudinit"
+)
+
+const (
+	controlPlaneAirGappedInstall = `INSTALL_RKE2_ARTIFACT_PATH=/opt/rke2-artifacts sh /opt/install.sh`
+	controlPlaneOnlineInstall    = `curl -sfL https://get.rke2.io | INSTALL_RKE2_VERSION=%s sh -s - server`
+	controlPlaneServiceName      = "rke2-server.service"
+)
+
+// NewInitControlPlane returns the Ignition user data to be used on the first controlplane instance.
+func NewInitControlPlane(input *cloudinit.ControlPlaneInput) ([]byte, error) {
+	input.WriteFiles = append(input.WriteFiles, input.Certificates.AsFiles()...)
+	input.WriteFiles = append(input.WriteFiles, input.ConfigFile)
+
+	return generate("InitControlplane", &input.BaseUserData, controlPlaneInstallCommand(&input.BaseUserData), controlPlaneServiceName)
+}
+
+// NewJoinControlPlane returns the Ignition user data to be used on a joining controlplane instance.
+func NewJoinControlPlane(input *cloudinit.ControlPlaneInput) ([]byte, error) {
+	input.WriteFiles = append(input.WriteFiles, input.Certificates.AsFiles()...)
+	input.WriteFiles = append(input.WriteFiles, input.ConfigFile)
+
+	return generate("JoinControlplane", &input.BaseUserData, controlPlaneInstallCommand(&input.BaseUserData), controlPlaneServiceName)
+}
+
+func controlPlaneInstallCommand(input *cloudinit.BaseUserData) string {
+	if input.AirGapped {
+		return controlPlaneAirGappedInstall
+	}
+	return fmt.Sprintf(controlPlaneOnlineInstall, input.RKE2Version)
+}
@@ -0,0 +1,267 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ignition generates Ignition v3 bootstrap data from the same input used by the cloudinit package.
+package ignition
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/internal/cloudinit"
+)
+
+const (
+	// ignitionVersion is the Ignition specification version of the generated documents.
+	ignitionVersion = "3.3.0"
+
+	// bootstrapScriptPath is the location of the script running the RKE2 installation on the node.
+	bootstrapScriptPath = "/etc/rancher/rke2/bootstrap.sh"
+
+	// bootstrapUnitName is the systemd unit running the bootstrap script on first boot.
+	bootstrapUnitName = "rke2-bootstrap.service"
+
+	// bootstrapStampPath is written once the bootstrap script completed, so that it does not run again on reboot.
+	bootstrapStampPath = "/etc/rancher/rke2/.bootstrap-complete"
+
+	// timesyncdConfigPath is the systemd-timesyncd drop-in used to configure NTP servers.
+	timesyncdConfigPath = "/etc/systemd/timesyncd.conf.d/rke2-ntp.conf"
+
+	defaultFileMode = 0o644
+	scriptFileMode  = 0o700
+)
+
+const (
+	bootstrapScript = `#!/bin/sh
+set -e
+{{- range .PreRKE2Commands }}
+{{ . }}
+{{- end }}
+{{ .InstallCommand }}
+systemctl enable {{ .ServiceName }}
+systemctl start {{ .ServiceName }}
+mkdir -p /run/cluster-api
+{{ .SentinelFileCommand }}
+{{- range .PostRKE2Commands }}
+{{ . }}
+{{- end }}
+touch {{ .StampPath }}
+`
+
+	bootstrapUnit = `[Unit]
+Description=Cluster API RKE2 bootstrap
+Wants=network-online.target
+After=network-online.target
+ConditionPathExists=!%s
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=%s
+
+[Install]
+WantedBy=multi-user.target
+`
+
+	sentinelFileCommand = `echo success > /run/cluster-api/bootstrap-success.complete`
+)
+
+// Config is the subset of the Ignition v3 configuration used by the RKE2 bootstrap provider.
+type Config struct {
+	Ignition Ignition `json:"ignition"`
+	Storage  Storage  `json:"storage,omitempty"`
+	Systemd  Systemd  `json:"systemd,omitempty"`
+}
+
+// Ignition holds the Ignition specific metadata of the configuration.
+type Ignition struct {
+	Version string `json:"version"`
+}
+
+// Storage describes the files to be written on the node.
+type Storage struct {
+	Files []File `json:"files,omitempty"`
+}
+
+// File describes a single file written by Ignition.
+type File struct {
+	Path      string       `json:"path"`
+	Overwrite *bool        `json:"overwrite,omitempty"`
+	Mode      *int         `json:"mode,omitempty"`
+	User      *NodeUser    `json:"user,omitempty"`
+	Group     *NodeGroup   `json:"group,omitempty"`
+	Contents  FileContents `json:"contents"`
+}
+
+// NodeUser references the owner of a file by name.
+type NodeUser struct {
+	Name string `json:"name,omitempty"`
+}
+
+// NodeGroup references the group of a file by name.
+type NodeGroup struct {
+	Name string `json:"name,omitempty"`
+}
+
+// FileContents describes the contents of a file, as a data URL.
+type FileContents struct {
+	Compression string `json:"compression,omitempty"`
+	Source      string `json:"source"`
+}
+
+// Systemd describes the systemd units to be created on the node.
+type Systemd struct {
+	Units []Unit `json:"units,omitempty"`
+}
+
+// Unit is a single systemd unit.
+type Unit struct {
+	Name     string `json:"name"`
+	Enabled  *bool  `json:"enabled,omitempty"`
+	Contents string `json:"contents,omitempty"`
+}
+
+// scriptInput is the data used to render the bootstrap script.
+type scriptInput struct {
+	cloudinit.BaseUserData
+	InstallCommand string
+	ServiceName    string
+	StampPath      string
+}
+
+func generate(kind string, input *cloudinit.BaseUserData, installCommand, serviceName string) ([]byte, error) {
+	input.SentinelFileCommand = sentinelFileCommand
+
+	t, err := template.New(kind).Parse(bootstrapScript)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s bootstrap script template", kind)
+	}
+
+	var script bytes.Buffer
+	if err := t.Execute(&script, scriptInput{
+		BaseUserData:   *input,
+		InstallCommand: installCommand,
+		ServiceName:    serviceName,
+		StampPath:      bootstrapStampPath,
+	}); err != nil {
+		return nil, errors.Wrapf(err, "failed to generate %s bootstrap script", kind)
+	}
+
+	files := make([]File, 0, len(input.WriteFiles)+2)
+	for _, f := range input.WriteFiles {
+		if f.Path == "" {
+			continue
+		}
+		file, err := toIgnitionFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert file %s", f.Path)
+		}
+		files = append(files, file)
+	}
+
+	if len(input.NTPServers) > 0 {
+		files = append(files, newFile(timesyncdConfigPath, defaultFileMode,
+			fmt.Sprintf("[Time]\nNTP=%s\n", strings.Join(input.NTPServers, " "))))
+	}
+
+	files = append(files, newFile(bootstrapScriptPath, scriptFileMode, script.String()))
+
+	config := Config{
+		Ignition: Ignition{Version: ignitionVersion},
+		Storage:  Storage{Files: files},
+		Systemd: Systemd{
+			Units: []Unit{
+				{
+					Name:     bootstrapUnitName,
+					Enabled:  boolPtr(true),
+					Contents: fmt.Sprintf(bootstrapUnit, bootstrapStampPath, bootstrapScriptPath),
+				},
+			},
+		},
+	}
+
+	out, err := json.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal %s ignition config", kind)
+	}
+
+	return out, nil
+}
+
+// toIgnitionFile converts a bootstrap file into an Ignition file, honouring its encoding, owner and permissions.
+func toIgnitionFile(f bootstrapv1.File) (File, error) {
+	file := File{
+		Path:      f.Path,
+		Overwrite: boolPtr(true),
+	}
+
+	switch f.Encoding {
+	case bootstrapv1.Base64:
+		file.Contents.Source = "data:;base64," + f.Content
+	case bootstrapv1.GzipBase64:
+		file.Contents.Source = "data:;base64," + f.Content
+		file.Contents.Compression = "gzip"
+	case bootstrapv1.Gzip:
+		file.Contents.Source = "data:;base64," + base64.StdEncoding.EncodeToString([]byte(f.Content))
+		file.Contents.Compression = "gzip"
+	default:
+		file.Contents.Source = "data:;base64," + base64.StdEncoding.EncodeToString([]byte(f.Content))
+	}
+
+	if f.Permissions != "" {
+		mode, err := strconv.ParseInt(f.Permissions, 8, 32)
+		if err != nil {
+			return File{}, errors.Wrapf(err, "invalid permissions %q", f.Permissions)
+		}
+		m := int(mode)
+		file.Mode = &m
+	}
+
+	if f.Owner != "" {
+		user, group, _ := strings.Cut(f.Owner, ":")
+		if user != "" {
+			file.User = &NodeUser{Name: user}
+		}
+		if group != "" {
+			file.Group = &NodeGroup{Name: group}
+		}
+	}
+
+	return file, nil
+}
+
+func newFile(path string, mode int, content string) File {
+	return File{
+		Path:      path,
+		Overwrite: boolPtr(true),
+		Mode:      &mode,
+		Contents: FileContents{
+			Source: "data:;base64," + base64.StdEncoding.EncodeToString([]byte(content)),
+		},
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
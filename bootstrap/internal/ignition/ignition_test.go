@@ -0,0 +1,150 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/internal/cloudinit"
+)
+
+func decodeSource(source string) string {
+	content, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(source, "data:;base64,"))
+	Expect(err).ToNot(HaveOccurred())
+	return string(content)
+}
+
+func findFile(config *Config, path string) *File {
+	for i := range config.Storage.Files {
+		if config.Storage.Files[i].Path == path {
+			return &config.Storage.Files[i]
+		}
+	}
+	return nil
+}
+
+var _ = Describe("WorkerIgnitionTest", func() {
+	var input *cloudinit.BaseUserData
+
+	BeforeEach(func() {
+		input = &cloudinit.BaseUserData{
+			RKE2Version:      "v1.25.6+rke2r1",
+			PreRKE2Commands:  []string{"echo pre"},
+			PostRKE2Commands: []string{"echo post"},
+			NTPServers:       []string{"test.ntp.org"},
+			ConfigFile: bootstrapv1.File{
+				Path:        "/etc/rancher/rke2/config.yaml",
+				Content:     "token: abc",
+				Owner:       "root:root",
+				Permissions: "0640",
+			},
+			WriteFiles: []bootstrapv1.File{
+				{
+					Path:     "/etc/encoded",
+					Content:  base64.StdEncoding.EncodeToString([]byte("encoded")),
+					Encoding: bootstrapv1.Base64,
+				},
+			},
+		}
+	})
+
+	It("Should generate a valid Ignition config", func() {
+		data, err := NewJoinWorker(input)
+		Expect(err).ToNot(HaveOccurred())
+
+		config := &Config{}
+		Expect(json.Unmarshal(data, config)).To(Succeed())
+		Expect(config.Ignition.Version).To(Equal(ignitionVersion))
+
+		configFile := findFile(config, "/etc/rancher/rke2/config.yaml")
+		Expect(configFile).ToNot(BeNil())
+		Expect(decodeSource(configFile.Contents.Source)).To(Equal("token: abc"))
+		Expect(*configFile.Mode).To(Equal(0o640))
+		Expect(configFile.User.Name).To(Equal("root"))
+		Expect(configFile.Group.Name).To(Equal("root"))
+
+		encodedFile := findFile(config, "/etc/encoded")
+		Expect(encodedFile).ToNot(BeNil())
+		Expect(decodeSource(encodedFile.Contents.Source)).To(Equal("encoded"))
+
+		ntpFile := findFile(config, timesyncdConfigPath)
+		Expect(ntpFile).ToNot(BeNil())
+		Expect(decodeSource(ntpFile.Contents.Source)).To(ContainSubstring("NTP=test.ntp.org"))
+
+		script := findFile(config, bootstrapScriptPath)
+		Expect(script).ToNot(BeNil())
+		Expect(decodeSource(script.Contents.Source)).To(Equal(`#!/bin/sh
+set -e
+echo pre
+curl -sfL https://get.rke2.io | INSTALL_RKE2_VERSION=v1.25.6+rke2r1 INSTALL_RKE2_TYPE="agent" sh -s -
+systemctl enable rke2-agent.service
+systemctl start rke2-agent.service
+mkdir -p /run/cluster-api
+echo success > /run/cluster-api/bootstrap-success.complete
+echo post
+touch /etc/rancher/rke2/.bootstrap-complete
+`))
+
+		Expect(config.Systemd.Units).To(HaveLen(1))
+		Expect(config.Systemd.Units[0].Name).To(Equal(bootstrapUnitName))
+		Expect(*config.Systemd.Units[0].Enabled).To(BeTrue())
+		Expect(config.Systemd.Units[0].Contents).To(ContainSubstring("ExecStart=" + bootstrapScriptPath))
+	})
+})
+
+var _ = Describe("ControlPlaneAirGappedIgnitionTest", func() {
+	var input *cloudinit.ControlPlaneInput
+
+	BeforeEach(func() {
+		input = &cloudinit.ControlPlaneInput{
+			BaseUserData: cloudinit.BaseUserData{
+				AirGapped: true,
+				ConfigFile: bootstrapv1.File{
+					Path:    "/etc/rancher/rke2/config.yaml",
+					Content: "token: abc",
+				},
+			},
+		}
+	})
+
+	It("Should use the image embedded install.sh method", func() {
+		data, err := NewJoinControlPlane(input)
+		Expect(err).ToNot(HaveOccurred())
+
+		config := &Config{}
+		Expect(json.Unmarshal(data, config)).To(Succeed())
+
+		script := findFile(config, bootstrapScriptPath)
+		Expect(script).ToNot(BeNil())
+		content := decodeSource(script.Contents.Source)
+		Expect(content).To(ContainSubstring("INSTALL_RKE2_ARTIFACT_PATH=/opt/rke2-artifacts sh /opt/install.sh"))
+		Expect(content).To(ContainSubstring("systemctl start rke2-server.service"))
+		Expect(findFile(config, timesyncdConfigPath)).To(BeNil())
+	})
+
+	It("Should reject invalid file permissions", func() {
+		input.WriteFiles = []bootstrapv1.File{{Path: "/etc/bad", Permissions: "rw-r--r--"}}
+		_, err := NewJoinControlPlane(input)
+		Expect(err).To(HaveOccurred())
+	})
+})
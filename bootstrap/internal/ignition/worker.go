@@ -0,0 +1,41 @@
+/*
+Copyright 2023 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+import (
+	"fmt"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/internal/cloudinit"
+)
+
+const (
+	workerAirGappedInstall = `INSTALL_RKE2_ARTIFACT_PATH=/opt/rke2-artifacts INSTALL_RKE2_TYPE="agent" sh /opt/install.sh`
+	workerOnlineInstall    = `curl -sfL https://get.rke2.io | INSTALL_RKE2_VERSION=%s INSTALL_RKE2_TYPE="agent" sh -s -`
+	workerServiceName      = "rke2-agent.service"
+)
+
+// NewJoinWorker returns the Ignition user data to be used on a worker instance.
+func NewJoinWorker(input *cloudinit.BaseUserData) ([]byte, error) {
+	input.WriteFiles = append(input.WriteFiles, input.ConfigFile)
+
+	installCommand := fmt.Sprintf(workerOnlineInstall, input.RKE2Version)
+	if input.AirGapped {
+		installCommand = workerAirGappedInstall
+	}
+
+	return generate("JoinWorker", input, installCommand, workerServiceName)
+}
@@ -38,7 +38,7 @@ const (
 const (
 	// ControlPlaneComponentsHealthyCondition reports the overall status of control plane components
 	// implemented as static pods generated by RKE2 including kube-api-server, kube-controller manager,
-	// kube-scheduler and etcd if managed.
+	// kube-scheduler, etcd and cloud-controller-manager, unless disabled.
 	ControlPlaneComponentsHealthyCondition clusterv1.ConditionType = "ControlPlaneComponentsHealthy"
 
 	// ControlPlaneComponentsUnhealthyReason (Severity=Error) documents a control plane component not healthy.
@@ -57,11 +57,33 @@ const (
 	// MachineAgentHealthyCondition reports a machine's rke2 agent's operational status.
 	MachineAgentHealthyCondition clusterv1.ConditionType = "AgentHealthy"
 
+	// NodeNotReadyReason (Severity=Warning) documents a machine whose node is not reporting ready.
+	NodeNotReadyReason = "NodeNotReady"
+
+	// MachineAPIServerPodHealthyCondition reports a machine's kube-apiserver's operational status.
+	MachineAPIServerPodHealthyCondition clusterv1.ConditionType = "APIServerPodHealthy"
+
+	// MachineControllerManagerPodHealthyCondition reports a machine's kube-controller-manager's health status.
+	MachineControllerManagerPodHealthyCondition clusterv1.ConditionType = "ControllerManagerPodHealthy"
+
+	// MachineSchedulerPodHealthyCondition reports a machine's kube-scheduler's operational status.
+	MachineSchedulerPodHealthyCondition clusterv1.ConditionType = "SchedulerPodHealthy"
+
+	// MachineEtcdPodHealthyCondition reports a machine's etcd pod's operational status.
+	MachineEtcdPodHealthyCondition clusterv1.ConditionType = "EtcdPodHealthy"
+
+	// MachineCloudControllerManagerPodHealthyCondition reports a machine's cloud-controller-manager's operational status.
+	// NOTE: This condition exists only if the RKE2 cloud controller manager is not disabled.
+	MachineCloudControllerManagerPodHealthyCondition clusterv1.ConditionType = "CloudControllerManagerPodHealthy"
+
+	// PodProvisioningReason (Severity=Info) documents a pod waiting to be provisioned i.e., Pod is in "Pending" phase.
+	PodProvisioningReason = "PodProvisioning"
+
 	// PodInspectionFailedReason documents a failure in inspecting the pod status.
 	PodInspectionFailedReason = "PodInspectionFailed"
 
 	// PodMissingReason (Severity=Error) documents a pod does not exist.
-	PodMissingReason = "PodMissing" // RollingUpdateInProgressReason (Severity=Warning) documents a RKE2ControlPlane object executing a
+	PodMissingReason = "PodMissing"
 
 	// PodFailedReason (Severity=Error) documents if a pod failed during provisioning i.e., e.g CrashLoopbackOff, ImagePullBackOff
 	// or if all the containers in a pod have terminated.
@@ -70,6 +92,18 @@ const (
 	// RollingUpdateInProgressReason (Severity=Warning) documents a RKE2ControlPlane object executing a
 	// rolling upgrade for aligning the machines spec to the desired state.
 	RollingUpdateInProgressReason = "RollingUpdateInProgress"
+
+	// RollingUpdateDeletingOutdatedMachineReason (Severity=Warning) documents a RKE2ControlPlane object rolling out with
+	// maxSurge 0, deleting an outdated machine before creating its replacement.
+	RollingUpdateDeletingOutdatedMachineReason = "RollingUpdateDeletingOutdatedMachine"
+
+	// RollingUpdateCreatingReplacementMachineReason (Severity=Warning) documents a RKE2ControlPlane object rolling out with
+	// maxSurge 0, creating the replacement of an outdated machine that has been deleted.
+	RollingUpdateCreatingReplacementMachineReason = "RollingUpdateCreatingReplacementMachine"
+
+	// RollingUpdateWaitingForEtcdQuorumReason (Severity=Warning) documents a RKE2ControlPlane object rolling out with
+	// maxSurge 0 that can't delete an outdated machine because this could result in etcd losing quorum.
+	RollingUpdateWaitingForEtcdQuorumReason = "RollingUpdateWaitingForEtcdQuorum"
 )
 
 const (
@@ -79,6 +113,19 @@ const (
 	// EtcdClusterInspectionFailedReason documents a failure in inspecting the etcd cluster status.
 	EtcdClusterInspectionFailedReason = "EtcdClusterInspectionFailed"
 
+	// EtcdClusterInspectionUnavailableReason documents that the etcd cluster can't be inspected because the etcd CA
+	// is not available on the management cluster, e.g. for clusters created before the provider managed it.
+	EtcdClusterInspectionUnavailableReason = "EtcdClusterInspectionUnavailable"
+
+	// EtcdClusterUnknownReason reports an etcd cluster in unknown status.
+	EtcdClusterUnknownReason = "EtcdClusterUnknown"
+
+	// EtcdClusterUnhealthyReason (Severity=Error) is set when the etcd cluster is unhealthy.
+	EtcdClusterUnhealthyReason = "EtcdClusterUnhealthy"
+
+	// EtcdClusterOrphanMemberReason (Severity=Error) is set when the etcd cluster has members without a corresponding machine.
+	EtcdClusterOrphanMemberReason = "EtcdClusterOrphanMember"
+
 	// MachineEtcdMemberHealthyCondition report the machine's etcd member's health status.
 	// NOTE: This conditions exists only if a stacked etcd cluster is used.
 	MachineEtcdMemberHealthyCondition clusterv1.ConditionType = "EtcdMemberHealthy"
@@ -86,6 +133,19 @@ const (
 	// EtcdMemberInspectionFailedReason documents a failure in inspecting the etcd member status.
 	EtcdMemberInspectionFailedReason = "MemberInspectionFailed"
 
+	// EtcdMemberUnreachableReason (Severity=Error) documents that the etcd member on the machine's node can't be reached.
+	EtcdMemberUnreachableReason = "EtcdMemberUnreachable"
+
+	// EtcdMemberMissingReason (Severity=Error) documents a machine without a corresponding etcd member.
+	EtcdMemberMissingReason = "EtcdMemberMissing"
+
+	// EtcdMemberAlarmReason (Severity=Error) documents an etcd member reporting alarms, e.g. NOSPACE or CORRUPT.
+	EtcdMemberAlarmReason = "EtcdMemberAlarm"
+
+	// EtcdMemberUnhealthyReason (Severity=Error) documents an etcd member that is unhealthy, e.g. reporting errors
+	// or a view of the cluster that is different from other members.
+	EtcdMemberUnhealthyReason = "EtcdMemberUnhealthy"
+
 	// ResizedCondition documents a RKE2ControlPlane that is resizing the set of controlled machines.
 	ResizedCondition clusterv1.ConditionType = "Resized"
 
@@ -100,4 +160,67 @@ const (
 	CertificatesAvailableCondition clusterv1.ConditionType = "CertificatesAvailable"
 
 	CertificatesGenerationFailedReason string = "CertificateGenerationFailed"
+
+	// CertificatesInvalidReason (Severity=Error) documents a certificate provided by the user, e.g. an intermediate CA
+	// issued by an enterprise PKI, that can't be used: the private key does not match, the certificate is not a CA
+	// allowed to sign certificates, or it is expired.
+	CertificatesInvalidReason string = "CertificatesInvalid"
+)
+
+const (
+	// CARotationCompletedCondition documents the progress of a cluster CA rotation requested with the rotate-ca annotation.
+	// It is only set once a rotation has been requested.
+	CARotationCompletedCondition clusterv1.ConditionType = "CARotationCompleted"
+
+	// CARotationTrustingNewCAReason (Severity=Info) documents control plane machines being rolled out
+	// to trust the new CAs alongside the old ones.
+	CARotationTrustingNewCAReason = "TrustingNewCA"
+
+	// CARotationSigningWithNewCAReason (Severity=Info) documents control plane machines being rolled out
+	// to get certificates signed by the new CAs.
+	CARotationSigningWithNewCAReason = "SigningWithNewCA"
+
+	// CARotationDroppingOldCAReason (Severity=Info) documents control plane machines being rolled out
+	// to stop trusting the old CAs.
+	CARotationDroppingOldCAReason = "DroppingOldCA"
+
+	// CARotationWaitingForWorkersReason (Severity=Warning) documents a CA rotation waiting for the worker machines
+	// created before the new CAs started signing to be rolled out by the user, before the old CAs are dropped.
+	CARotationWaitingForWorkersReason = "WaitingForWorkers"
+
+	// CARotationFailedReason (Severity=Error) documents a CA rotation that can't proceed, e.g. because
+	// the CAs were provided by the user.
+	CARotationFailedReason = "CARotationFailed"
+)
+
+const (
+	// InPlaceUpgradeCompletedCondition documents the progress of an in-place upgrade of the control plane nodes
+	// with system-upgrade-controller Plans. It is only set when the upgrade strategy is InPlace.
+	InPlaceUpgradeCompletedCondition clusterv1.ConditionType = "InPlaceUpgradeCompleted"
+
+	// InPlaceUpgradeInProgressReason (Severity=Info) documents control plane nodes being upgraded in place.
+	InPlaceUpgradeInProgressReason = "InPlaceUpgradeInProgress"
+
+	// InPlaceUpgradeFailedReason (Severity=Warning) documents an in-place upgrade that could not be completed,
+	// e.g. because the system-upgrade-controller is not deployed or a node did not upgrade in time;
+	// the remaining control plane machines are replaced instead.
+	InPlaceUpgradeFailedReason = "InPlaceUpgradeFailed"
+)
+
+const (
+	// VersionChannelResolvedCondition documents the resolution of the release channel set as the version
+	// to an RKE2 version. It is only set when the version is a release channel.
+	VersionChannelResolvedCondition clusterv1.ConditionType = "VersionChannelResolved"
+
+	// VersionChannelResolutionFailedReason (Severity=Warning) documents a release channel that can't be resolved,
+	// e.g. because the channel catalogue is not configured or doesn't have the channel.
+	VersionChannelResolutionFailedReason = "VersionChannelResolutionFailed"
+
+	// VersionChannelUpgradePendingReason (Severity=Info) documents a newer version of the release channel that
+	// the control plane will move to once the ongoing rollout is completed.
+	VersionChannelUpgradePendingReason = "VersionChannelUpgradePending"
+
+	// VersionChannelUpgradeBlockedReason (Severity=Warning) documents a newer version of the release channel that
+	// the control plane can't be upgraded to directly, e.g. because it skips Kubernetes minor versions.
+	VersionChannelUpgradeBlockedReason = "VersionChannelUpgradeBlocked"
 )
@@ -17,9 +17,13 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/util/version"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
@@ -29,6 +33,32 @@ const (
 	// RKE2ServerConfigurationAnnotation is a machine annotation that stores the json-marshalled string of RKE2Config
 	// This annotation is used to detect any changes in RKE2Config and trigger machine rollout.
 	RKE2ServerConfigurationAnnotation = "controlplane.cluster.x-k8s.io/rke2-server-configuration"
+
+	// RKE2VersionAnnotation is a machine annotation that stores the RKE2 version a machine was upgraded to in place,
+	// its RKE2Config still holding the version it was created with.
+	RKE2VersionAnnotation = "controlplane.cluster.x-k8s.io/rke2-version"
+
+	// RemediationInProgressAnnotation is used to keep track that a RCP remediation is in progress, and more
+	// specifically it tracks that the system is in between having deleted an unhealthy machine and recreating its replacement.
+	// NOTE: if something external to CAPI removes this annotation the system cannot detect the above situation; this can lead to
+	// failures in updating remediation retry or remediation count (both counters restart from zero).
+	RemediationInProgressAnnotation = "controlplane.cluster.x-k8s.io/remediation-in-progress"
+
+	// RemediationForAnnotation is used to link a new machine to the unhealthy machine it is replacing;
+	// please note that in case of retry, when also the remediating machine fails, the system keeps track of
+	// the first machine of the sequence only.
+	// NOTE: if something external to CAPI removes this annotation the system this can lead to
+	// failures in updating remediation retry (the counter restarts from zero).
+	RemediationForAnnotation = "controlplane.cluster.x-k8s.io/remediation-for"
+
+	// CARotationAnnotation requests the rotation of the cluster CAs stored in the <cluster>-ca and <cluster>-cca secrets.
+	// Its value identifies the request, e.g. a timestamp: setting a new value starts a new rotation once the previous
+	// one is completed.
+	CARotationAnnotation = "controlplane.cluster.x-k8s.io/rotate-ca"
+
+	// DefaultMinHealthyPeriod defines the default minimum period before we consider a remediation on a
+	// machine unrelated from the previous remediation.
+	DefaultMinHealthyPeriod = 1 * time.Hour
 )
 
 // RKE2ControlPlaneSpec defines the desired state of RKE2ControlPlane
@@ -36,7 +66,7 @@ type RKE2ControlPlaneSpec struct {
 	// RKE2AgentSpec contains the node spec for the RKE2 Control plane nodes.
 	bootstrapv1.RKE2ConfigSpec `json:",inline"`
 
-	// Replicas is the number of replicas for the Control Plane.
+	// Replicas is the number of replicas for the Control Plane. It must be odd, to preserve etcd quorum.
 	Replicas *int32 `json:"replicas,omitempty"`
 
 	// ServerConfig specifies configuration for the agent nodes.
@@ -48,15 +78,170 @@ type RKE2ControlPlaneSpec struct {
 	//+optional
 	ManifestsConfigMapReference corev1.ObjectReference `json:"manifestsConfigMapReference,omitempty"`
 
-	// InfrastructureRef is a required reference to a custom resource
-	// offered by an infrastructure provider.
-	InfrastructureRef corev1.ObjectReference `json:"infrastructureRef"`
+	// Version defines the desired RKE2 version, e.g. "v1.25.6+rke2r1".
+	// It is set by the topology controller for clusters using a ClusterClass, and takes precedence over AgentConfig.Version.
+	// Either of them can also be a release channel, e.g. "stable", "latest" or "v1.26", resolved by the controller
+	// from the channel catalogue to the version reported in status.versionChannel.
+	//+optional
+	Version string `json:"version,omitempty"`
+
+	// InfrastructureRef is a reference to a custom resource offered by an infrastructure provider.
+	// Either InfrastructureRef or MachineTemplate.InfrastructureRef must be set.
+	//+optional
+	InfrastructureRef corev1.ObjectReference `json:"infrastructureRef,omitempty"`
+
+	// MachineTemplate contains information about how machines should be shaped when creating or updating a control plane.
+	// It is set by the topology controller for clusters using a ClusterClass.
+	//+optional
+	MachineTemplate RKE2ControlPlaneMachineTemplate `json:"machineTemplate,omitempty"`
 
 	// NodeDrainTimeout is the total amount of time that the controller will spend on draining a controlplane node
 	// The default value is 0, meaning that the node can be drained without any time limitations.
 	// NOTE: NodeDrainTimeout is different from `kubectl drain --timeout`
 	// +optional
 	NodeDrainTimeout *metav1.Duration `json:"nodeDrainTimeout,omitempty"`
+
+	// RemediationStrategy is the RemediationStrategy that controls how control plane machine remediation happens.
+	// +optional
+	RemediationStrategy *RemediationStrategy `json:"remediationStrategy,omitempty"`
+
+	// RolloutStrategy is the RolloutStrategy to use to replace control plane machines with new ones.
+	// If not set, control plane machines are replaced by creating a new machine before deleting an outdated one.
+	// +optional
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// RolloutAfter is a field to indicate a rollout should be performed after the specified time
+	// even if no changes have been made to the RKE2ControlPlane; machines created before this time are replaced.
+	// +optional
+	RolloutAfter *metav1.Time `json:"rolloutAfter,omitempty"`
+
+	// CertificatesExpiryDays indicates a rollout needs to be performed if the certificates of the control plane
+	// machines will expire within the specified days. The expiry is read from the kube-apiserver serving
+	// certificate of each machine.
+	// +kubebuilder:validation:Minimum=7
+	// +optional
+	CertificatesExpiryDays *int32 `json:"certificatesExpiryDays,omitempty"`
+
+	// UpgradeStrategy defines how control plane machines are upgraded when only the RKE2 version changes.
+	// With InPlace, the system-upgrade-controller, which must be deployed in the workload cluster, upgrades the nodes
+	// through Plans instead of replacing the machines; any other change still triggers a replacement. The agent nodes
+	// are upgraded as well once the servers are, but the version of their MachineDeployments is not updated.
+	// Defaults to Replace.
+	// +kubebuilder:validation:Enum=Replace;InPlace
+	// +optional
+	UpgradeStrategy UpgradeStrategyType `json:"upgradeStrategy,omitempty"`
+
+	// ChannelAutoUpgrade moves the control plane to the latest version of its release channel when the channel
+	// catalogue changes, when the version is a release channel, one Kubernetes minor version at a time. Otherwise the
+	// version first resolved from the channel stays pinned until the channel is changed.
+	// +optional
+	ChannelAutoUpgrade bool `json:"channelAutoUpgrade,omitempty"`
+}
+
+// UpgradeStrategyType defines how the RKE2 version of control plane machines is upgraded.
+type UpgradeStrategyType string
+
+const (
+	// ReplaceUpgradeStrategyType upgrades control plane machines by replacing them, like any other change.
+	ReplaceUpgradeStrategyType UpgradeStrategyType = "Replace"
+
+	// InPlaceUpgradeStrategyType upgrades the RKE2 version of the nodes in place with system-upgrade-controller Plans,
+	// the server Plan first and then the agent Plan.
+	InPlaceUpgradeStrategyType UpgradeStrategyType = "InPlace"
+)
+
+// RolloutStrategyType defines the rollout strategies for a RKE2ControlPlane.
+// +kubebuilder:validation:Enum=RollingUpdate
+type RolloutStrategyType string
+
+const (
+	// RollingUpdateStrategyType replaces the old control planes by new one using rolling update
+	// i.e. gradually scale up or down the old control planes and scale up or down the new one.
+	RollingUpdateStrategyType RolloutStrategyType = "RollingUpdate"
+)
+
+// RolloutStrategy describes how to replace existing machines with new ones.
+type RolloutStrategy struct {
+	// Type of rollout. Currently the only supported strategy is "RollingUpdate".
+	// Default is RollingUpdate.
+	// +optional
+	Type RolloutStrategyType `json:"type,omitempty"`
+
+	// RollingUpdate is the rolling update config params. Present only if RolloutStrategyType = RollingUpdate.
+	// +optional
+	RollingUpdate *RollingUpdate `json:"rollingUpdate,omitempty"`
+}
+
+// RollingUpdate is used to control the desired behavior of rolling update.
+type RollingUpdate struct {
+	// MaxSurge is the maximum number of control planes that can be scheduled above or under the
+	// desired number of control planes.
+	// Value can be an absolute number 1 or 0.
+	// Defaults to 1.
+	// When set to 0, an outdated machine is deleted before its replacement is created (scale in place),
+	// which requires at least 3 replicas to preserve etcd quorum.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+}
+
+// RemediationStrategy allows to define how control plane machine remediation happens.
+type RemediationStrategy struct {
+	// MaxRetry is the Max number of retries while attempting to remediate an unhealthy machine.
+	// A retry happens when a machine that was created as a replacement for an unhealthy machine also fails.
+	// For example, given a control plane with three machines M1, M2, M3:
+	//
+	//	M1 become unhealthy; remediation happens, and M1-1 is created as a replacement.
+	//	If M1-1 (replacement of M1) has problems while bootstrapping it will become unhealthy, and then be
+	//	remediated; such operation is considered a retry, remediation-retry #1.
+	//	If M1-2 (replacement of M1-1) becomes unhealthy, remediation-retry #2 will happen, etc.
+	//
+	// A retry could happen only after RetryPeriod from the previous retry.
+	// If a machine is marked as unhealthy after MinHealthyPeriod from the previous remediation expired,
+	// this is not considered a retry anymore because the new issue is assumed unrelated from the previous one.
+	//
+	// If not set, the remedation will be retried infinitely.
+	// +optional
+	MaxRetry *int32 `json:"maxRetry,omitempty"`
+
+	// RetryPeriod is the duration that RCP should wait before remediating a machine being created as a replacement
+	// for an unhealthy machine (a retry).
+	//
+	// If not set, a retry will happen immediately.
+	// +optional
+	RetryPeriod metav1.Duration `json:"retryPeriod,omitempty"`
+
+	// MinHealthyPeriod defines the duration after which RCP will consider any failure to a machine unrelated
+	// from the previous one. In this case the remediation is not considered a retry anymore, and thus the retry
+	// counter restarts from 0. For example, assuming MinHealthyPeriod is set to 1h (default)
+	//
+	//	M1 become unhealthy; remediation happens, and M1-1 is created as a replacement.
+	//	If M1-1 (replacement of M1) has problems within the 1hr after the creation, also
+	//	this machine will be remediated and this operation is considered a retry - a problem related
+	//	to the original issue happened to M1 -.
+	//
+	//	If instead the problem on M1-1 is happening after MinHealthyPeriod expired, e.g. four days after
+	//	m1-1 has been created as a remediation of M1, the problem on M1-1 is considered unrelated to
+	//	the original issue happened to M1.
+	//
+	// If not set, this value is defaulted to 1h.
+	// +optional
+	MinHealthyPeriod *metav1.Duration `json:"minHealthyPeriod,omitempty"`
+}
+
+// RKE2ControlPlaneMachineTemplate defines the template for Machines in a RKE2ControlPlane object.
+type RKE2ControlPlaneMachineTemplate struct {
+	// ObjectMeta is the standard object's metadata, its labels and annotations are propagated to the Machines.
+	//+optional
+	ObjectMeta clusterv1.ObjectMeta `json:"metadata,omitempty"`
+
+	// InfrastructureRef is a reference to a custom resource offered by an infrastructure provider.
+	//+optional
+	InfrastructureRef corev1.ObjectReference `json:"infrastructureRef,omitempty"`
+
+	// NodeDrainTimeout is the total amount of time that the controller will spend on draining a controlplane node.
+	// It takes precedence over the NodeDrainTimeout of the RKE2ControlPlaneSpec.
+	// +optional
+	NodeDrainTimeout *metav1.Duration `json:"nodeDrainTimeout,omitempty"`
 }
 
 type RKE2ServerConfig struct {
@@ -158,9 +343,19 @@ type RKE2ControlPlaneStatus struct {
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 
+	// Selector is the label selector in string format of the control plane machines, used by the scale subresource
+	// to avoid exposing the labels (see https://kubernetes.io/docs/tasks/extend-kubernetes/custom-resources/custom-resource-definitions/#scale-subresource).
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
 	// Replicas is the number of replicas current attached to this ControlPlane Resource.
 	Replicas int32 `json:"replicas,omitempty"`
 
+	// Version is the lowest Kubernetes version of the control plane machines, so it only reports a version once
+	// all the machines have been upgraded to it.
+	// +optional
+	Version *string `json:"version,omitempty"`
+
 	// ReadyReplicas is the number of replicas current attached to this ControlPlane Resource and that have Ready Status.
 	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
 
@@ -173,10 +368,119 @@ type RKE2ControlPlaneStatus struct {
 	// AvailableServerIPs is a list of the Control Plane IP adds that can be used to register further nodes.
 	// +optional
 	AvailableServerIPs []string `json:"availableServerIPs,omitempty"`
+
+	// LastRemediation stores info about last remediation performed.
+	// +optional
+	LastRemediation *LastRemediationStatus `json:"lastRemediation,omitempty"`
+
+	// MachinesNeedingRollout lists the control plane machines that need to be rolled out, with the reasons why
+	// they differ from the desired state.
+	// +optional
+	MachinesNeedingRollout []MachineRolloutStatus `json:"machinesNeedingRollout,omitempty"`
+
+	// CARotation reports the progress of the last cluster CA rotation requested with the rotate-ca annotation.
+	// +optional
+	CARotation *CARotationStatus `json:"caRotation,omitempty"`
+
+	// InPlaceUpgrade reports the progress of the last in-place upgrade, when the upgrade strategy is InPlace.
+	// +optional
+	InPlaceUpgrade *InPlaceUpgradeStatus `json:"inPlaceUpgrade,omitempty"`
+
+	// VersionChannel reports the RKE2 version resolved from the release channel set as the version, if any.
+	// +optional
+	VersionChannel *VersionChannelStatus `json:"versionChannel,omitempty"`
+}
+
+// VersionChannelStatus reports the RKE2 version the control plane is pinned to when its version is a release channel.
+type VersionChannelStatus struct {
+	// Channel is the release channel the version was resolved from.
+	Channel string `json:"channel"`
+
+	// ResolvedVersion is the RKE2 version the control plane is pinned to.
+	ResolvedVersion string `json:"resolvedVersion"`
+
+	// LatestVersion is the latest version of the channel in the catalogue, when the control plane is not moved to it,
+	// either because ChannelAutoUpgrade is off or because it can't be upgraded to it directly.
+	// +optional
+	LatestVersion string `json:"latestVersion,omitempty"`
+}
+
+// InPlaceUpgradeStatus reports the progress of an in-place upgrade.
+type InPlaceUpgradeStatus struct {
+	// Version is the RKE2 version the nodes are upgraded to.
+	Version string `json:"version"`
+
+	// StartTime is when the upgrade Plans were created.
+	StartTime metav1.Time `json:"startTime"`
+
+	// PendingMachines lists the control plane machines whose node does not report the new version yet.
+	// +optional
+	PendingMachines []string `json:"pendingMachines,omitempty"`
+
+	// Failed is set when the upgrade did not complete in time, the remaining machines then being replaced.
+	// +optional
+	Failed bool `json:"failed,omitempty"`
+}
+
+// CARotationPhase is a step of a cluster CA rotation.
+type CARotationPhase string
+
+const (
+	// CARotationTrustingNewCA is the phase during which the new CAs are trusted alongside the old ones,
+	// and the control plane machines are rolled out to get the CA bundles.
+	CARotationTrustingNewCA CARotationPhase = "TrustingNewCA"
+
+	// CARotationSigningWithNewCA is the phase during which the new CAs are used for signing,
+	// and the control plane machines are rolled out to get certificates signed by the new CAs.
+	CARotationSigningWithNewCA CARotationPhase = "SigningWithNewCA"
+
+	// CARotationDroppingOldCA is the phase during which the old CAs are removed from the CA bundles,
+	// and the control plane machines are rolled out to stop trusting them.
+	CARotationDroppingOldCA CARotationPhase = "DroppingOldCA"
+
+	// CARotationCompleted is the phase of a CA rotation that is over.
+	CARotationCompleted CARotationPhase = "Completed"
+)
+
+// CARotationStatus reports the progress of a cluster CA rotation.
+type CARotationStatus struct {
+	// Request is the value of the rotate-ca annotation that triggered the rotation.
+	Request string `json:"request"`
+
+	// Phase is the current step of the rotation.
+	Phase CARotationPhase `json:"phase"`
+
+	// PhaseStartTime is when the current phase started; the control plane machines created before
+	// are rolled out before moving to the next phase.
+	PhaseStartTime metav1.Time `json:"phaseStartTime"`
+}
+
+// MachineRolloutStatus reports why a control plane machine needs to be rolled out.
+type MachineRolloutStatus struct {
+	// Machine is the name of the machine needing rollout.
+	Machine string `json:"machine"`
+
+	// Reasons lists the differences between the machine and the desired state of the control plane,
+	// e.g. the version or the configuration fields that changed.
+	Reasons []string `json:"reasons"`
+}
+
+// LastRemediationStatus  stores info about last remediation performed.
+type LastRemediationStatus struct {
+	// Machine is the machine name of the latest machine being remediated.
+	Machine string `json:"machine"`
+
+	// Timestamp is when last remediation happened. It is represented in RFC3339 form and is in UTC.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// RetryCount used to keep track of remediation retry for the last remediated machine.
+	// A retry happens when a machine that was created as a replacement for an unhealthy machine also fails.
+	RetryCount int32 `json:"retryCount"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 
 // RKE2ControlPlane is the Schema for the rke2controlplanes API
 type RKE2ControlPlane struct {
@@ -196,6 +500,59 @@ type RKE2ControlPlaneList struct {
 	Items           []RKE2ControlPlane `json:"items"`
 }
 
+// GetDesiredVersion returns the desired RKE2 version of the control plane. When the version is a release channel,
+// it is the version resolved from the channel, or an empty string until it has been resolved.
+func (r *RKE2ControlPlane) GetDesiredVersion() string {
+	if r.GetVersionChannel() != "" {
+		if r.Status.VersionChannel == nil {
+			return ""
+		}
+		return r.Status.VersionChannel.ResolvedVersion
+	}
+	return r.specVersion()
+}
+
+// GetVersionChannel returns the release channel set as the version, or an empty string when the version is not a channel.
+func (r *RKE2ControlPlane) GetVersionChannel() string {
+	if specVersion := r.specVersion(); version.IsChannel(specVersion) {
+		return specVersion
+	}
+	return ""
+}
+
+// specVersion returns the version set in the spec, either an RKE2 version or a release channel.
+func (r *RKE2ControlPlane) specVersion() string {
+	if r.Spec.Version != "" {
+		return r.Spec.Version
+	}
+	return r.Spec.AgentConfig.Version
+}
+
+// GetInfrastructureRef returns the reference to the infrastructure template used to create the control plane machines.
+func (r *RKE2ControlPlane) GetInfrastructureRef() *corev1.ObjectReference {
+	if r.Spec.MachineTemplate.InfrastructureRef.Name != "" {
+		return &r.Spec.MachineTemplate.InfrastructureRef
+	}
+	return &r.Spec.InfrastructureRef
+}
+
+// GetNodeDrainTimeout returns the node drain timeout of the control plane machines.
+func (r *RKE2ControlPlane) GetNodeDrainTimeout() *metav1.Duration {
+	if r.Spec.MachineTemplate.NodeDrainTimeout != nil {
+		return r.Spec.MachineTemplate.NodeDrainTimeout
+	}
+	return r.Spec.NodeDrainTimeout
+}
+
+// GetMaxSurge returns the number of control plane machines that can be created above the desired number of replicas
+// during a rollout, which is either 0 or 1 (default).
+func (r *RKE2ControlPlane) GetMaxSurge() int32 {
+	if r.Spec.RolloutStrategy == nil || r.Spec.RolloutStrategy.RollingUpdate == nil || r.Spec.RolloutStrategy.RollingUpdate.MaxSurge == nil {
+		return 1
+	}
+	return r.Spec.RolloutStrategy.RollingUpdate.MaxSurge.IntVal
+}
+
 // EtcdConfig regroups the ETCD-specific configuration of the control plane
 type EtcdConfig struct {
 	// ExposeEtcdMetrics defines the policy for ETCD Metrics exposure.
@@ -17,16 +17,52 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/util/version"
 )
 
+// maxKubeletMinorVersionSkew is how many minor versions the kubelet can be older than the kube-apiserver,
+// see https://kubernetes.io/releases/version-skew-policy/#kubelet.
+const maxKubeletMinorVersionSkew = 2
+
 // log is for logging in this package.
 var rke2controlplanelog = logf.Log.WithName("rke2controlplane-resource")
 
 func (r *RKE2ControlPlane) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	// The scale subresource doesn't go through the Validator, its replicas are validated separately.
+	mgr.GetWebhookServer().Register("/validate-scale-controlplane-cluster-x-k8s-io-v1alpha1-rke2controlplane", &webhook.Admission{
+		Handler: &scaleValidator{Client: mgr.GetClient()},
+	})
+
+	// The validating webhook is registered before the builder does, so the responses of the Validator
+	// can carry warnings about the version skew of the workers, which need a client.
+	mgr.GetWebhookServer().Register("/validate-controlplane-cluster-x-k8s-io-v1alpha1-rke2controlplane", &webhook.Admission{
+		Handler: &versionSkewWarningHandler{
+			Handler: admission.ValidatingWebhookFor(r).Handler,
+			Client:  mgr.GetClient(),
+		},
+	})
+
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -49,14 +85,30 @@ var _ webhook.Validator = &RKE2ControlPlane{}
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
 func (r *RKE2ControlPlane) ValidateCreate() error {
 	rke2controlplanelog.Info("validate create", "name", r.Name)
-	return nil
+
+	allErrs := r.validate(nil)
+	allErrs = append(allErrs, validateReplicas(field.NewPath("spec", "replicas"), r.Spec.Replicas)...)
+	return r.toInvalid(allErrs)
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *RKE2ControlPlane) ValidateUpdate(old runtime.Object) error {
 	rke2controlplanelog.Info("validate update", "name", r.Name)
 
-	return nil
+	oldControlPlane, ok := old.(*RKE2ControlPlane)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a RKE2ControlPlane but got a %T", old))
+	}
+
+	// Only the changed fields are validated, so objects which were valid when created, or were created before
+	// a validation was added, can still have their metadata, e.g. finalizers, and their other fields updated.
+	allErrs := r.validate(oldControlPlane)
+	allErrs = append(allErrs, validateVersionUpdate(r.versionPath(), oldControlPlane, r)...)
+	// Control planes created with an even number of replicas can still be updated, as long as it doesn't change.
+	if pointer.Int32Deref(r.Spec.Replicas, 0) != pointer.Int32Deref(oldControlPlane.Spec.Replicas, 0) {
+		allErrs = append(allErrs, validateReplicas(field.NewPath("spec", "replicas"), r.Spec.Replicas)...)
+	}
+	return r.toInvalid(allErrs)
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -65,3 +117,269 @@ func (r *RKE2ControlPlane) ValidateDelete() error {
 
 	return nil
 }
+
+// validate validates the spec of the control plane, or only the fields changed from the old control plane when given.
+func (r *RKE2ControlPlane) validate(old *RKE2ControlPlane) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if old == nil || !reflect.DeepEqual(r.Spec.RKE2ConfigSpec, old.Spec.RKE2ConfigSpec) {
+		allErrs = append(allErrs, bootstrapv1.ValidateRKE2ConfigSpec(field.NewPath("spec"), &r.Spec.RKE2ConfigSpec)...)
+	}
+
+	if (old == nil || *r.GetInfrastructureRef() != *old.GetInfrastructureRef()) && r.GetInfrastructureRef().Name == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("spec", "infrastructureRef"),
+			"either spec.infrastructureRef or spec.machineTemplate.infrastructureRef must be set"))
+	}
+
+	specVersion := r.specVersion()
+	if (old == nil || specVersion != old.specVersion()) && specVersion != "" && !version.IsChannel(specVersion) {
+		if _, err := version.Rke2ToKubeVersion(specVersion); err != nil {
+			allErrs = append(allErrs, field.Invalid(r.versionPath(), specVersion,
+				fmt.Sprintf("%v, or a release channel like stable, latest or v1.26", err)))
+		}
+	}
+
+	if old == nil || !reflect.DeepEqual(r.Spec.RolloutStrategy, old.Spec.RolloutStrategy) ||
+		pointer.Int32Deref(r.Spec.Replicas, 0) != pointer.Int32Deref(old.Spec.Replicas, 0) {
+		allErrs = append(allErrs, validateRolloutStrategy(field.NewPath("spec", "rolloutStrategy"), r.Spec.RolloutStrategy, r.Spec.Replicas)...)
+	}
+
+	return allErrs
+}
+
+func (r *RKE2ControlPlane) toInvalid(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlane").GroupKind(), r.Name, allErrs)
+}
+
+// versionPath returns the path of the field the desired version is read from.
+func (r *RKE2ControlPlane) versionPath() *field.Path {
+	if r.Spec.Version != "" {
+		return field.NewPath("spec", "version")
+	}
+	return field.NewPath("spec", "agentConfig", "version")
+}
+
+// validateVersionUpdate checks that a version change is an upgrade to the same or the next Kubernetes minor version,
+// and that it doesn't happen while the control plane machines are being rolled out.
+func validateVersionUpdate(path *field.Path, oldControlPlane, newControlPlane *RKE2ControlPlane) field.ErrorList {
+	oldVersion, newVersion := oldControlPlane.GetDesiredVersion(), newControlPlane.GetDesiredVersion()
+	if oldVersion == "" {
+		return nil
+	}
+
+	// A new release channel is only resolved by the controller, the desired version is the one of the previous channel until then.
+	channel := newControlPlane.GetVersionChannel()
+	unresolvedChannel := channel != "" &&
+		(newControlPlane.Status.VersionChannel == nil || newControlPlane.Status.VersionChannel.Channel != channel)
+	switch {
+	case unresolvedChannel && oldControlPlane.specVersion() == channel:
+		return nil
+	case !unresolvedChannel && (oldVersion == newVersion || newVersion == ""):
+		return nil
+	}
+
+	from, err := version.ParseRKE2Version(oldVersion)
+	if err != nil {
+		// The version can be fixed, whatever the new one is.
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	if unresolvedChannel {
+		// The controller only moves to versions of the channel the control plane can be upgraded to, but a channel of a
+		// minor version must not skip minor versions either.
+		if err := version.CheckChannelUpgrade(from, channel); err != nil {
+			allErrs = append(allErrs, field.Forbidden(path, err.Error()))
+		}
+	} else {
+		to, err := version.ParseRKE2Version(newVersion)
+		if err != nil {
+			// Already reported by validate.
+			return nil
+		}
+		if err := version.CheckUpgrade(from, to); err != nil {
+			allErrs = append(allErrs, field.Forbidden(path, err.Error()))
+		}
+	}
+
+	if pending := len(oldControlPlane.Status.MachinesNeedingRollout); pending > 0 {
+		allErrs = append(allErrs, field.Forbidden(path,
+			fmt.Sprintf("the version can't be changed while a rollout is in progress, %d machines still need to be rolled out", pending)))
+	}
+	return allErrs
+}
+
+// validateReplicas checks that the number of replicas is odd, so the etcd members of the control plane keep a quorum
+// when one of them is lost.
+func validateReplicas(path *field.Path, replicas *int32) field.ErrorList {
+	if replicas == nil || (*replicas > 0 && *replicas%2 == 1) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(path, *replicas, "must be an odd number, to preserve etcd quorum")}
+}
+
+// validateRolloutStrategy checks that maxSurge is either 0 or 1, and that scaling in place is only
+// used with enough replicas to preserve etcd quorum while a machine is being replaced.
+func validateRolloutStrategy(path *field.Path, strategy *RolloutStrategy, replicas *int32) field.ErrorList {
+	if strategy == nil || strategy.RollingUpdate == nil || strategy.RollingUpdate.MaxSurge == nil {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	maxSurgePath := path.Child("rollingUpdate", "maxSurge")
+	maxSurge := strategy.RollingUpdate.MaxSurge
+	if maxSurge.Type != intstr.Int || (maxSurge.IntVal != 0 && maxSurge.IntVal != 1) {
+		allErrs = append(allErrs, field.Invalid(maxSurgePath, maxSurge.String(), "must be either 0 or 1"))
+		return allErrs
+	}
+
+	if maxSurge.IntVal == 0 && replicas != nil && *replicas < 3 {
+		allErrs = append(allErrs, field.Forbidden(maxSurgePath,
+			"scaling in place (maxSurge 0) requires at least 3 replicas to preserve etcd quorum"))
+	}
+	return allErrs
+}
+
+//+kubebuilder:webhook:path=/validate-scale-controlplane-cluster-x-k8s-io-v1alpha1-rke2controlplane,mutating=false,failurePolicy=fail,sideEffects=None,groups=controlplane.cluster.x-k8s.io,resources=rke2controlplanes/scale,verbs=update,versions=v1alpha1,name=vrke2controlplanescale.kb.io,admissionReviewVersions=v1
+
+// scaleValidator validates the replicas of RKE2ControlPlanes scaled through the scale subresource, like the Validator
+// does for the replicas set in the spec.
+type scaleValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// InjectDecoder injects the decoder into the validator.
+func (v *scaleValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle validates the replicas of the Scale, and the rollout strategy of the RKE2ControlPlane with these replicas.
+func (v *scaleValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	scale := &autoscalingv1.Scale{}
+	if err := v.decoder.Decode(req, scale); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	rcp := &RKE2ControlPlane{}
+	if err := v.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, rcp); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	allErrs := validateReplicas(field.NewPath("spec", "replicas"), &scale.Spec.Replicas)
+	allErrs = append(allErrs, validateRolloutStrategy(field.NewPath("spec", "rolloutStrategy"), rcp.Spec.RolloutStrategy, &scale.Spec.Replicas)...)
+	if len(allErrs) > 0 {
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+// versionSkewWarningHandler validates RKE2ControlPlanes with their Validator implementation, and warns when the
+// RKE2ConfigTemplates used by the MachineDeployments of the cluster have versions the control plane doesn't support
+// for kubelets. The version of the workers is only checked once the request is allowed, and never denies it.
+type versionSkewWarningHandler struct {
+	admission.Handler
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// InjectDecoder injects the decoder into the handler, and the Validator handler it wraps.
+func (h *versionSkewWarningHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	_, err := admission.InjectDecoderInto(d, h.Handler)
+	return err
+}
+
+// Handle validates the RKE2ControlPlane, and adds warnings about the version skew of the workers to the response.
+func (h *versionSkewWarningHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	resp := h.Handler.Handle(ctx, req)
+	if !resp.Allowed || (req.Operation != admissionv1.Create && req.Operation != admissionv1.Update) {
+		return resp
+	}
+
+	rcp := &RKE2ControlPlane{}
+	if err := h.decoder.DecodeRaw(req.Object, rcp); err != nil {
+		return resp
+	}
+
+	warnings, err := workerVersionSkewWarnings(ctx, h.Client, rcp)
+	if err != nil {
+		rke2controlplanelog.Error(err, "failed to check the version skew of the workers", "name", rcp.Name)
+	}
+	return resp.WithWarnings(warnings...)
+}
+
+// workerVersionSkewWarnings returns a warning for each RKE2ConfigTemplate used by the MachineDeployments of the cluster
+// whose version is newer than the control plane version, or older than the kubelet version skew allows.
+func workerVersionSkewWarnings(ctx context.Context, c client.Client, rcp *RKE2ControlPlane) ([]string, error) {
+	controlPlaneVersion, err := version.ParseRKE2Version(rcp.GetDesiredVersion())
+	if err != nil {
+		return nil, nil
+	}
+	clusterName := rcp.clusterName()
+	if clusterName == "" {
+		return nil, nil
+	}
+
+	machineDeployments := &clusterv1.MachineDeploymentList{}
+	if err := c.List(ctx, machineDeployments, client.InNamespace(rcp.Namespace), client.MatchingLabels{clusterv1.ClusterLabelName: clusterName}); err != nil {
+		return nil, err
+	}
+
+	warnings := []string{}
+	checked := map[string]bool{}
+	for _, md := range machineDeployments.Items {
+		ref := md.Spec.Template.Spec.Bootstrap.ConfigRef
+		if ref == nil || ref.GroupVersionKind().GroupKind() != bootstrapv1.GroupVersion.WithKind("RKE2ConfigTemplate").GroupKind() || checked[ref.Name] {
+			continue
+		}
+		checked[ref.Name] = true
+
+		template := &bootstrapv1.RKE2ConfigTemplate{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: md.Namespace, Name: ref.Name}, template); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return warnings, err
+		}
+
+		workerVersion, err := version.ParseRKE2Version(template.Spec.Template.Spec.AgentConfig.Version)
+		if err != nil {
+			continue
+		}
+		if reason := kubeletVersionSkew(controlPlaneVersion.Kubernetes, workerVersion.Kubernetes); reason != "" {
+			warnings = append(warnings, fmt.Sprintf("RKE2ConfigTemplate %s used by MachineDeployment %s has version %s, %s the control plane version %s",
+				template.Name, md.Name, template.Spec.Template.Spec.AgentConfig.Version, reason, rcp.GetDesiredVersion()))
+		}
+	}
+	return warnings, nil
+}
+
+// kubeletVersionSkew returns why a kubelet version is not supported with the given kube-apiserver version,
+// or an empty string if it is.
+func kubeletVersionSkew(apiServerVersion, kubeletVersion *utilversion.Version) string {
+	switch {
+	case kubeletVersion.Major() != apiServerVersion.Major():
+		return "which has a different major version than"
+	case kubeletVersion.Minor() > apiServerVersion.Minor():
+		return "which is newer than"
+	case apiServerVersion.Minor()-kubeletVersion.Minor() > maxKubeletMinorVersionSkew:
+		return fmt.Sprintf("which is more than %d minor versions older than", maxKubeletMinorVersionSkew)
+	}
+	return ""
+}
+
+// clusterName returns the name of the cluster the control plane belongs to, from its owner reference
+// or its cluster name label when it isn't owned by the cluster yet.
+func (r *RKE2ControlPlane) clusterName() string {
+	for _, ref := range r.OwnerReferences {
+		if ref.Kind == "Cluster" && ref.APIVersion == clusterv1.GroupVersion.String() {
+			return ref.Name
+		}
+	}
+	return r.Labels[clusterv1.ClusterLabelName]
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+)
+
+var _ = Describe("RKE2ControlPlane version validation", func() {
+	var rcp *RKE2ControlPlane
+
+	BeforeEach(func() {
+		rcp = &RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "rcp", Namespace: "default"},
+			Spec: RKE2ControlPlaneSpec{
+				InfrastructureRef: corev1.ObjectReference{Name: "infra"},
+				Version:           "v1.24.6+rke2r1",
+			},
+		}
+	})
+
+	withVersion := func(version string) *RKE2ControlPlane {
+		updated := rcp.DeepCopy()
+		updated.Spec.Version = version
+		return updated
+	}
+
+	It("should reject malformed versions", func() {
+		err := withVersion("1.24.6").ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(withVersion("v1.24.6+rke2r1").ValidateCreate()).To(Succeed())
+	})
+
+	It("should accept pre-release versions", func() {
+		Expect(withVersion("v1.25.0-rc1+rke2r1").ValidateCreate()).To(Succeed())
+		Expect(withVersion("v1.25.0-rc1+rke2r1").ValidateUpdate(rcp)).To(Succeed())
+	})
+
+	It("should only validate the changed fields on update", func() {
+		// A control plane created before its version was validated.
+		rcp.Spec.Version = "1.24.6"
+		rcp.Spec.InfrastructureRef = corev1.ObjectReference{}
+
+		updated := rcp.DeepCopy()
+		updated.Finalizers = []string{"test"}
+		Expect(updated.ValidateUpdate(rcp)).To(Succeed())
+
+		err := withVersion("1.24.7").ValidateUpdate(rcp)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("spec.version")))
+		Expect(err).ToNot(MatchError(ContainSubstring("spec.infrastructureRef")))
+	})
+
+	It("should accept release channels", func() {
+		Expect(withVersion("stable").ValidateCreate()).To(Succeed())
+		Expect(withVersion("stable").ValidateUpdate(rcp)).To(Succeed())
+		Expect(withVersion("v1.25").ValidateUpdate(rcp)).To(Succeed())
+	})
+
+	It("should reject release channels skipping minor versions", func() {
+		err := withVersion("v1.26").ValidateUpdate(rcp)
+		Expect(err).To(MatchError(ContainSubstring("skips Kubernetes minor versions")))
+		err = withVersion("v1.23").ValidateUpdate(rcp)
+		Expect(err).To(MatchError(ContainSubstring("downgrading")))
+
+		// The channel was resolved by the controller, the pinned version is the current one.
+		rcp.Spec.Version = "stable"
+		rcp.Status.VersionChannel = &VersionChannelStatus{Channel: "stable", ResolvedVersion: "v1.24.6+rke2r1"}
+		err = withVersion("v1.26").ValidateUpdate(rcp)
+		Expect(err).To(MatchError(ContainSubstring("skips Kubernetes minor versions")))
+	})
+
+	It("should reject switching to a release channel during a rollout", func() {
+		rcp.Status.MachinesNeedingRollout = []MachineRolloutStatus{{Machine: "m1", Reasons: []string{"version"}}}
+		err := withVersion("stable").ValidateUpdate(rcp)
+		Expect(err).To(MatchError(ContainSubstring("rollout is in progress")))
+	})
+
+	It("should allow upgrades to the same or the next minor version", func() {
+		Expect(withVersion("v1.24.6+rke2r2").ValidateUpdate(rcp)).To(Succeed())
+		Expect(withVersion("v1.24.10+rke2r1").ValidateUpdate(rcp)).To(Succeed())
+		Expect(withVersion("v1.25.3+rke2r1").ValidateUpdate(rcp)).To(Succeed())
+	})
+
+	It("should reject downgrades", func() {
+		err := withVersion("v1.24.6+rke2r1").ValidateUpdate(withVersion("v1.24.6+rke2r2"))
+		Expect(err).To(MatchError(ContainSubstring("downgrading")))
+		err = withVersion("v1.23.9+rke2r1").ValidateUpdate(rcp)
+		Expect(err).To(MatchError(ContainSubstring("downgrading")))
+	})
+
+	It("should reject skipping minor versions", func() {
+		err := withVersion("v1.26.0+rke2r1").ValidateUpdate(rcp)
+		Expect(err).To(MatchError(ContainSubstring("skips Kubernetes minor versions")))
+	})
+
+	It("should reject version changes during a rollout", func() {
+		rcp.Status.MachinesNeedingRollout = []MachineRolloutStatus{{Machine: "m1", Reasons: []string{"version"}}}
+		err := withVersion("v1.25.3+rke2r1").ValidateUpdate(rcp)
+		Expect(err).To(MatchError(ContainSubstring("rollout is in progress")))
+
+		// Other changes are still allowed.
+		updated := rcp.DeepCopy()
+		updated.Spec.Replicas = pointer.Int32(3)
+		Expect(updated.ValidateUpdate(rcp)).To(Succeed())
+	})
+})
+
+var _ = Describe("RKE2ControlPlane replicas validation", func() {
+	var rcp *RKE2ControlPlane
+
+	BeforeEach(func() {
+		rcp = &RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "rcp", Namespace: "default"},
+			Spec: RKE2ControlPlaneSpec{
+				InfrastructureRef: corev1.ObjectReference{Name: "infra"},
+				Replicas:          pointer.Int32(3),
+			},
+		}
+	})
+
+	It("should reject even replica counts", func() {
+		Expect(rcp.ValidateCreate()).To(Succeed())
+
+		for _, replicas := range []int32{0, 2, 4} {
+			updated := rcp.DeepCopy()
+			updated.Spec.Replicas = pointer.Int32(replicas)
+			Expect(updated.ValidateCreate()).ToNot(Succeed())
+			Expect(updated.ValidateUpdate(rcp)).ToNot(Succeed())
+		}
+	})
+
+	It("should allow updating control planes with an unchanged even replica count", func() {
+		rcp.Spec.Replicas = pointer.Int32(2)
+		updated := rcp.DeepCopy()
+		updated.Spec.NodeDrainTimeout = &metav1.Duration{}
+		Expect(updated.ValidateUpdate(rcp)).To(Succeed())
+	})
+
+	It("should reject even replica counts set through the scale subresource", func() {
+		validator := newScaleValidator(rcp)
+
+		Expect(validator.Handle(ctx, scaleRequest(5)).Allowed).To(BeTrue())
+		Expect(validator.Handle(ctx, scaleRequest(4)).Allowed).To(BeFalse())
+	})
+
+	It("should reject scaling in place below 3 replicas through the scale subresource", func() {
+		rcp.Spec.RolloutStrategy = &RolloutStrategy{RollingUpdate: &RollingUpdate{MaxSurge: &intstr.IntOrString{Type: intstr.Int, IntVal: 0}}}
+		validator := newScaleValidator(rcp)
+
+		Expect(validator.Handle(ctx, scaleRequest(3)).Allowed).To(BeTrue())
+		resp := validator.Handle(ctx, scaleRequest(1))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring("maxSurge"))
+	})
+})
+
+// newScaleValidator returns a scale validator whose client holds the RKE2ControlPlane.
+func newScaleValidator(rcp *RKE2ControlPlane) *scaleValidator {
+	scheme := runtime.NewScheme()
+	Expect(autoscalingv1.AddToScheme(scheme)).To(Succeed())
+	Expect(AddToScheme(scheme)).To(Succeed())
+	decoder, err := admission.NewDecoder(scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	validator := &scaleValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(rcp).Build()}
+	Expect(validator.InjectDecoder(decoder)).To(Succeed())
+	return validator
+}
+
+// scaleRequest returns the request scaling the "rcp" RKE2ControlPlane of the default namespace.
+func scaleRequest(replicas int32) admission.Request {
+	raw, err := json.Marshal(&autoscalingv1.Scale{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "autoscaling/v1", Kind: "Scale"},
+		ObjectMeta: metav1.ObjectMeta{Name: "rcp", Namespace: "default"},
+		Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Update,
+		Name:      "rcp",
+		Namespace: "default",
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+var _ = Describe("RKE2ControlPlane worker version skew warnings", func() {
+	It("should warn about the RKE2ConfigTemplates out of the kubelet version skew", func() {
+		scheme := runtime.NewScheme()
+		Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+		Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+
+		machineDeployment := func(name, template string) *clusterv1.MachineDeployment {
+			return &clusterv1.MachineDeployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{clusterv1.ClusterLabelName: "cluster"}},
+				Spec: clusterv1.MachineDeploymentSpec{
+					ClusterName: "cluster",
+					Template: clusterv1.MachineTemplateSpec{Spec: clusterv1.MachineSpec{
+						ClusterName: "cluster",
+						Bootstrap: clusterv1.Bootstrap{ConfigRef: &corev1.ObjectReference{
+							APIVersion: bootstrapv1.GroupVersion.String(),
+							Kind:       "RKE2ConfigTemplate",
+							Name:       template,
+						}},
+					}},
+				},
+			}
+		}
+		configTemplate := func(name, version string) *bootstrapv1.RKE2ConfigTemplate {
+			template := &bootstrapv1.RKE2ConfigTemplate{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+			template.Spec.Template.Spec.AgentConfig.Version = version
+			return template
+		}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			machineDeployment("md-current", "current"),
+			machineDeployment("md-old", "old"),
+			machineDeployment("md-too-old", "too-old"),
+			machineDeployment("md-newer", "newer"),
+			configTemplate("current", "v1.26.0+rke2r1"),
+			configTemplate("old", "v1.24.6+rke2r1"),
+			configTemplate("too-old", "v1.23.9+rke2r1"),
+			configTemplate("newer", "v1.27.1+rke2r1"),
+		).Build()
+
+		rcp := &RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "rcp", Namespace: "default", Labels: map[string]string{clusterv1.ClusterLabelName: "cluster"}},
+			Spec:       RKE2ControlPlaneSpec{Version: "v1.26.0+rke2r1"},
+		}
+
+		warnings, err := workerVersionSkewWarnings(ctx, c, rcp)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).To(ConsistOf(
+			ContainSubstring("RKE2ConfigTemplate too-old used by MachineDeployment md-too-old"),
+			ContainSubstring("RKE2ConfigTemplate newer used by MachineDeployment md-newer"),
+		))
+	})
+})
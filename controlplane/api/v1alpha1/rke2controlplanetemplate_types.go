@@ -17,42 +17,94 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-)
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+)
 
-// RKE2ControlPlaneTemplateSpec defines the desired state of RKE2ControlPlaneTemplate
+// RKE2ControlPlaneTemplateSpec defines the desired state of RKE2ControlPlaneTemplate.
 type RKE2ControlPlaneTemplateSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Template describes the RKE2ControlPlane created from this template, e.g. by the topology controller of a ClusterClass.
+	Template RKE2ControlPlaneTemplateResource `json:"template"`
+}
 
-	// Foo is an example field of RKE2ControlPlaneTemplate. Edit rke2controlplanetemplate_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+// RKE2ControlPlaneTemplateResource describes the data needed to create a RKE2ControlPlane from a template.
+type RKE2ControlPlaneTemplateResource struct {
+	// Spec is the specification of the desired behavior of the control plane.
+	Spec RKE2ControlPlaneTemplateResourceSpec `json:"spec"`
 }
 
-// RKE2ControlPlaneTemplateStatus defines the observed state of RKE2ControlPlaneTemplate
-type RKE2ControlPlaneTemplateStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+// RKE2ControlPlaneTemplateResourceSpec defines the desired state of a RKE2ControlPlane created from a template.
+// It mirrors the RKE2ControlPlaneSpec, without the fields set per cluster: replicas, version and infrastructure reference.
+type RKE2ControlPlaneTemplateResourceSpec struct {
+	// RKE2AgentSpec contains the node spec for the RKE2 Control plane nodes.
+	bootstrapv1.RKE2ConfigSpec `json:",inline"`
+
+	// ServerConfig specifies configuration for the agent nodes.
+	//+optional
+	ServerConfig RKE2ServerConfig `json:"serverConfig,omitempty"`
+
+	// ManifestsConfigMapReference references a ConfigMap which contains Kubernetes manifests to be deployed automatically on the cluster
+	// Each data entry in the ConfigMap will be will be copied to a folder on the control plane nodes that RKE2 scans and uses to deploy manifests.
+	//+optional
+	ManifestsConfigMapReference corev1.ObjectReference `json:"manifestsConfigMapReference,omitempty"`
+
+	// NodeDrainTimeout is the total amount of time that the controller will spend on draining a controlplane node
+	// The default value is 0, meaning that the node can be drained without any time limitations.
+	// NOTE: NodeDrainTimeout is different from `kubectl drain --timeout`
+	// +optional
+	NodeDrainTimeout *metav1.Duration `json:"nodeDrainTimeout,omitempty"`
+
+	// RemediationStrategy is the RemediationStrategy that controls how control plane machine remediation happens.
+	// +optional
+	RemediationStrategy *RemediationStrategy `json:"remediationStrategy,omitempty"`
+
+	// RolloutStrategy is the RolloutStrategy to use to replace control plane machines with new ones.
+	// +optional
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// RolloutAfter is a field to indicate a rollout should be performed after the specified time
+	// even if no changes have been made to the RKE2ControlPlane; machines created before this time are replaced.
+	// +optional
+	RolloutAfter *metav1.Time `json:"rolloutAfter,omitempty"`
+
+	// CertificatesExpiryDays indicates a rollout needs to be performed if the certificates of the control plane
+	// machines will expire within the specified days. The expiry is read from the kube-apiserver serving
+	// certificate of each machine.
+	// +kubebuilder:validation:Minimum=7
+	// +optional
+	CertificatesExpiryDays *int32 `json:"certificatesExpiryDays,omitempty"`
+
+	// UpgradeStrategy defines how control plane machines are upgraded when only the RKE2 version changes.
+	// With InPlace, the system-upgrade-controller, which must be deployed in the workload cluster, upgrades the nodes
+	// through Plans instead of replacing the machines; any other change still triggers a replacement. The agent nodes
+	// are upgraded as well once the servers are, but the version of their MachineDeployments is not updated.
+	// Defaults to Replace.
+	// +kubebuilder:validation:Enum=Replace;InPlace
+	// +optional
+	UpgradeStrategy UpgradeStrategyType `json:"upgradeStrategy,omitempty"`
+
+	// ChannelAutoUpgrade moves the control plane to the latest version of its release channel when the channel
+	// catalogue changes, when the version is a release channel, one Kubernetes minor version at a time. Otherwise the
+	// version first resolved from the channel stays pinned until the channel is changed.
+	// +optional
+	ChannelAutoUpgrade bool `json:"channelAutoUpgrade,omitempty"`
 }
 
 //+kubebuilder:object:root=true
-//+kubebuilder:subresource:status
 
-// RKE2ControlPlaneTemplate is the Schema for the rke2controlplanetemplates API
+// RKE2ControlPlaneTemplate is the Schema for the rke2controlplanetemplates API.
 type RKE2ControlPlaneTemplate struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   RKE2ControlPlaneTemplateSpec   `json:"spec,omitempty"`
-	Status RKE2ControlPlaneTemplateStatus `json:"status,omitempty"`
+	Spec RKE2ControlPlaneTemplateSpec `json:"spec,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 
-// RKE2ControlPlaneTemplateList contains a list of RKE2ControlPlaneTemplate
+// RKE2ControlPlaneTemplateList contains a list of RKE2ControlPlaneTemplate.
 type RKE2ControlPlaneTemplateList struct {
 	metav1.TypeMeta `json:",inline"`
 	metav1.ListMeta `json:"metadata,omitempty"`
@@ -17,10 +17,14 @@ limitations under the License.
 package v1alpha1
 
 import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
 )
 
 // log is for logging in this package.
@@ -50,14 +54,14 @@ var _ webhook.Validator = &RKE2ControlPlaneTemplate{}
 func (r *RKE2ControlPlaneTemplate) ValidateCreate() error {
 	rke2controlplanetemplatelog.Info("validate create", "name", r.Name)
 
-	return nil
+	return r.validate()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *RKE2ControlPlaneTemplate) ValidateUpdate(old runtime.Object) error {
 	rke2controlplanetemplatelog.Info("validate update", "name", r.Name)
 
-	return nil
+	return r.validate()
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -66,3 +70,12 @@ func (r *RKE2ControlPlaneTemplate) ValidateDelete() error {
 
 	return nil
 }
+
+func (r *RKE2ControlPlaneTemplate) validate() error {
+	allErrs := bootstrapv1.ValidateRKE2ConfigSpec(field.NewPath("spec", "template", "spec"), &r.Spec.Template.Spec.RKE2ConfigSpec)
+	allErrs = append(allErrs, validateRolloutStrategy(field.NewPath("spec", "template", "spec", "rolloutStrategy"), r.Spec.Template.Spec.RolloutStrategy, nil)...)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("RKE2ControlPlaneTemplate").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/secret"
+)
+
+// caRotationStep is a step of a CA rotation, applied to the CA secrets when entering a phase.
+type caRotationStep func(ctx context.Context, c client.Client, clusterName client.ObjectKey, purpose secret.Purpose) error
+
+// caRotationPhases describes the phases of a CA rotation, in order, with the step entering each phase
+// and the reason reported while the control plane machines are rolled out.
+var caRotationPhases = []struct {
+	phase  controlplanev1.CARotationPhase
+	step   caRotationStep
+	reason string
+}{
+	{controlplanev1.CARotationTrustingNewCA, secret.TrustNextCA, controlplanev1.CARotationTrustingNewCAReason},
+	{controlplanev1.CARotationSigningWithNewCA, secret.SignWithNextCA, controlplanev1.CARotationSigningWithNewCAReason},
+	{controlplanev1.CARotationDroppingOldCA, secret.DropPreviousCA, controlplanev1.CARotationDroppingOldCAReason},
+}
+
+// reconcileCARotation drives the rotation of the cluster CAs requested with the rotate-ca annotation.
+// Each phase updates the CA secrets and regenerates the kubeconfig, then the control plane machines created
+// before the phase started are rolled out by the regular rollout operation (see ControlPlane.MachinesRolloutReasons);
+// the next phase starts once all the machines have been replaced and are ready.
+// Worker machines get the CA bundle and their client certificates from the servers when joining, so the old CAs
+// are only dropped once all the worker machines have been created after the new CAs started signing;
+// their rollout is left to the user.
+func (r *RKE2ControlPlaneReconciler) reconcileCARotation(ctx context.Context, cluster *clusterv1.Cluster, controlPlane *rke2.ControlPlane) error {
+	logger := controlPlane.Logger()
+	rcp := controlPlane.RCP
+
+	request := rcp.Annotations[controlplanev1.CARotationAnnotation]
+	rotation := rcp.Status.CARotation
+
+	if rotation == nil || rotation.Phase == controlplanev1.CARotationCompleted {
+		if request == "" || (rotation != nil && rotation.Request == request) {
+			return nil
+		}
+
+		// Only CAs generated by the provider can be rotated, the external ones have to be rotated by the user.
+		for _, purpose := range secret.RotatedCAs {
+			s, err := secret.GetFromNamespacedName(ctx, r.Client, util.ObjectKey(cluster), purpose)
+			if err != nil {
+				return errors.Wrapf(err, "failed to get %s CA secret", purpose)
+			}
+			if secret.IsExternal(s) {
+				conditions.MarkFalse(rcp, controlplanev1.CARotationCompletedCondition, controlplanev1.CARotationFailedReason, clusterv1.ConditionSeverityError,
+					"Secret %s is provided by the user, it can't be rotated", s.Name)
+				return nil
+			}
+		}
+
+		logger.Info("Starting CA rotation", "request", request)
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "CARotationStarted", "Starting the rotation of the cluster CAs, request %q", request)
+		return r.enterCARotationPhase(ctx, cluster, controlPlane, request, 0)
+	}
+
+	current := -1
+	for i := range caRotationPhases {
+		if caRotationPhases[i].phase == rotation.Phase {
+			current = i
+		}
+	}
+	if current < 0 {
+		return errors.Errorf("unknown CA rotation phase %q", rotation.Phase)
+	}
+
+	// Wait for all the machines created before the phase started to be replaced by ready machines.
+	outdated := controlPlane.Machines.Filter(func(m *clusterv1.Machine) bool {
+		return m.CreationTimestamp.Before(&rotation.PhaseStartTime)
+	})
+	notReady := controlPlane.Machines.Filter(collections.Not(collections.IsReady()))
+	if len(outdated) > 0 || len(notReady) > 0 || len(controlPlane.Machines) != int(*rcp.Spec.Replicas) {
+		conditions.MarkFalse(rcp, controlplanev1.CARotationCompletedCondition, caRotationPhases[current].reason, clusterv1.ConditionSeverityInfo,
+			"Rolling out control plane machines: %d outdated, %d not ready", len(outdated), len(notReady))
+		return nil
+	}
+
+	if current+1 < len(caRotationPhases) && caRotationPhases[current+1].phase == controlplanev1.CARotationDroppingOldCA {
+		workers, err := r.managementCluster.GetMachinesForCluster(ctx, util.ObjectKey(cluster), collections.Not(collections.ControlPlaneMachines(cluster.Name)))
+		if err != nil {
+			return errors.Wrap(err, "failed to get worker machines")
+		}
+		outdatedWorkers := workers.Filter(func(m *clusterv1.Machine) bool {
+			return m.CreationTimestamp.Before(&rotation.PhaseStartTime)
+		})
+		if len(outdatedWorkers) > 0 {
+			conditions.MarkFalse(rcp, controlplanev1.CARotationCompletedCondition, controlplanev1.CARotationWaitingForWorkersReason, clusterv1.ConditionSeverityWarning,
+				"Waiting for %d worker machines created before the %s phase to be rolled out, e.g. with clusterctl alpha rollout restart, before dropping the old CAs",
+				len(outdatedWorkers), rotation.Phase)
+			return nil
+		}
+	}
+
+	if current+1 < len(caRotationPhases) {
+		return r.enterCARotationPhase(ctx, cluster, controlPlane, rotation.Request, current+1)
+	}
+
+	logger.Info("CA rotation completed", "request", rotation.Request)
+	r.recorder.Eventf(rcp, corev1.EventTypeNormal, "CARotationCompleted", "Completed the rotation of the cluster CAs, request %q", rotation.Request)
+	rotation.Phase = controlplanev1.CARotationCompleted
+	rotation.PhaseStartTime = metav1.Now()
+	conditions.MarkTrue(rcp, controlplanev1.CARotationCompletedCondition)
+	return nil
+}
+
+// enterCARotationPhase applies the step of the given phase to the CA secrets, regenerates the kubeconfig,
+// and records the phase so the control plane machines get rolled out.
+func (r *RKE2ControlPlaneReconciler) enterCARotationPhase(ctx context.Context, cluster *clusterv1.Cluster, controlPlane *rke2.ControlPlane, request string, index int) error {
+	rcp := controlPlane.RCP
+	phase := caRotationPhases[index]
+
+	for _, purpose := range secret.RotatedCAs {
+		if err := phase.step(ctx, r.Client, util.ObjectKey(cluster), purpose); err != nil {
+			conditions.MarkFalse(rcp, controlplanev1.CARotationCompletedCondition, controlplanev1.CARotationFailedReason, clusterv1.ConditionSeverityError, err.Error())
+			return err
+		}
+	}
+
+	if _, err := r.reconcileKubeconfig(ctx, util.ObjectKey(cluster), cluster.Spec.ControlPlaneEndpoint, rcp); err != nil {
+		return errors.Wrap(err, "failed to regenerate the kubeconfig after updating the cluster CAs")
+	}
+
+	controlPlane.Logger().Info("CA rotation phase started", "phase", phase.phase)
+	r.recorder.Eventf(rcp, corev1.EventTypeNormal, "CARotationPhase", "CA rotation entering phase %s", phase.phase)
+	rcp.Status.CARotation = &controlplanev1.CARotationStatus{
+		Request:        request,
+		Phase:          phase.phase,
+		PhaseStartTime: metav1.Now(),
+	}
+	conditions.MarkFalse(rcp, controlplanev1.CARotationCompletedCondition, phase.reason, clusterv1.ConditionSeverityInfo,
+		"Rolling out control plane machines")
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/secret"
+)
+
+var _ = Describe("CA rotation", func() {
+	var (
+		ctx          context.Context
+		c            client.Client
+		r            *RKE2ControlPlaneReconciler
+		cluster      *clusterv1.Cluster
+		controlPlane *rke2.ControlPlane
+	)
+
+	newClusterMachine := func(name string, controlPlane bool, created time.Time) *clusterv1.Machine {
+		machine := newMachine(name)
+		machine.Labels = map[string]string{clusterv1.ClusterLabelName: cluster.Name}
+		machine.CreationTimestamp = metav1.NewTime(created)
+		if controlPlane {
+			machine.Labels[clusterv1.MachineControlPlaneLabelName] = ""
+		}
+		conditions.MarkTrue(machine, clusterv1.ReadyCondition)
+		return machine
+	}
+
+	// replaceControlPlaneMachine simulates the rollout of the control plane machine.
+	replaceControlPlaneMachine := func() {
+		controlPlane.Machines = collections.FromMachines(newClusterMachine("cp-new", true, time.Now().Add(time.Hour)))
+	}
+
+	phase := func() controlplanev1.CARotationPhase {
+		return controlPlane.RCP.Status.CARotation.Phase
+	}
+
+	reason := func() string {
+		return conditions.GetReason(controlPlane.RCP, controlplanev1.CARotationCompletedCondition)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		cluster = &clusterv1.Cluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: clusterv1.ClusterSpec{
+				ControlPlaneEndpoint: clusterv1.APIEndpoint{Host: "example.com", Port: 6443},
+			},
+		}
+		rcp := &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "default",
+				UID:         "rcp-uid",
+				Annotations: map[string]string{controlplanev1.CARotationAnnotation: "r1"},
+			},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{Replicas: pointer.Int32(1)},
+		}
+
+		before := time.Now().Add(-time.Hour)
+		c = newFakeClient(
+			newClusterMachine("cp-old", true, before),
+			newClusterMachine("worker-old", false, before),
+		)
+		r = newFakeReconciler(c, &fakeWorkloadCluster{})
+
+		owner := *metav1.NewControllerRef(rcp, controlplanev1.GroupVersion.WithKind("RKE2ControlPlane"))
+		Expect(secret.NewCertificatesForInitialControlPlane().LookupOrGenerate(ctx, c, util.ObjectKey(cluster), owner)).To(Succeed())
+
+		controlPlane = &rke2.ControlPlane{
+			RCP:      rcp,
+			Cluster:  cluster,
+			Machines: collections.FromMachines(newClusterMachine("cp-old", true, before)),
+		}
+	})
+
+	It("should go through the phases as the machines are rolled out", func() {
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+		Expect(phase()).To(Equal(controlplanev1.CARotationTrustingNewCA))
+		Expect(controlPlane.RCP.Status.CARotation.Request).To(Equal("r1"))
+
+		// The phase is held until the control plane machine is replaced.
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+		Expect(phase()).To(Equal(controlplanev1.CARotationTrustingNewCA))
+		Expect(reason()).To(Equal(controlplanev1.CARotationTrustingNewCAReason))
+
+		replaceControlPlaneMachine()
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+		Expect(phase()).To(Equal(controlplanev1.CARotationSigningWithNewCA))
+
+		// The old CAs are not dropped while workers created before the new CAs signed remain.
+		controlPlane.RCP.Status.CARotation.PhaseStartTime = metav1.NewTime(time.Now().Add(-time.Minute))
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+		Expect(phase()).To(Equal(controlplanev1.CARotationSigningWithNewCA))
+		Expect(reason()).To(Equal(controlplanev1.CARotationWaitingForWorkersReason))
+		Expect(conditions.GetSeverity(controlPlane.RCP, controlplanev1.CARotationCompletedCondition)).To(HaveValue(Equal(clusterv1.ConditionSeverityWarning)))
+
+		Expect(c.Delete(ctx, newClusterMachine("worker-old", false, time.Now()))).To(Succeed())
+		Expect(c.Create(ctx, newClusterMachine("worker-new", false, time.Now().Add(time.Hour)))).To(Succeed())
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+		Expect(phase()).To(Equal(controlplanev1.CARotationDroppingOldCA))
+
+		replaceControlPlaneMachine()
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+		Expect(phase()).To(Equal(controlplanev1.CARotationCompleted))
+		Expect(conditions.IsTrue(controlPlane.RCP, controlplanev1.CARotationCompletedCondition)).To(BeTrue())
+
+		// The same request is not rotated again.
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+		Expect(phase()).To(Equal(controlplanev1.CARotationCompleted))
+	})
+
+	It("should wait for all the replicas to be ready", func() {
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+
+		notReady := newClusterMachine("cp-new", true, time.Now().Add(time.Hour))
+		conditions.MarkFalse(notReady, clusterv1.ReadyCondition, "Provisioning", clusterv1.ConditionSeverityInfo, "")
+		controlPlane.Machines = collections.FromMachines(notReady)
+		Expect(r.reconcileCARotation(ctx, cluster, controlPlane)).To(Succeed())
+		Expect(phase()).To(Equal(controlplanev1.CARotationTrustingNewCA))
+	})
+})
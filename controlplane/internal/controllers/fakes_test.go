@@ -0,0 +1,138 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// newFakeClient returns a fake client holding the given objects, with the types used by the control plane controller.
+func newFakeClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+	Expect(controlplanev1.AddToScheme(scheme)).To(Succeed())
+	Expect(bootstrapv1.AddToScheme(scheme)).To(Succeed())
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+// newFakeReconciler returns a control plane reconciler using the client, whose management cluster returns the
+// fake workload cluster.
+func newFakeReconciler(c client.Client, workload *fakeWorkloadCluster) *RKE2ControlPlaneReconciler {
+	return &RKE2ControlPlaneReconciler{
+		Client:            c,
+		apiReader:         c,
+		recorder:          record.NewFakeRecorder(32),
+		managementCluster: &fakeManagementCluster{Management: &rke2.Management{Client: c}, workload: workload},
+	}
+}
+
+// newMachine returns a machine of the "test" cluster, in the default namespace, with a node named after it.
+func newMachine(name string) *clusterv1.Machine {
+	return &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       clusterv1.MachineSpec{ClusterName: "test"},
+		Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: name + "-node"}},
+	}
+}
+
+// fakeManagementCluster is a management cluster returning a fake workload cluster.
+type fakeManagementCluster struct {
+	*rke2.Management
+	workload *fakeWorkloadCluster
+}
+
+func (f *fakeManagementCluster) GetWorkloadCluster(_ context.Context, _ client.ObjectKey) (rke2.WorkloadCluster, error) {
+	return f.workload, nil
+}
+
+// fakeWorkloadCluster records the operations on the etcd members and the upgrade Plans of the workload cluster,
+// and reports the versions and certificates expiry of its nodes.
+type fakeWorkloadCluster struct {
+	rke2.WorkloadCluster
+
+	etcdMembers        []string
+	removedEtcdMembers []string
+
+	plans        map[string]string
+	plansDeleted bool
+	planErr      error
+	nodeVersions map[string]string
+
+	certificateExpiries map[string]time.Time
+}
+
+func (f *fakeWorkloadCluster) ClusterStatus(_ context.Context) (rke2.ClusterStatus, error) {
+	return rke2.ClusterStatus{Nodes: int32(len(f.etcdMembers)), ReadyNodes: int32(len(f.etcdMembers))}, nil
+}
+
+func (f *fakeWorkloadCluster) EtcdMembers(_ context.Context) ([]string, error) {
+	return f.etcdMembers, nil
+}
+
+func (f *fakeWorkloadCluster) ForwardEtcdLeadership(_ context.Context, _ *clusterv1.Machine, _ *clusterv1.Machine) error {
+	return nil
+}
+
+func (f *fakeWorkloadCluster) RemoveEtcdMemberForMachine(_ context.Context, machine *clusterv1.Machine) error {
+	f.removedEtcdMembers = append(f.removedEtcdMembers, machine.Status.NodeRef.Name)
+	return nil
+}
+
+func (f *fakeWorkloadCluster) EnsureUpgradePlan(_ context.Context, version, _ string, agents bool) error {
+	if f.planErr != nil {
+		return f.planErr
+	}
+	name := rke2.ServerUpgradePlanName
+	if agents {
+		name = rke2.AgentUpgradePlanName
+	}
+	f.plans[name] = version
+	return nil
+}
+
+func (f *fakeWorkloadCluster) DeleteUpgradePlans(_ context.Context) error {
+	f.plansDeleted = true
+	return nil
+}
+
+func (f *fakeWorkloadCluster) NodeVersions(_ context.Context) (map[string]string, error) {
+	return f.nodeVersions, nil
+}
+
+func (f *fakeWorkloadCluster) GetAPIServerCertificateExpiry(_ context.Context, nodeName string) (*time.Time, error) {
+	expiry, ok := f.certificateExpiries[nodeName]
+	if !ok {
+		return nil, errors.Errorf("no kube-apiserver pod found on node %s", nodeName)
+	}
+	return &expiry, nil
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+	bsutil "github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/util"
+)
+
+const (
+	// inPlaceUpgradeTimeoutPerMachine is how long each control plane machine has to be upgraded in place,
+	// before the upgrade is considered failed and the remaining machines are replaced.
+	inPlaceUpgradeTimeoutPerMachine = 30 * time.Minute
+
+	// inPlaceUpgradeRequeueAfter is how often the node versions are checked during an in-place upgrade.
+	inPlaceUpgradeRequeueAfter = 30 * time.Second
+)
+
+// reconcileInPlaceUpgrade upgrades the RKE2 version of the control plane machines in place, when the upgrade strategy
+// is InPlace and the version is the only change: the server Plan is applied in the workload cluster, the machines are
+// updated as their node reports the new version, then the agent Plan is applied. It returns false when the machines
+// have to be replaced instead, i.e. when other changes need a rollout or when the in-place upgrade failed.
+// NOTE: the agent Plan upgrades the nodes of all the other machines of the cluster, e.g. of MachineDeployments, but their
+// Machine spec.version is left unchanged, as it's owned by the MachineSets; the version of the MachineDeployments has
+// to be updated by the user so new worker machines get the new version, which also rolls out the existing ones.
+func (r *RKE2ControlPlaneReconciler) reconcileInPlaceUpgrade(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	controlPlane *rke2.ControlPlane,
+	needRollout collections.Machines,
+) (ctrl.Result, bool, error) {
+	logger := controlPlane.Logger()
+	rcp := controlPlane.RCP
+	version := rcp.GetDesiredVersion()
+
+	if rcp.Spec.UpgradeStrategy != controlplanev1.InPlaceUpgradeStrategyType || !rcp.Status.Initialized {
+		return ctrl.Result{}, false, nil
+	}
+	if status := rcp.Status.InPlaceUpgrade; status != nil && status.Version == version && status.Failed {
+		return ctrl.Result{}, false, nil
+	}
+
+	machines := controlPlane.MachinesNeedingInPlaceUpgrade()
+	if len(machines) != len(needRollout) || len(machines.Filter(collections.Not(hasNodeRef))) > 0 {
+		logger.Info("Control plane machines need a rollout for other changes than the version, replacing them")
+		return ctrl.Result{}, false, nil
+	}
+
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(cluster))
+	if err != nil {
+		return ctrl.Result{}, true, errors.Wrap(err, "failed to get remote client for workload cluster")
+	}
+
+	if status := rcp.Status.InPlaceUpgrade; status == nil || status.Version != version {
+		logger.Info("Starting in-place upgrade", "version", version)
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "InPlaceUpgradeStarted", "Upgrading control plane nodes in place to %s", version)
+		rcp.Status.InPlaceUpgrade = &controlplanev1.InPlaceUpgradeStatus{
+			Version:   version,
+			StartTime: metav1.Now(),
+		}
+	}
+	status := rcp.Status.InPlaceUpgrade
+
+	registry := rcp.Spec.AgentConfig.SystemDefaultRegistry
+	if err := workloadCluster.EnsureUpgradePlan(ctx, version, registry, false); err != nil {
+		if errors.Is(err, rke2.ErrUpgradePlansUnavailable) {
+			return r.failInPlaceUpgrade(ctx, controlPlane, workloadCluster, err.Error())
+		}
+		return ctrl.Result{}, true, err
+	}
+
+	nodeVersions, err := workloadCluster.NodeVersions(ctx)
+	if err != nil {
+		return ctrl.Result{}, true, err
+	}
+
+	status.PendingMachines = []string{}
+	for _, machine := range machines {
+		if nodeVersions[machine.Status.NodeRef.Name] != version {
+			status.PendingMachines = append(status.PendingMachines, machine.Name)
+			continue
+		}
+		if err := r.markMachineUpgraded(ctx, rcp, machine, version); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		logger.Info("Control plane machine upgraded in place", "machine", machine.Name, "version", version)
+	}
+	sort.Strings(status.PendingMachines)
+
+	if len(status.PendingMachines) == 0 {
+		// The servers are upgraded, the agents can follow.
+		if err := workloadCluster.EnsureUpgradePlan(ctx, version, registry, true); err != nil {
+			return ctrl.Result{}, true, err
+		}
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "InPlaceUpgradeCompleted",
+			"Control plane nodes upgraded in place to %s, the agent nodes are upgraded next; the version of their MachineDeployments has to be updated separately", version)
+		conditions.MarkTrue(rcp, controlplanev1.InPlaceUpgradeCompletedCondition)
+		return ctrl.Result{}, true, nil
+	}
+
+	timeout := inPlaceUpgradeTimeoutPerMachine * time.Duration(len(controlPlane.Machines))
+	if time.Since(status.StartTime.Time) > timeout {
+		return r.failInPlaceUpgrade(ctx, controlPlane, workloadCluster,
+			fmt.Sprintf("machines %v did not report version %s after %s", status.PendingMachines, version, timeout))
+	}
+
+	conditions.MarkFalse(rcp, controlplanev1.InPlaceUpgradeCompletedCondition, controlplanev1.InPlaceUpgradeInProgressReason, clusterv1.ConditionSeverityInfo,
+		"Upgrading to %s: %d of %d machines upgraded", version, len(machines)-len(status.PendingMachines), len(machines))
+	return ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter}, true, nil
+}
+
+// reconcileInPlaceUpgradeCompletion deletes the upgrade Plans once all the nodes of the workload cluster, the agents
+// included, report the version of a completed in-place upgrade, and then clears the in-place upgrade status. The Plans
+// are kept until then, as deleting the agent Plan would stop the upgrade of the agent nodes not upgraded yet.
+// It returns true while the agent nodes are still being upgraded.
+func (r *RKE2ControlPlaneReconciler) reconcileInPlaceUpgradeCompletion(
+	ctx context.Context,
+	cluster *clusterv1.Cluster,
+	controlPlane *rke2.ControlPlane,
+) (bool, error) {
+	rcp := controlPlane.RCP
+	status := rcp.Status.InPlaceUpgrade
+
+	if status == nil || status.Failed || !conditions.IsTrue(rcp, controlplanev1.InPlaceUpgradeCompletedCondition) {
+		return false, nil
+	}
+
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(cluster))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get remote client for workload cluster")
+	}
+
+	nodeVersions, err := workloadCluster.NodeVersions(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, nodeVersion := range nodeVersions {
+		if nodeVersion != status.Version {
+			return true, nil
+		}
+	}
+
+	if err := workloadCluster.DeleteUpgradePlans(ctx); err != nil {
+		return false, err
+	}
+	controlPlane.Logger().Info("All nodes upgraded in place, upgrade Plans deleted", "version", status.Version)
+	rcp.Status.InPlaceUpgrade = nil
+	return false, nil
+}
+
+// failInPlaceUpgrade records the failure of an in-place upgrade, and deletes the upgrade Plans so the machines
+// can be replaced without the system-upgrade-controller interfering.
+func (r *RKE2ControlPlaneReconciler) failInPlaceUpgrade(
+	ctx context.Context,
+	controlPlane *rke2.ControlPlane,
+	workloadCluster rke2.WorkloadCluster,
+	message string,
+) (ctrl.Result, bool, error) {
+	rcp := controlPlane.RCP
+
+	controlPlane.Logger().Info("In-place upgrade failed, replacing the remaining machines", "reason", message)
+	r.recorder.Eventf(rcp, corev1.EventTypeWarning, "InPlaceUpgradeFailed", "In-place upgrade failed, replacing the remaining machines: %s", message)
+	rcp.Status.InPlaceUpgrade.Failed = true
+	conditions.MarkFalse(rcp, controlplanev1.InPlaceUpgradeCompletedCondition, controlplanev1.InPlaceUpgradeFailedReason, clusterv1.ConditionSeverityWarning, message)
+
+	if err := workloadCluster.DeleteUpgradePlans(ctx); err != nil {
+		return ctrl.Result{}, true, err
+	}
+	return ctrl.Result{}, false, nil
+}
+
+// markMachineUpgraded updates the Kubernetes and RKE2 versions of a machine upgraded in place, and its stored server configuration,
+// so it is no longer considered as needing rollout. Its certificates expiry is dropped, to be read again from the
+// restarted node.
+func (r *RKE2ControlPlaneReconciler) markMachineUpgraded(ctx context.Context, rcp *controlplanev1.RKE2ControlPlane, machine *clusterv1.Machine, version string) error {
+	serverConfig, err := json.Marshal(rcp.Spec.ServerConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cluster configuration")
+	}
+
+	kubeVersion, err := bsutil.Rke2ToKubeVersion(version)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert rke2 version to kubernetes version")
+	}
+
+	patchHelper, err := patch.NewHelper(machine, r.Client)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create PatchHelper for Machine/%s", machine.Name)
+	}
+	machine.Spec.Version = &kubeVersion
+	annotations.AddAnnotations(machine, map[string]string{
+		controlplanev1.RKE2ServerConfigurationAnnotation: string(serverConfig),
+		controlplanev1.RKE2VersionAnnotation:             version,
+	})
+	delete(machine.Annotations, clusterv1.MachineCertificatesExpiryDateAnnotation)
+	return errors.Wrapf(patchHelper.Patch(ctx, machine), "failed to patch Machine/%s with its new version", machine.Name)
+}
+
+func hasNodeRef(machine *clusterv1.Machine) bool {
+	return machine.Status.NodeRef != nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+)
+
+var _ = Describe("in-place upgrades", func() {
+	const version = "v1.25.6+rke2r1"
+
+	var (
+		ctx          context.Context
+		c            client.Client
+		r            *RKE2ControlPlaneReconciler
+		workload     *fakeWorkloadCluster
+		controlPlane *rke2.ControlPlane
+	)
+
+	// reconcile runs an in-place upgrade step, with the machines needing a rollout at that time.
+	reconcile := func() (ctrl.Result, bool, error) {
+		return r.reconcileInPlaceUpgrade(ctx, controlPlane.Cluster, controlPlane, controlPlane.MachinesNeedingRollout())
+	}
+
+	machineVersion := func(name string) string {
+		machine := &clusterv1.Machine{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, machine)).To(Succeed())
+		return *machine.Spec.Version
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		m1, m2 := newMachine("m1"), newMachine("m2")
+		m1.Spec.Version, m2.Spec.Version = pointer.String("v1.24.6"), pointer.String("v1.24.6")
+		m1.Annotations = map[string]string{clusterv1.MachineCertificatesExpiryDateAnnotation: "2023-06-01T00:00:00Z"}
+		c = newFakeClient(m1.DeepCopy(), m2.DeepCopy())
+		// The machines of the control plane are the ones of the client, so they can be patched.
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(m1), m1)).To(Succeed())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(m2), m2)).To(Succeed())
+
+		workload = &fakeWorkloadCluster{plans: map[string]string{}, nodeVersions: map[string]string{}}
+		r = newFakeReconciler(c, workload)
+
+		controlPlane = &rke2.ControlPlane{
+			RCP: &controlplanev1.RKE2ControlPlane{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+				Spec: controlplanev1.RKE2ControlPlaneSpec{
+					Version:         version,
+					Replicas:        pointer.Int32(2),
+					UpgradeStrategy: controlplanev1.InPlaceUpgradeStrategyType,
+				},
+				Status: controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+			},
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(m1, m2),
+		}
+	})
+
+	It("should upgrade the servers then the agents", func() {
+		result, handled, err := reconcile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(BeTrue())
+		Expect(result.RequeueAfter).To(Equal(inPlaceUpgradeRequeueAfter))
+		Expect(workload.plans).To(Equal(map[string]string{rke2.ServerUpgradePlanName: version}))
+		status := controlPlane.RCP.Status.InPlaceUpgrade
+		Expect(status.Version).To(Equal(version))
+		Expect(status.PendingMachines).To(Equal([]string{"m1", "m2"}))
+		Expect(conditions.GetReason(controlPlane.RCP, controlplanev1.InPlaceUpgradeCompletedCondition)).To(Equal(controlplanev1.InPlaceUpgradeInProgressReason))
+
+		// The machines are updated as their node reports the new version.
+		workload.nodeVersions["m1-node"] = version
+		_, handled, err = reconcile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(BeTrue())
+		Expect(controlPlane.RCP.Status.InPlaceUpgrade.PendingMachines).To(Equal([]string{"m2"}))
+		Expect(machineVersion("m1")).To(Equal("1.25.6"))
+		Expect(machineVersion("m2")).To(Equal("v1.24.6"))
+		Expect(controlPlane.Machines["m1"].Annotations).To(HaveKeyWithValue(controlplanev1.RKE2VersionAnnotation, version))
+		// The certificates expiry of the upgraded machine is read again from its restarted node.
+		Expect(controlPlane.Machines["m1"].Annotations).ToNot(HaveKey(clusterv1.MachineCertificatesExpiryDateAnnotation))
+		Expect(workload.plans).ToNot(HaveKey(rke2.AgentUpgradePlanName))
+
+		workload.nodeVersions["m2-node"] = version
+		result, handled, err = reconcile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(BeTrue())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(machineVersion("m2")).To(Equal("1.25.6"))
+		Expect(workload.plans).To(HaveKeyWithValue(rke2.AgentUpgradePlanName, version))
+		Expect(conditions.IsTrue(controlPlane.RCP, controlplanev1.InPlaceUpgradeCompletedCondition)).To(BeTrue())
+		Expect(controlPlane.MachinesNeedingRollout()).To(BeEmpty())
+	})
+
+	It("should delete the upgrade Plans once all the nodes are upgraded", func() {
+		workload.plans = map[string]string{rke2.ServerUpgradePlanName: version, rke2.AgentUpgradePlanName: version}
+		workload.nodeVersions = map[string]string{"m1-node": version, "m2-node": version, "worker-node": "v1.24.6+rke2r1"}
+		controlPlane.RCP.Status.InPlaceUpgrade = &controlplanev1.InPlaceUpgradeStatus{Version: version, PendingMachines: []string{}}
+		conditions.MarkTrue(controlPlane.RCP, controlplanev1.InPlaceUpgradeCompletedCondition)
+
+		// The Plans are kept while the agent nodes are upgraded.
+		agentsUpgrading, err := r.reconcileInPlaceUpgradeCompletion(ctx, controlPlane.Cluster, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(agentsUpgrading).To(BeTrue())
+		Expect(workload.plansDeleted).To(BeFalse())
+		Expect(controlPlane.RCP.Status.InPlaceUpgrade).ToNot(BeNil())
+
+		workload.nodeVersions["worker-node"] = version
+		agentsUpgrading, err = r.reconcileInPlaceUpgradeCompletion(ctx, controlPlane.Cluster, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(agentsUpgrading).To(BeFalse())
+		Expect(workload.plansDeleted).To(BeTrue())
+		Expect(controlPlane.RCP.Status.InPlaceUpgrade).To(BeNil())
+	})
+
+	It("should replace the machines when the upgrade times out", func() {
+		controlPlane.RCP.Status.InPlaceUpgrade = &controlplanev1.InPlaceUpgradeStatus{
+			Version:   version,
+			StartTime: metav1.NewTime(time.Now().Add(-2*inPlaceUpgradeTimeoutPerMachine - time.Minute)),
+		}
+
+		_, handled, err := reconcile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(BeFalse())
+		Expect(controlPlane.RCP.Status.InPlaceUpgrade.Failed).To(BeTrue())
+		Expect(workload.plansDeleted).To(BeTrue())
+		Expect(conditions.GetReason(controlPlane.RCP, controlplanev1.InPlaceUpgradeCompletedCondition)).To(Equal(controlplanev1.InPlaceUpgradeFailedReason))
+	})
+
+	It("should replace the machines when the upgrade Plans are unavailable", func() {
+		workload.planErr = errors.Wrap(rke2.ErrUpgradePlansUnavailable, "no Plan CRD")
+
+		_, handled, err := reconcile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(BeFalse())
+		Expect(controlPlane.RCP.Status.InPlaceUpgrade.Failed).To(BeTrue())
+		Expect(workload.plansDeleted).To(BeTrue())
+	})
+
+	It("should not retry a failed upgrade to the same version", func() {
+		controlPlane.RCP.Status.InPlaceUpgrade = &controlplanev1.InPlaceUpgradeStatus{Version: version, Failed: true}
+
+		_, handled, err := reconcile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(BeFalse())
+		Expect(workload.plans).To(BeEmpty())
+
+		// A new version is upgraded in place again.
+		controlPlane.RCP.Spec.Version = "v1.25.7+rke2r1"
+		_, handled, err = reconcile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(BeTrue())
+		Expect(controlPlane.RCP.Status.InPlaceUpgrade.Failed).To(BeFalse())
+		Expect(workload.plans).To(HaveKeyWithValue(rke2.ServerUpgradePlanName, "v1.25.7+rke2r1"))
+	})
+
+	It("should replace the machines needing other changes", func() {
+		controlPlane.Machines["m2"].Status.NodeRef = nil
+
+		_, handled, err := reconcile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(handled).To(BeFalse())
+		Expect(workload.plans).To(BeEmpty())
+	})
+})
@@ -0,0 +1,469 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+)
+
+// reconcileUnhealthyMachines tries to remediate RKE2ControlPlane unhealthy machines, i.e. machines marked by a
+// MachineHealthCheck, by deleting them one at a time when it is safe for etcd quorum; the machine is then
+// replaced by the regular scale up operation.
+func (r *RKE2ControlPlaneReconciler) reconcileUnhealthyMachines(ctx context.Context, controlPlane *rke2.ControlPlane) (ret ctrl.Result, retErr error) {
+	logger := controlPlane.Logger()
+
+	// Cleanup pending remediation actions not completed for any reasons (e.g. number of current replicas is less or equal to 1)
+	// if the underlying machine is now back to healthy / not deleting.
+	errList := []error{}
+	healthyMachines := controlPlane.HealthyMachines()
+	for _, m := range healthyMachines {
+		if conditions.IsTrue(m, clusterv1.MachineHealthCheckSucceededCondition) &&
+			conditions.IsFalse(m, clusterv1.MachineOwnerRemediatedCondition) &&
+			m.DeletionTimestamp.IsZero() {
+			patchHelper, err := patch.NewHelper(m, r.Client)
+			if err != nil {
+				errList = append(errList, errors.Wrapf(err, "failed to get PatchHelper for machine %s", m.Name))
+				continue
+			}
+
+			conditions.Delete(m, clusterv1.MachineOwnerRemediatedCondition)
+
+			if err := patchHelper.Patch(ctx, m, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+				clusterv1.MachineOwnerRemediatedCondition,
+			}}); err != nil {
+				errList = append(errList, errors.Wrapf(err, "failed to patch machine %s", m.Name))
+			}
+		}
+	}
+	if len(errList) > 0 {
+		return ctrl.Result{}, kerrors.NewAggregate(errList)
+	}
+
+	// Return early if another remediation is in progress, waiting for the replacement machine to be created.
+	if _, ok := controlPlane.RCP.Annotations[controlplanev1.RemediationInProgressAnnotation]; ok {
+		// The remediation in progress is completed by the scale up creating the replacement machine;
+		// if no scale up is required anymore (e.g. replicas have been reduced), the annotation is stale.
+		if !controlPlane.HasDeletingMachine() && controlPlane.Machines.Len() >= int(*controlPlane.RCP.Spec.Replicas) {
+			delete(controlPlane.RCP.Annotations, controlplanev1.RemediationInProgressAnnotation)
+			return ctrl.Result{}, nil
+		}
+		logger.Info("Another remediation is already in progress. Skipping remediation.")
+		return ctrl.Result{}, nil
+	}
+
+	// Gets all machines that have `MachineHealthCheckSucceeded=False` (indicating a problem was detected on the machine)
+	// and `MachineOwnerRemediated` present, indicating that this controller is responsible for performing remediation.
+	unhealthyMachines := controlPlane.UnhealthyMachines()
+
+	// If there are no unhealthy machines, return so RCP can proceed with other operations (ctrl.Result nil).
+	if len(unhealthyMachines) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	// Select the machine to be remediated, which is the oldest machine marked as unhealthy.
+	machineToBeRemediated := unhealthyMachines.Oldest()
+
+	// Returns if the machine is in the process of being deleted.
+	if !machineToBeRemediated.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	patchHelper, err := patch.NewHelper(machineToBeRemediated, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	defer func() {
+		// Always attempt to Patch the Machine conditions after each reconcileUnhealthyMachines.
+		if err := patchHelper.Patch(ctx, machineToBeRemediated, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
+			clusterv1.MachineOwnerRemediatedCondition,
+		}}); err != nil {
+			logger.Error(err, "Failed to patch control plane Machine", "machine", machineToBeRemediated.Name)
+			if retErr == nil {
+				retErr = errors.Wrapf(err, "failed to patch control plane Machine %s", machineToBeRemediated.Name)
+			}
+		}
+	}()
+
+	// Before starting remediation, run preflight checks in order to verify it is safe to remediate.
+	// If any of the following checks fails, we'll surface the reason in the MachineOwnerRemediated condition.
+	logger = logger.WithValues("machine", machineToBeRemediated.Name)
+	desiredReplicas := int(*controlPlane.RCP.Spec.Replicas)
+
+	// Check if RCP is allowed to remediate considering retry limits:
+	// - Remediation cannot happen because retryPeriod is not yet expired.
+	// - RCP already reached MaxRetries limit.
+	remediationInProgressData, canRemediate, err := checkRetryLimits(logger, machineToBeRemediated, controlPlane, time.Now())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !canRemediate {
+		return ctrl.Result{}, nil
+	}
+
+	// The cluster MUST have more than one replica, because this is the smallest cluster size that allows any etcd failure tolerance.
+	if controlPlane.Machines.Len() <= 1 {
+		logger.Info("A control plane machine needs remediation, but the number of current replicas is less or equal to 1. Skipping remediation", "replicas", controlPlane.Machines.Len())
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "RCP can't remediate if current replicas are less or equal to 1")
+		return ctrl.Result{}, nil
+	}
+
+	// The number of replicas MUST be equal to or greater than the desired replicas. This rule ensures that when the cluster
+	// is missing replicas, we skip remediation and instead perform regular scale up/rollout operations first.
+	if controlPlane.Machines.Len() < desiredReplicas {
+		logger.Info("A control plane machine needs remediation, but the current number of replicas is lower that expected. Skipping remediation", "replicas", desiredReplicas, "currentReplicas", controlPlane.Machines.Len())
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "RCP waiting for having at least %d control plane machines before triggering remediation", desiredReplicas)
+		return ctrl.Result{}, nil
+	}
+
+	// The cluster MUST have no machines with a deletion timestamp. This rule prevents RCP taking actions while the cluster is in a transitional state.
+	if controlPlane.HasDeletingMachine() {
+		logger.Info("A control plane machine needs remediation, but there are other control-plane machines being deleted. Skipping remediation")
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "RCP waiting for control plane machine deletion to complete before triggering remediation")
+		return ctrl.Result{}, nil
+	}
+
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
+	if err != nil {
+		logger.Error(err, "Failed to create client to workload cluster")
+		return ctrl.Result{}, errors.Wrapf(err, "failed to create client to workload cluster")
+	}
+
+	// Remediation MUST preserve etcd quorum. This rule ensures that we will not remove a member that would result in etcd
+	// losing a majority of members and thus become unable to field new requests.
+	canSafelyRemediate, err := canSafelyRemoveEtcdMember(ctx, workloadCluster, controlPlane, machineToBeRemediated)
+	if err != nil {
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationFailedReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, err
+	}
+	if !canSafelyRemediate {
+		logger.Info("A control plane machine needs remediation, but removing this machine could result in etcd quorum loss. Skipping remediation")
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "RCP can't remediate this machine because this could result in etcd loosing quorum")
+		return ctrl.Result{}, nil
+	}
+
+	// If the machine that is about to be deleted is the etcd leader, move it to the newest member available.
+	etcdLeaderCandidate := controlPlane.HealthyMachines().Newest()
+	if etcdLeaderCandidate == nil {
+		logger.Info("A control plane machine needs remediation, but there is no healthy machine to forward etcd leadership to")
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationFailedReason, clusterv1.ConditionSeverityWarning,
+			"A control plane machine needs remediation, but there is no healthy machine to forward etcd leadership to. Skipping remediation")
+		return ctrl.Result{}, nil
+	}
+	if err := workloadCluster.ForwardEtcdLeadership(ctx, machineToBeRemediated, etcdLeaderCandidate); err != nil {
+		if !errors.Is(err, rke2.ErrEtcdClientUnavailable) {
+			logger.Error(err, "Failed to move etcd leadership to candidate machine", "candidate", etcdLeaderCandidate.Name)
+			conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationFailedReason, clusterv1.ConditionSeverityError, err.Error())
+			return ctrl.Result{}, err
+		}
+		logger.Info("Skipping etcd leadership forwarding and member removal", "reason", err.Error())
+	} else if err := workloadCluster.RemoveEtcdMemberForMachine(ctx, machineToBeRemediated); err != nil {
+		logger.Error(err, "Failed to remove etcd member for machine")
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationFailedReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	if err := r.Client.Delete(ctx, machineToBeRemediated); err != nil && !apierrors.IsNotFound(err) {
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationFailedReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, errors.Wrapf(err, "failed to delete unhealthy machine %s", machineToBeRemediated.Name)
+	}
+
+	logger.Info("Remediating unhealthy machine", "retryCount", remediationInProgressData.RetryCount)
+	conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.RemediationInProgressReason, clusterv1.ConditionSeverityWarning, "")
+
+	// Set annotations tracking remediation details so they can be picked up by the machine
+	// that will be created as part of the scale up action that completes the remediation.
+	remediationInProgressValue, err := remediationInProgressData.Marshal()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	annotations.AddAnnotations(controlPlane.RCP, map[string]string{
+		controlplanev1.RemediationInProgressAnnotation: remediationInProgressValue,
+	})
+
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// checkRetryLimits checks if RCP is allowed to remediate considering retry limits:
+// - Remediation cannot happen because retryPeriod is not yet expired.
+// - RCP already reached the maximum number of retries for a machine.
+// NOTE: Counting the number of retries is required In order to prevent infinite remediation e.g. in case the
+// first Control Plane machine is failing due to quota issue.
+func checkRetryLimits(logger logr.Logger, machineToBeRemediated *clusterv1.Machine, controlPlane *rke2.ControlPlane, reconciliationTime time.Time) (*RemediationData, bool, error) {
+	// Get last remediation info from the machine.
+	var lastRemediationData *RemediationData
+	if value, ok := machineToBeRemediated.Annotations[controlplanev1.RemediationForAnnotation]; ok {
+		l, err := RemediationDataFromAnnotation(value)
+		if err != nil {
+			return nil, false, err
+		}
+		lastRemediationData = l
+	}
+
+	remediationInProgressData := &RemediationData{
+		Machine:    machineToBeRemediated.Name,
+		Timestamp:  metav1.Time{Time: reconciliationTime},
+		RetryCount: 0,
+	}
+
+	// If there is no last remediation, this is the first try of a new retry sequence.
+	if lastRemediationData == nil {
+		return remediationInProgressData, true, nil
+	}
+
+	// Gets MinHealthyPeriod and RetryPeriod from the remediation strategy, or use defaults.
+	strategy := controlPlane.RCP.Spec.RemediationStrategy
+	minHealthyPeriod := controlplanev1.DefaultMinHealthyPeriod
+	if strategy != nil && strategy.MinHealthyPeriod != nil {
+		minHealthyPeriod = strategy.MinHealthyPeriod.Duration
+	}
+	retryPeriod := time.Duration(0)
+	if strategy != nil {
+		retryPeriod = strategy.RetryPeriod.Duration
+	}
+
+	// Gets the timestamp of the last remediation; if missing, default to a value
+	// that ensures both MinHealthyPeriod and RetryPeriod are expired.
+	lastRemediationTime := reconciliationTime.Add(-2 * maxDuration(minHealthyPeriod, retryPeriod))
+	if !lastRemediationData.Timestamp.IsZero() {
+		lastRemediationTime = lastRemediationData.Timestamp.Time
+	}
+
+	// Once we get here we already know that there was a last remediation for the Machine.
+	// If the current remediation is happening after minHealthyPeriod is expired, then RCP considers this
+	// as a remediation for a new, unrelated problem and starts a new retry sequence.
+	if !lastRemediationTime.Add(minHealthyPeriod).After(reconciliationTime) {
+		return remediationInProgressData, true, nil
+	}
+
+	// If the remediation is for the same machine, carry over the retry count and the machine name of the sequence.
+	logger = logger.WithValues("remediationRetryFor", lastRemediationData.Machine)
+	remediationInProgressData.Machine = lastRemediationData.Machine
+	remediationInProgressData.RetryCount = lastRemediationData.RetryCount + 1
+
+	// Check if remediation can happen because retryPeriod is passed.
+	if lastRemediationTime.Add(retryPeriod).After(reconciliationTime) {
+		logger.Info(fmt.Sprintf("A control plane machine needs remediation, but the operation already failed in the latest %s. Skipping remediation", retryPeriod))
+		conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "RCP can't remediate this machine because the operation already failed in the latest %s (RetryPeriod)", retryPeriod)
+		return remediationInProgressData, false, nil
+	}
+
+	// Check if remediation can happen because of maxRetry is not reached yet, if defined.
+	if strategy != nil && strategy.MaxRetry != nil {
+		maxRetry := *strategy.MaxRetry
+		if remediationInProgressData.RetryCount > maxRetry {
+			logger.Info(fmt.Sprintf("A control plane machine needs remediation, but the operation already failed %d times (MaxRetry %d). Skipping remediation", remediationInProgressData.RetryCount, maxRetry))
+			conditions.MarkFalse(machineToBeRemediated, clusterv1.MachineOwnerRemediatedCondition, clusterv1.WaitingForRemediationReason, clusterv1.ConditionSeverityWarning, "RCP can't remediate this machine because the operation already failed %d times (MaxRetry)", maxRetry)
+			return remediationInProgressData, false, nil
+		}
+	}
+
+	return remediationInProgressData, true, nil
+}
+
+func maxDuration(x, y time.Duration) time.Duration {
+	if x < y {
+		return y
+	}
+	return x
+}
+
+// canSafelyRemoveEtcdMember assess if it is possible to remove the member hosted on the machine to be remediated
+// without loosing etcd quorum.
+//
+// The answer mostly depend on the existence of other failing members on top of the one being deleted, and according
+// to the etcd fault tolerance specification (see https://etcd.io/docs/v3.3/faq/#what-is-failure-tolerance):
+//   - 3 CP cluster does not tolerate additional failing members on top of the one being deleted (the target
+//     cluster size after deletion is 2, fault tolerance 0)
+//   - 5 CP cluster tolerates 1 additional failing members on top of the one being deleted (the target
+//     cluster size after deletion is 4, fault tolerance 1)
+//   - 7 CP cluster tolerates 2 additional failing members on top of the one being deleted (the target
+//     cluster size after deletion is 6, fault tolerance 2)
+//   - etc.
+//
+// When etcd can't be inspected because the etcd CA is not available, the projection is based on the control plane
+// machines and their agent health instead.
+//
+// NOTE: this func requires reconcileControlPlaneConditions to be called before it.
+func canSafelyRemoveEtcdMember(ctx context.Context, workloadCluster rke2.WorkloadCluster, controlPlane *rke2.ControlPlane, machineToBeRemediated *clusterv1.Machine) (bool, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	healthCondition := controlplanev1.MachineEtcdMemberHealthyCondition
+
+	// This makes it possible to have a set of etcd members status different from the MHC unhealthy/unhealthy conditions.
+	etcdMembers, err := workloadCluster.EtcdMembers(ctx)
+	if err != nil {
+		if !errors.Is(err, rke2.ErrEtcdClientUnavailable) {
+			return false, errors.Wrapf(err, "failed to get etcdStatus for workload cluster %s", controlPlane.Cluster.Name)
+		}
+		etcdMembers = []string{}
+		for _, m := range controlPlane.Machines {
+			if m.Status.NodeRef != nil {
+				etcdMembers = append(etcdMembers, m.Status.NodeRef.Name)
+			}
+		}
+		healthCondition = controlplanev1.MachineAgentHealthyCondition
+	}
+
+	currentTotalMembers := len(etcdMembers)
+
+	logger.Info("etcd cluster before remediation",
+		"currentTotalMembers", currentTotalMembers,
+		"currentMembers", etcdMembers)
+
+	// Projects the target etcd cluster after remediation, considering all the etcd members except the one being remediated.
+	targetTotalMembers := 0
+	targetUnhealthyMembers := 0
+
+	healthyMembers := []string{}
+	unhealthyMembers := []string{}
+	for _, etcdMember := range etcdMembers {
+		// Skip the machine to be deleted because it won't be part of the target etcd cluster.
+		if machineToBeRemediated.Status.NodeRef != nil && machineToBeRemediated.Status.NodeRef.Name == etcdMember {
+			continue
+		}
+
+		// Include the member in the target etcd cluster.
+		targetTotalMembers++
+
+		// Search for the machine corresponding to the etcd member.
+		var machine *clusterv1.Machine
+		for _, m := range controlPlane.Machines {
+			if m.Status.NodeRef != nil && m.Status.NodeRef.Name == etcdMember {
+				machine = m
+				break
+			}
+		}
+
+		// If an etcd member does not have a corresponding machine, it is not possible to retrieve etcd member health
+		// so we are assuming the worst scenario and considering the member unhealthy.
+		if machine == nil {
+			logger.Info("An etcd member does not have a corresponding machine, assuming this member is unhealthy", "memberName", etcdMember)
+			targetUnhealthyMembers++
+			unhealthyMembers = append(unhealthyMembers, fmt.Sprintf("%s (no machine)", etcdMember))
+			continue
+		}
+
+		// Check member health as reported by machine's health conditions
+		if !conditions.IsTrue(machine, healthCondition) {
+			targetUnhealthyMembers++
+			unhealthyMembers = append(unhealthyMembers, fmt.Sprintf("%s (%s)", etcdMember, machine.Name))
+			continue
+		}
+
+		healthyMembers = append(healthyMembers, fmt.Sprintf("%s (%s)", etcdMember, machine.Name))
+	}
+
+	// See https://etcd.io/docs/v3.3/faq/#what-is-failure-tolerance for fault tolerance formula explanation.
+	targetQuorum := (targetTotalMembers / 2.0) + 1
+	canSafelyRemediate := targetTotalMembers-targetUnhealthyMembers >= targetQuorum
+
+	logger.Info(fmt.Sprintf("etcd cluster projected after remediation of %s", machineToBeRemediated.Name),
+		"healthyMembers", healthyMembers,
+		"unhealthyMembers", unhealthyMembers,
+		"targetTotalMembers", targetTotalMembers,
+		"targetQuorum", targetQuorum,
+		"targetUnhealthyMembers", targetUnhealthyMembers,
+		"canSafelyRemediate", canSafelyRemediate)
+
+	return canSafelyRemediate, nil
+}
+
+// RemediationData struct is used to keep track of information stored in the RemediationInProgressAnnotation in RCP
+// during remediation and then into the RemediationForAnnotation on the replacement machine once it is created.
+type RemediationData struct {
+	// Machine is the machine name of the latest machine being remediated.
+	Machine string `json:"machine"`
+
+	// Timestamp is when last remediation happened. It is represented in RFC3339 form and is in UTC.
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// RetryCount used to keep track of remediation retry for the last remediated machine.
+	// A retry happens when a machine that was created as a replacement for an unhealthy machine also fails.
+	RetryCount int32 `json:"retryCount"`
+}
+
+// RemediationDataFromAnnotation gets RemediationData from an annotation value.
+func RemediationDataFromAnnotation(value string) (*RemediationData, error) {
+	ret := &RemediationData{}
+	if err := json.Unmarshal([]byte(value), ret); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal value %s for %s annotation", value, clusterv1.RemediationInProgressReason)
+	}
+	return ret, nil
+}
+
+// Marshal an RemediationData into an annotation value.
+func (r *RemediationData) Marshal() (string, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal value for %s annotation", clusterv1.RemediationInProgressReason)
+	}
+	return string(b), nil
+}
+
+// ToStatus converts a RemediationData into a LastRemediationStatus struct.
+func (r *RemediationData) ToStatus() *controlplanev1.LastRemediationStatus {
+	return &controlplanev1.LastRemediationStatus{
+		Machine:    r.Machine,
+		Timestamp:  r.Timestamp,
+		RetryCount: r.RetryCount,
+	}
+}
+
+// setLastRemediation sets the LastRemediation status of the RCP from the remediation in progress, if any,
+// or from the remediation annotation of the newest replacement machine.
+func setLastRemediation(rcp *controlplanev1.RKE2ControlPlane, controlPlane *rke2.ControlPlane) error {
+	if v, ok := rcp.Annotations[controlplanev1.RemediationInProgressAnnotation]; ok {
+		remediationData, err := RemediationDataFromAnnotation(v)
+		if err != nil {
+			return err
+		}
+		rcp.Status.LastRemediation = remediationData.ToStatus()
+		return nil
+	}
+
+	remediatingMachines := controlPlane.Machines.Filter(collections.HasAnnotationKey(controlplanev1.RemediationForAnnotation))
+	if remediatingMachines.Len() == 0 {
+		return nil
+	}
+	m := remediatingMachines.Newest()
+	remediationData, err := RemediationDataFromAnnotation(m.Annotations[controlplanev1.RemediationForAnnotation])
+	if err != nil {
+		return err
+	}
+	rcp.Status.LastRemediation = remediationData.ToStatus()
+	return nil
+}
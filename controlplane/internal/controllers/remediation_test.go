@@ -0,0 +1,105 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+)
+
+var _ = Describe("remediation retry limits", func() {
+	var (
+		now          time.Time
+		controlPlane *rke2.ControlPlane
+		machine      *clusterv1.Machine
+	)
+
+	withLastRemediation := func(m *clusterv1.Machine, data RemediationData) {
+		value, err := data.Marshal()
+		Expect(err).ToNot(HaveOccurred())
+		m.SetAnnotations(map[string]string{controlplanev1.RemediationForAnnotation: value})
+	}
+
+	BeforeEach(func() {
+		now = time.Now()
+		controlPlane = &rke2.ControlPlane{
+			RCP: &controlplanev1.RKE2ControlPlane{
+				Spec: controlplanev1.RKE2ControlPlaneSpec{
+					RemediationStrategy: &controlplanev1.RemediationStrategy{
+						MaxRetry:    pointer.Int32(2),
+						RetryPeriod: metav1.Duration{Duration: 10 * time.Minute},
+					},
+				},
+			},
+		}
+		machine = &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "m2"}}
+	})
+
+	It("should allow the first remediation of a machine", func() {
+		data, ok, err := checkRetryLimits(ctrl.Log, machine, controlPlane, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(data.Machine).To(Equal("m2"))
+		Expect(data.RetryCount).To(BeEquivalentTo(0))
+	})
+
+	It("should start a new retry sequence after the min healthy period", func() {
+		withLastRemediation(machine, RemediationData{Machine: "m1", Timestamp: metav1.Time{Time: now.Add(-2 * time.Hour)}, RetryCount: 2})
+
+		data, ok, err := checkRetryLimits(ctrl.Log, machine, controlPlane, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(data.Machine).To(Equal("m2"))
+		Expect(data.RetryCount).To(BeEquivalentTo(0))
+	})
+
+	It("should wait for the retry period before retrying", func() {
+		withLastRemediation(machine, RemediationData{Machine: "m1", Timestamp: metav1.Time{Time: now.Add(-5 * time.Minute)}})
+
+		_, ok, err := checkRetryLimits(ctrl.Log, machine, controlPlane, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(conditions.GetReason(machine, clusterv1.MachineOwnerRemediatedCondition)).To(Equal(clusterv1.WaitingForRemediationReason))
+	})
+
+	It("should retry the remediation after the retry period", func() {
+		withLastRemediation(machine, RemediationData{Machine: "m1", Timestamp: metav1.Time{Time: now.Add(-15 * time.Minute)}, RetryCount: 1})
+
+		data, ok, err := checkRetryLimits(ctrl.Log, machine, controlPlane, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(data.Machine).To(Equal("m1"))
+		Expect(data.RetryCount).To(BeEquivalentTo(2))
+	})
+
+	It("should stop remediating when max retry is reached", func() {
+		withLastRemediation(machine, RemediationData{Machine: "m1", Timestamp: metav1.Time{Time: now.Add(-15 * time.Minute)}, RetryCount: 2})
+
+		_, ok, err := checkRetryLimits(ctrl.Log, machine, controlPlane, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+})
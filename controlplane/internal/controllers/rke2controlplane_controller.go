@@ -19,6 +19,9 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -26,6 +29,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -47,6 +51,7 @@ import (
 
 	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
 	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/kubeconfig"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/locking"
 	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
 	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/secret"
 )
@@ -61,11 +66,15 @@ const (
 type RKE2ControlPlaneReconciler struct {
 	Log logr.Logger
 	client.Client
-	Scheme                    *runtime.Scheme
-	managementClusterUncached rke2.ManagementCluster
-	managementCluster         rke2.ManagementCluster
-	recorder                  record.EventRecorder
-	controller                controller.Controller
+	Scheme                      *runtime.Scheme
+	EtcdDialTimeout             time.Duration
+	KubeconfigRotationThreshold time.Duration
+	ChannelCatalogue            client.ObjectKey
+	managementClusterUncached   rke2.ManagementCluster
+	managementCluster           rke2.ManagementCluster
+	apiReader                   client.Reader
+	recorder                    record.EventRecorder
+	controller                  controller.Controller
 }
 
 //+kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=rke2controlplanes,verbs=get;list;watch;create;update;patch;delete
@@ -75,6 +84,9 @@ type RKE2ControlPlaneReconciler struct {
 // +kubebuilder:rbac:groups="",resources=secrets;events;configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="bootstrap.cluster.x-k8s.io",resources=rke2configs,verbs=get;list;watch;create;patch;delete
 // +kubebuilder:rbac:groups="infrastructure.cluster.x-k8s.io",resources=*,verbs=get;list;watch;create;patch;delete
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machinedeployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch
+// +kubebuilder:rbac:groups="bootstrap.cluster.x-k8s.io",resources=rke2configtemplates,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -223,15 +235,29 @@ func (r *RKE2ControlPlaneReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	if err != nil {
 		return errors.Wrap(err, "failed adding Watch for Clusters to controller manager")
 	}
+
+	if r.ChannelCatalogue.Name != "" {
+		err = c.Watch(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.channelCatalogueToRKE2ControlPlanes),
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed adding Watch for the channel catalogue to controller manager")
+		}
+	}
 	//r.Scheme = mgr.GetScheme()
 	r.controller = c
 	r.recorder = mgr.GetEventRecorderFor("rke2-control-plane-controller")
 
 	if r.managementCluster == nil {
-		r.managementCluster = &rke2.Management{Client: r.Client}
+		r.managementCluster = &rke2.Management{Client: r.Client, EtcdDialTimeout: r.EtcdDialTimeout}
 	}
 	if r.managementClusterUncached == nil {
-		r.managementClusterUncached = &rke2.Management{Client: mgr.GetAPIReader()}
+		r.managementClusterUncached = &rke2.Management{Client: mgr.GetAPIReader(), EtcdDialTimeout: r.EtcdDialTimeout}
+	}
+	if r.apiReader == nil {
+		// Leases are read without the cache, which would otherwise hold all the node Leases of the management cluster.
+		r.apiReader = mgr.GetAPIReader()
 	}
 	return nil
 }
@@ -255,6 +281,7 @@ func (r *RKE2ControlPlaneReconciler) updateStatus(ctx context.Context, rcp *cont
 		return err
 	}
 	rcp.Status.UpdatedReplicas = int32(len(controlPlane.UpToDateMachines()))
+	rcp.Status.MachinesNeedingRollout = machinesRolloutStatus(controlPlane.MachinesRolloutReasons())
 
 	replicas := int32(len(ownedMachines))
 	desiredReplicas := *rcp.Spec.Replicas
@@ -264,6 +291,11 @@ func (r *RKE2ControlPlaneReconciler) updateStatus(ctx context.Context, rcp *cont
 	rcp.Status.Replicas = replicas
 	rcp.Status.ReadyReplicas = 0
 	rcp.Status.UnavailableReplicas = replicas
+	rcp.Status.Selector = labels.SelectorFromSet(rke2.ControlPlaneLabelsForCluster(cluster.Name)).String()
+
+	if lowestVersion := ownedMachines.LowestVersion(); lowestVersion != nil {
+		rcp.Status.Version = lowestVersion
+	}
 
 	// Return early if the deletion timestamp is set, because we don't want to try to connect to the workload cluster
 	// and we don't want to report resize condition (because it is set to deleting into reconcile delete).
@@ -271,6 +303,11 @@ func (r *RKE2ControlPlaneReconciler) updateStatus(ctx context.Context, rcp *cont
 		return nil
 	}
 
+	// Surface the last remediation, either still in progress on the RCP or completed on the replacement machine.
+	if err := setLastRemediation(rcp, controlPlane); err != nil {
+		return err
+	}
+
 	switch {
 	// We are scaling up
 	case replicas < desiredReplicas:
@@ -344,9 +381,28 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(ctx context.Context, cluste
 		return ctrl.Result{}, nil
 	}
 
+	// Pin the version before the machines are compared with it, when it is a release channel.
+	if err := r.reconcileVersionChannel(ctx, cluster, rcp); err != nil {
+		logger.Error(err, "failed to resolve the version channel")
+		return ctrl.Result{}, err
+	}
+
 	certificates := secret.NewCertificatesForInitialControlPlane()
 	controllerRef := metav1.NewControllerRef(rcp, controlplanev1.GroupVersion.WithKind("RKE2ControlPlane"))
-	if err := certificates.LookupOrGenerate(ctx, r.Client, util.ObjectKey(cluster), *controllerRef); err != nil {
+	var err error
+	if rcp.Status.Initialized {
+		// Once the cluster is initialized, missing CAs have been generated by RKE2 itself on the first server
+		// (clusters created before the provider managed them): generating new ones would not match the cluster.
+		err = certificates.Lookup(ctx, r.Client, util.ObjectKey(cluster))
+	} else {
+		err = certificates.LookupOrGenerate(ctx, r.Client, util.ObjectKey(cluster), *controllerRef)
+	}
+	switch {
+	case errors.Is(err, secret.ErrInvalidExternalCertificate):
+		logger.Error(err, "invalid cluster certificates provided")
+		conditions.MarkFalse(rcp, controlplanev1.CertificatesAvailableCondition, controlplanev1.CertificatesInvalidReason, clusterv1.ConditionSeverityError, err.Error())
+		return ctrl.Result{}, err
+	case err != nil:
 		logger.Error(err, "unable to lookup or create cluster certificates")
 		conditions.MarkFalse(rcp, controlplanev1.CertificatesAvailableCondition, controlplanev1.CertificatesGenerationFailedReason, clusterv1.ConditionSeverityWarning, err.Error())
 		return ctrl.Result{}, err
@@ -396,12 +452,46 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(ctx context.Context, cluste
 		return result, err
 	}
 
+	// Record the certificates expiry of the control plane machines, so they are rolled out before their certificates expire.
+	if result, err := r.reconcileCertificateExpiries(ctx, controlPlane); err != nil || !result.IsZero() {
+		logger.Error(err, "failed to reconcile certificate expiries")
+		return result, err
+	}
+
+	// Replace the first machine if it can't initialize the cluster, so a new machine takes the init lock over.
+	if result, err := r.reconcileInitLockHolder(ctx, controlPlane); err != nil || !result.IsZero() {
+		logger.Error(err, "failed to reconcile the init lock holder")
+		return result, err
+	}
+
+	// Reconcile unhealthy machines by triggering deletion and requeue if it is considered safe to remediate,
+	// otherwise continue with the other RCP operations.
+	if result, err := r.reconcileUnhealthyMachines(ctx, controlPlane); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	// Advance the rotation of the cluster CAs, if any; the machines to roll out are then picked up below.
+	if err := r.reconcileCARotation(ctx, cluster, controlPlane); err != nil {
+		logger.Error(err, "failed to reconcile CA rotation")
+		return ctrl.Result{}, err
+	}
+
 	// Control plane machines rollout due to configuration changes (e.g. upgrades) takes precedence over other operations.
 	needRollout := controlPlane.MachinesNeedingRollout()
 	switch {
 	case len(needRollout) > 0:
-		logger.Info("Rolling out Control Plane machines", "needRollout", needRollout.Names())
-		conditions.MarkFalse(controlPlane.RCP, controlplanev1.MachinesSpecUpToDateCondition, controlplanev1.RollingUpdateInProgressReason, clusterv1.ConditionSeverityWarning, "Rolling %d replicas with outdated spec (%d replicas up to date)", len(needRollout), len(controlPlane.Machines)-len(needRollout))
+		rolloutReasons := controlPlane.MachinesRolloutReasons()
+		logger.Info("Rolling out Control Plane machines", "needRollout", needRollout.Names(), "reasons", rolloutReasons)
+		for _, status := range newMachinesRolloutStatus(rcp.Status.MachinesNeedingRollout, rolloutReasons) {
+			r.recorder.Eventf(rcp, corev1.EventTypeNormal, "MachineNeedsRollout",
+				"Control plane Machine %s needs to be rolled out: %s", status.Machine, strings.Join(status.Reasons, ", "))
+		}
+		conditions.MarkFalse(controlPlane.RCP, controlplanev1.MachinesSpecUpToDateCondition, controlplanev1.RollingUpdateInProgressReason, clusterv1.ConditionSeverityWarning,
+			"Rolling %d replicas with outdated spec (%d replicas up to date): %s", len(needRollout), len(controlPlane.Machines)-len(needRollout), rolloutReasonsMessage(rolloutReasons))
+		// Version only changes are applied in place with the InPlace upgrade strategy, machines are replaced otherwise.
+		if result, handled, err := r.reconcileInPlaceUpgrade(ctx, cluster, controlPlane, needRollout); handled || err != nil {
+			return result, err
+		}
 		return r.upgradeControlPlane(ctx, cluster, rcp, controlPlane, needRollout)
 	default:
 		// make sure last upgrade operation is marked as completed.
@@ -412,6 +502,13 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(ctx context.Context, cluste
 		}
 	}
 
+	// Delete the upgrade Plans of a completed in-place upgrade once the agent nodes are upgraded too.
+	agentsUpgrading, err := r.reconcileInPlaceUpgradeCompletion(ctx, cluster, controlPlane)
+	if err != nil {
+		logger.Error(err, "failed to reconcile in-place upgrade completion")
+		return ctrl.Result{}, err
+	}
+
 	// If we've made it this far, we can assume that all ownedMachines are up to date
 	numMachines := len(ownedMachines)
 	desiredReplicas := int(*rcp.Spec.Replicas)
@@ -435,9 +532,107 @@ func (r *RKE2ControlPlaneReconciler) reconcileNormal(ctx context.Context, cluste
 		return r.scaleDownControlPlane(ctx, cluster, rcp, controlPlane, collections.Machines{})
 	}
 
+	// Requeue at the RolloutAfter deadline, so the rollout starts on time even if nothing else triggers a reconcile.
+	if rcp.Spec.RolloutAfter != nil && rcp.Spec.RolloutAfter.After(time.Now()) {
+		return ctrl.Result{RequeueAfter: time.Until(rcp.Spec.RolloutAfter.Time)}, nil
+	}
+
+	// Requeue when the certificates of a machine enter the CertificatesExpiryDays window.
+	if deadline := controlPlane.NextCertificatesExpiryRollout(); deadline != nil {
+		return ctrl.Result{RequeueAfter: time.Until(*deadline)}, nil
+	}
+
+	// Requeue while the agent nodes are upgraded in place, as their nodes don't trigger a reconcile.
+	if agentsUpgrading {
+		return ctrl.Result{RequeueAfter: inPlaceUpgradeRequeueAfter}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// reconcileCertificateExpiries sets the certificates-expiry annotation on the control plane machines missing it,
+// with the expiry of the kube-apiserver serving certificate of their node. The annotation is refreshed once it falls
+// within RCP.Spec.CertificatesExpiryDays, as RKE2 renews the certificates close to their expiry when restarted.
+func (r *RKE2ControlPlaneReconciler) reconcileCertificateExpiries(ctx context.Context, controlPlane *rke2.ControlPlane) (ctrl.Result, error) {
+	logger := controlPlane.Logger()
+
+	// Return if there are no RCP-owned control-plane machines, or no API server to contact yet.
+	if controlPlane.Machines.Len() == 0 || !controlPlane.RCP.Status.Initialized {
+		return ctrl.Result{}, nil
+	}
+
+	// Ignore machines which are being deleted, annotated with an expiry out of the window, or still provisioning.
+	expiring := controlPlane.MachinesWithExpiringCertificates()
+	machines := controlPlane.Machines.Filter(
+		collections.Not(collections.HasDeletionTimestamp),
+		collections.Or(
+			collections.Not(collections.HasAnnotationKey(clusterv1.MachineCertificatesExpiryDateAnnotation)),
+			func(machine *clusterv1.Machine) bool { _, ok := expiring[machine.Name]; return ok },
+		),
+		hasNodeRef,
+	)
+	if len(machines) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(controlPlane.Cluster))
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to reconcile certificate expiries: cannot get remote client to workload cluster")
+	}
+
+	for _, m := range machines {
+		// A machine whose certificates can't be read, e.g. because its node is down, must not block the remediation
+		// or the rollout which would replace it.
+		certificateExpiry, err := workloadCluster.GetAPIServerCertificateExpiry(ctx, m.Status.NodeRef.Name)
+		if err != nil {
+			logger.Error(err, "Failed to get the certificates expiry, skipping", "machine", m.Name)
+			continue
+		}
+		expiry := certificateExpiry.UTC().Format(time.RFC3339)
+
+		logger.V(2).Info("Setting certificate expiry", "machine", m.Name, "expiry", expiry)
+		patchHelper, err := patch.NewHelper(m, r.Client)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to create PatchHelper for Machine/%s", m.Name)
+		}
+		annotations.AddAnnotations(m, map[string]string{clusterv1.MachineCertificatesExpiryDateAnnotation: expiry})
+		if err := patchHelper.Patch(ctx, m); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to patch Machine/%s with its certificate expiry", m.Name)
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileInitLockHolder deletes the control plane machine holding the init lock before the cluster is initialized,
+// when it has no node after the TTL of the lock: only one machine is created before init, so another candidate to
+// take the lock over only exists once the holder is replaced.
+func (r *RKE2ControlPlaneReconciler) reconcileInitLockHolder(ctx context.Context, controlPlane *rke2.ControlPlane) (ctrl.Result, error) {
+	if controlPlane.RCP.Status.Initialized {
+		return ctrl.Result{}, nil
+	}
+
+	holder, err := locking.ExpiredInitLockHolder(ctx, r.apiReader, controlPlane.Cluster)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get the init lock holder")
+	}
+	if holder == nil {
+		return ctrl.Result{}, nil
+	}
+	machine, ok := controlPlane.Machines[holder.Name]
+	if !ok || !machine.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	controlPlane.Logger().Info("Deleting the machine holding the init lock without a node", "machine", machine.Name)
+	if err := r.Client.Delete(ctx, machine); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to delete Machine/%s holding the init lock", machine.Name)
+	}
+	r.recorder.Eventf(controlPlane.RCP, corev1.EventTypeWarning, "InitMachineReplaced",
+		"Control plane Machine %s did not get a node to initialize the cluster, replacing it", machine.Name)
+	return ctrl.Result{RequeueAfter: deleteRequeueAfter}, nil
+}
+
 func (r *RKE2ControlPlaneReconciler) reconcileDelete(ctx context.Context, cluster *clusterv1.Cluster, rcp *controlplanev1.RKE2ControlPlane) (res ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
 
@@ -539,6 +734,30 @@ func (r *RKE2ControlPlaneReconciler) reconcileKubeconfig(
 	if !util.IsControlledBy(configSecret, rcp) {
 		return ctrl.Result{}, nil
 	}
+
+	needsRotation, err := kubeconfig.NeedsClientCertRotation(configSecret, r.KubeconfigRotationThreshold)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	needsUpdate, err := kubeconfig.NeedsUpdate(ctx, r.Client, clusterName, configSecret, endpoint.String())
+	if err != nil {
+		if errors.Is(err, kubeconfig.ErrDependentCertificateNotFound) {
+			return ctrl.Result{RequeueAfter: dependentCertRequeueAfter}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if needsRotation || needsUpdate {
+		logger.Info("Regenerating kubeconfig secret", "clientCertificateExpiring", needsRotation, "endpointOrCAChanged", needsUpdate)
+		if err := kubeconfig.RegenerateSecret(ctx, r.Client, clusterName, configSecret, endpoint.String()); err != nil {
+			if errors.Is(err, kubeconfig.ErrDependentCertificateNotFound) {
+				return ctrl.Result{RequeueAfter: dependentCertRequeueAfter}, nil
+			}
+			return ctrl.Result{}, errors.Wrap(err, "failed to regenerate kubeconfig")
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -599,13 +818,89 @@ func (r *RKE2ControlPlaneReconciler) upgradeControlPlane(
 		return ctrl.Result{}, err
 	}
 
-	if status.Nodes <= *rcp.Spec.Replicas {
-		// scaleUp ensures that we don't continue scaling up while waiting for Machines to have NodeRefs
+	maxSurge := rcp.GetMaxSurge()
+	if maxSurge > 0 {
+		if status.Nodes < *rcp.Spec.Replicas+maxSurge {
+			// scaleUp ensures that we don't continue scaling up while waiting for Machines to have NodeRefs
+			return r.scaleUpControlPlane(ctx, cluster, rcp, controlPlane)
+		}
+		return r.scaleDownControlPlane(ctx, cluster, rcp, controlPlane, machinesRequireUpgrade)
+	}
+
+	// With maxSurge 0 the control plane is scaled in place: an outdated machine is deleted first, then its
+	// replacement is created, so the number of machines never exceeds the desired replicas.
+	upToDate := controlPlane.Machines.Len() - machinesRequireUpgrade.Len()
+	if int32(controlPlane.Machines.Len()) < *rcp.Spec.Replicas {
+		conditions.MarkFalse(rcp, controlplanev1.MachinesSpecUpToDateCondition, controlplanev1.RollingUpdateCreatingReplacementMachineReason, clusterv1.ConditionSeverityWarning,
+			"Creating a replacement machine (%d replicas with outdated spec, %d replicas up to date)", machinesRequireUpgrade.Len(), upToDate)
 		return r.scaleUpControlPlane(ctx, cluster, rcp, controlPlane)
 	}
+
+	machineToDelete, err := selectMachineForScaleDown(controlPlane, machinesRequireUpgrade)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to select machine for scale down")
+	}
+
+	// Deleting a machine before its replacement exists reduces the etcd cluster size, so ensure the etcd cluster
+	// can tolerate it before proceeding.
+	canSafelyDelete, err := canSafelyRemoveEtcdMember(ctx, workloadCluster, controlPlane, machineToDelete)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !canSafelyDelete {
+		logger.Info("Waiting for etcd members to be healthy before deleting an outdated machine", "machine", machineToDelete.Name)
+		conditions.MarkFalse(rcp, controlplanev1.MachinesSpecUpToDateCondition, controlplanev1.RollingUpdateWaitingForEtcdQuorumReason, clusterv1.ConditionSeverityWarning,
+			"Waiting for etcd members to be healthy before deleting outdated machine %s, deleting it now could result in etcd losing quorum", machineToDelete.Name)
+		return ctrl.Result{RequeueAfter: preflightFailedRequeueAfter}, nil
+	}
+
+	conditions.MarkFalse(rcp, controlplanev1.MachinesSpecUpToDateCondition, controlplanev1.RollingUpdateDeletingOutdatedMachineReason, clusterv1.ConditionSeverityWarning,
+		"Deleting outdated machine %s before creating its replacement (%d replicas with outdated spec, %d replicas up to date): %s",
+		machineToDelete.Name, machinesRequireUpgrade.Len(), upToDate, strings.Join(controlPlane.MachinesRolloutReasons()[machineToDelete.Name], ", "))
 	return r.scaleDownControlPlane(ctx, cluster, rcp, controlPlane, machinesRequireUpgrade)
 }
 
+// machinesRolloutStatus converts the rollout reasons of the control plane machines into their status, sorted by machine name.
+func machinesRolloutStatus(rolloutReasons map[string][]string) []controlplanev1.MachineRolloutStatus {
+	if len(rolloutReasons) == 0 {
+		return nil
+	}
+
+	result := make([]controlplanev1.MachineRolloutStatus, 0, len(rolloutReasons))
+	for machine, reasons := range rolloutReasons {
+		result = append(result, controlplanev1.MachineRolloutStatus{Machine: machine, Reasons: reasons})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Machine < result[j].Machine })
+	return result
+}
+
+// newMachinesRolloutStatus returns the rollout status of the machines which were not reported as needing a rollout in the
+// status yet, or with different reasons, so the machines needing a rollout are only reported once.
+func newMachinesRolloutStatus(reported []controlplanev1.MachineRolloutStatus, rolloutReasons map[string][]string) []controlplanev1.MachineRolloutStatus {
+	reportedReasons := map[string][]string{}
+	for _, status := range reported {
+		reportedReasons[status.Machine] = status.Reasons
+	}
+
+	result := []controlplanev1.MachineRolloutStatus{}
+	for _, status := range machinesRolloutStatus(rolloutReasons) {
+		if previous, ok := reportedReasons[status.Machine]; !ok || !reflect.DeepEqual(previous, status.Reasons) {
+			result = append(result, status)
+		}
+	}
+	return result
+}
+
+// rolloutReasonsMessage summarizes the rollout reasons of the control plane machines for condition messages,
+// e.g. "machine-a (version: v1.24.6 -> v1.25.6+rke2r1), machine-b (serverConfig.cni: "calico" -> "canal")".
+func rolloutReasonsMessage(rolloutReasons map[string][]string) string {
+	messages := []string{}
+	for _, status := range machinesRolloutStatus(rolloutReasons) {
+		messages = append(messages, fmt.Sprintf("%s (%s)", status.Machine, strings.Join(status.Reasons, ", ")))
+	}
+	return strings.Join(messages, ", ")
+}
+
 // ClusterToRKE2ControlPlane is a handler.ToRequestsFunc to be used to enqueue requests for reconciliation
 // for RKE2ControlPlane based on updates to a Cluster.
 func (r *RKE2ControlPlaneReconciler) ClusterToRKE2ControlPlane(o client.Object) []ctrl.Request {
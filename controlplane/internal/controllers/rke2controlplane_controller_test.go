@@ -0,0 +1,302 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+)
+
+var _ = Describe("newMachinesRolloutStatus", func() {
+	It("should only return the machines not reported yet or with different reasons", func() {
+		reported := []controlplanev1.MachineRolloutStatus{
+			{Machine: "m1", Reasons: []string{"rolloutAfter"}},
+			{Machine: "m2", Reasons: []string{"rolloutAfter"}},
+		}
+		rolloutReasons := map[string][]string{
+			"m1": {"rolloutAfter"},
+			"m2": {"rolloutAfter", "version: v1.24.6 -> v1.25.6+rke2r1"},
+			"m3": {"rolloutAfter"},
+		}
+
+		Expect(newMachinesRolloutStatus(reported, rolloutReasons)).To(Equal([]controlplanev1.MachineRolloutStatus{
+			{Machine: "m2", Reasons: []string{"rolloutAfter", "version: v1.24.6 -> v1.25.6+rke2r1"}},
+			{Machine: "m3", Reasons: []string{"rolloutAfter"}},
+		}))
+		Expect(newMachinesRolloutStatus(machinesRolloutStatus(rolloutReasons), rolloutReasons)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("upgradeControlPlane with maxSurge 0", func() {
+	var (
+		ctx      context.Context
+		c        client.Client
+		r        *RKE2ControlPlaneReconciler
+		workload *fakeWorkloadCluster
+		rcp      *controlplanev1.RKE2ControlPlane
+		cluster  *clusterv1.Cluster
+	)
+
+	// newControlPlane returns a control plane with the given number of outdated and healthy machines, m1 being the oldest.
+	newControlPlane := func(replicas int32, machines int) *rke2.ControlPlane {
+		rcp.Spec.Replicas = pointer.Int32(replicas)
+
+		owned := collections.New()
+		for i := 1; i <= machines; i++ {
+			machine := newMachine(fmt.Sprintf("m%d", i))
+			machine.CreationTimestamp = metav1.NewTime(time.Now().Add(time.Duration(i-10) * time.Minute))
+			machine.Spec.Version = pointer.String("v1.24.6")
+			conditions.MarkTrue(machine, controlplanev1.MachineAgentHealthyCondition)
+			conditions.MarkTrue(machine, controlplanev1.MachineEtcdMemberHealthyCondition)
+			Expect(c.Create(ctx, machine)).To(Succeed())
+			owned.Insert(machine)
+			workload.etcdMembers = append(workload.etcdMembers, machine.Status.NodeRef.Name)
+		}
+		return &rke2.ControlPlane{RCP: rcp, Cluster: cluster, Machines: owned}
+	}
+
+	upgrade := func(controlPlane *rke2.ControlPlane) (ctrl.Result, error) {
+		return r.upgradeControlPlane(ctx, cluster, rcp, controlPlane, controlPlane.MachinesNeedingRollout())
+	}
+
+	machineExists := func(name string) bool {
+		err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, &clusterv1.Machine{})
+		if apierrors.IsNotFound(err) {
+			return false
+		}
+		Expect(err).ToNot(HaveOccurred())
+		return true
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		c = newFakeClient()
+		workload = &fakeWorkloadCluster{}
+		r = newFakeReconciler(c, workload)
+
+		cluster = &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		maxSurge := intstr.FromInt(0)
+		rcp = &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: controlplanev1.RKE2ControlPlaneSpec{
+				Version:         "v1.25.6+rke2r1",
+				RolloutStrategy: &controlplanev1.RolloutStrategy{RollingUpdate: &controlplanev1.RollingUpdate{MaxSurge: &maxSurge}},
+			},
+			Status: controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+		}
+	})
+
+	It("should delete the oldest outdated machine before creating its replacement", func() {
+		controlPlane := newControlPlane(3, 3)
+
+		result, err := upgrade(controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Requeue).To(BeTrue())
+		Expect(machineExists("m1")).To(BeFalse())
+		Expect(machineExists("m2")).To(BeTrue())
+		Expect(machineExists("m3")).To(BeTrue())
+		Expect(workload.removedEtcdMembers).To(Equal([]string{"m1-node"}))
+		Expect(conditions.GetReason(rcp, controlplanev1.MachinesSpecUpToDateCondition)).To(Equal(controlplanev1.RollingUpdateDeletingOutdatedMachineReason))
+	})
+
+	It("should not delete a machine when etcd would lose quorum", func() {
+		controlPlane := newControlPlane(3, 3)
+		conditions.MarkFalse(controlPlane.Machines["m3"], controlplanev1.MachineEtcdMemberHealthyCondition, "Unhealthy", clusterv1.ConditionSeverityError, "")
+
+		result, err := upgrade(controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(preflightFailedRequeueAfter))
+		Expect(machineExists("m1")).To(BeTrue())
+		Expect(workload.removedEtcdMembers).To(BeEmpty())
+		Expect(conditions.GetReason(rcp, controlplanev1.MachinesSpecUpToDateCondition)).To(Equal(controlplanev1.RollingUpdateWaitingForEtcdQuorumReason))
+	})
+
+	It("should not delete the only machine of a single replica control plane", func() {
+		controlPlane := newControlPlane(1, 1)
+
+		result, err := upgrade(controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(preflightFailedRequeueAfter))
+		Expect(machineExists("m1")).To(BeTrue())
+		Expect(conditions.GetReason(rcp, controlplanev1.MachinesSpecUpToDateCondition)).To(Equal(controlplanev1.RollingUpdateWaitingForEtcdQuorumReason))
+	})
+
+	It("should wait for the deleted machine to be gone", func() {
+		controlPlane := newControlPlane(3, 3)
+		controlPlane.Machines["m1"].DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+		result, err := upgrade(controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(deleteRequeueAfter))
+		Expect(machineExists("m2")).To(BeTrue())
+		Expect(machineExists("m3")).To(BeTrue())
+		Expect(workload.removedEtcdMembers).To(BeEmpty())
+
+		// Once the machine is gone, its replacement is only created when no other machine is being deleted.
+		controlPlane.Machines = controlPlane.Machines.Filter(collections.Not(collections.HasDeletionTimestamp))
+		controlPlane.Machines["m2"].DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		result, err = upgrade(controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(deleteRequeueAfter))
+		Expect(conditions.GetReason(rcp, controlplanev1.MachinesSpecUpToDateCondition)).To(Equal(controlplanev1.RollingUpdateCreatingReplacementMachineReason))
+	})
+})
+
+var _ = Describe("reconcileCertificateExpiries", func() {
+	var (
+		ctx      context.Context
+		c        client.Client
+		r        *RKE2ControlPlaneReconciler
+		workload *fakeWorkloadCluster
+		expiry   time.Time
+	)
+
+	machineExpiry := func(name string) string {
+		machine := &clusterv1.Machine{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, machine)).To(Succeed())
+		return machine.Annotations[clusterv1.MachineCertificatesExpiryDateAnnotation]
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		expiry = time.Now().Add(365 * 24 * time.Hour).Truncate(time.Second).UTC()
+		c = newFakeClient()
+		workload = &fakeWorkloadCluster{certificateExpiries: map[string]time.Time{"m1-node": expiry, "m2-node": expiry}}
+		r = newFakeReconciler(c, workload)
+	})
+
+	It("should annotate the machines missing a certificates expiry or with an expiry in the window", func() {
+		days := int32(30)
+		inWindow := time.Now().Add(10 * 24 * time.Hour).UTC().Format(time.RFC3339)
+		outOfWindow := time.Now().Add(200 * 24 * time.Hour).UTC().Format(time.RFC3339)
+		machines := collections.New()
+		for name, recorded := range map[string]string{"m1": "", "m2": inWindow, "m3": outOfWindow, "m4": ""} {
+			machine := newMachine(name)
+			machine.Spec.InfrastructureRef = corev1.ObjectReference{
+				APIVersion: "infrastructure.cluster.x-k8s.io/v1beta1",
+				Kind:       "GenericInfrastructureMachine",
+				Name:       name,
+			}
+			if recorded != "" {
+				machine.Annotations = map[string]string{clusterv1.MachineCertificatesExpiryDateAnnotation: recorded}
+			}
+			Expect(c.Create(ctx, machine)).To(Succeed())
+			machines.Insert(machine)
+		}
+		// The certificates of the node of m4 can't be read, which does not prevent annotating the other machines.
+
+		rcp := &controlplanev1.RKE2ControlPlane{
+			Spec:   controlplanev1.RKE2ControlPlaneSpec{CertificatesExpiryDays: &days},
+			Status: controlplanev1.RKE2ControlPlaneStatus{Initialized: true},
+		}
+		cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		// The control plane is created for the current reconciliation, to compare the expiries with the current time.
+		controlPlane, err := rke2.NewControlPlane(ctx, c, cluster, rcp, machines)
+		Expect(err).ToNot(HaveOccurred())
+
+		result, err := r.reconcileCertificateExpiries(ctx, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(machineExpiry("m1")).To(Equal(expiry.Format(time.RFC3339)))
+		Expect(machineExpiry("m2")).To(Equal(expiry.Format(time.RFC3339)))
+		Expect(machineExpiry("m3")).To(Equal(outOfWindow))
+		Expect(machineExpiry("m4")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("reconcileInitLockHolder", func() {
+	var (
+		ctx          context.Context
+		c            client.Client
+		r            *RKE2ControlPlaneReconciler
+		controlPlane *rke2.ControlPlane
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acquired := metav1.NewMicroTime(time.Now().Add(-time.Hour))
+		lease := &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-init-lock", Namespace: "default"},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       pointer.String("m1"),
+				LeaseDurationSeconds: pointer.Int32(600),
+				AcquireTime:          &acquired,
+			},
+		}
+		m1 := newMachine("m1")
+		m1.Status.NodeRef = nil
+		c = newFakeClient(lease, m1)
+		r = newFakeReconciler(c, &fakeWorkloadCluster{})
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(m1), m1)).To(Succeed())
+		controlPlane = &rke2.ControlPlane{
+			RCP:      &controlplanev1.RKE2ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Cluster:  &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			Machines: collections.FromMachines(m1),
+		}
+	})
+
+	getMachine := func() error {
+		return c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "m1"}, &clusterv1.Machine{})
+	}
+
+	It("should replace the first machine without a node after the init lock TTL", func() {
+		result, err := r.reconcileInitLockHolder(ctx, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(deleteRequeueAfter))
+		Expect(apierrors.IsNotFound(getMachine())).To(BeTrue())
+		Expect(r.recorder.(*record.FakeRecorder).Events).To(Receive(ContainSubstring("InitMachineReplaced")))
+	})
+
+	It("should not replace the first machine once the cluster is initialized", func() {
+		controlPlane.RCP.Status.Initialized = true
+
+		result, err := r.reconcileInitLockHolder(ctx, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(getMachine()).To(Succeed())
+	})
+
+	It("should not replace the first machine with a node", func() {
+		m1 := controlPlane.Machines["m1"]
+		m1.Status.NodeRef = &corev1.ObjectReference{Name: "m1-node"}
+		Expect(c.Status().Update(ctx, m1)).To(Succeed())
+
+		result, err := r.reconcileInitLockHolder(ctx, controlPlane)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.IsZero()).To(BeTrue())
+		Expect(getMachine()).To(Succeed())
+	})
+})
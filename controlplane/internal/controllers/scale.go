@@ -115,32 +115,29 @@ func (r *RKE2ControlPlaneReconciler) scaleDownControlPlane(
 		return result, err
 	}
 
-	//workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(cluster))
-	//if err != nil {
-	//	logger.Error(err, "Failed to create client to workload cluster")
-	//	return ctrl.Result{}, errors.Wrapf(err, "failed to create client to workload cluster")
-	//}
-
 	if machineToDelete == nil {
 		logger.Info("Failed to pick control plane Machine to delete")
 		return ctrl.Result{}, errors.New("failed to pick control plane Machine to delete")
 	}
 
-	// TODO figure out etcd complexities
-	// If RCP should manage etcd, If etcd leadership is on machine that is about to be deleted, move it to the newest member available.
-	/**
-	if controlPlane.IsEtcdManaged() {
-		etcdLeaderCandidate := controlPlane.Machines.Newest()
-		if err := workloadCluster.ForwardEtcdLeadership(ctx, machineToDelete, etcdLeaderCandidate); err != nil {
+	workloadCluster, err := r.managementCluster.GetWorkloadCluster(ctx, util.ObjectKey(cluster))
+	if err != nil {
+		logger.Error(err, "Failed to create client to workload cluster")
+		return ctrl.Result{}, errors.Wrapf(err, "failed to create client to workload cluster")
+	}
+
+	// If etcd leadership is on machine that is about to be deleted, move it to the newest member available.
+	etcdLeaderCandidate := controlPlane.Machines.Newest()
+	if err := workloadCluster.ForwardEtcdLeadership(ctx, machineToDelete, etcdLeaderCandidate); err != nil {
+		if !errors.Is(err, rke2.ErrEtcdClientUnavailable) {
 			logger.Error(err, "Failed to move leadership to candidate machine", "candidate", etcdLeaderCandidate.Name)
 			return ctrl.Result{}, err
 		}
-		if err := workloadCluster.RemoveEtcdMemberForMachine(ctx, machineToDelete); err != nil {
-			logger.Error(err, "Failed to remove etcd member for machine")
-			return ctrl.Result{}, err
-		}
+		logger.Info("Skipping etcd leadership forwarding and member removal", "reason", err.Error())
+	} else if err := workloadCluster.RemoveEtcdMemberForMachine(ctx, machineToDelete); err != nil {
+		logger.Error(err, "Failed to remove etcd member for machine")
+		return ctrl.Result{}, err
 	}
-	**/
 
 	logger = logger.WithValues("machine", machineToDelete)
 	if err := r.Client.Delete(ctx, machineToDelete); err != nil && !apierrors.IsNotFound(err) {
@@ -179,6 +176,10 @@ func (r *RKE2ControlPlaneReconciler) preflightChecks(ctx context.Context, contro
 
 	// Check machine health conditions; if there are conditions with False or Unknown, then wait.
 	allMachineHealthConditions := []clusterv1.ConditionType{controlplanev1.MachineAgentHealthyCondition}
+	// etcd members are checked only when the etcd CA is available and the etcd cluster can be inspected.
+	if conditions.GetReason(controlPlane.RCP, controlplanev1.EtcdClusterHealthyCondition) != controlplanev1.EtcdClusterInspectionUnavailableReason {
+		allMachineHealthConditions = append(allMachineHealthConditions, controlplanev1.MachineEtcdMemberHealthyCondition)
+	}
 	machineErrors := []error{}
 
 loopmachines:
@@ -259,7 +260,7 @@ func (r *RKE2ControlPlaneReconciler) cloneConfigsAndGenerateMachine(ctx context.
 	// Clone the infrastructure template
 	infraRef, err := external.CreateFromTemplate(ctx, &external.CreateFromTemplateInput{
 		Client:      r.Client,
-		TemplateRef: &rcp.Spec.InfrastructureRef,
+		TemplateRef: rcp.GetInfrastructureRef(),
 		Namespace:   rcp.Namespace,
 		OwnerRef:    infraCloneOwner,
 		ClusterName: cluster.Name,
@@ -331,8 +332,10 @@ func (r *RKE2ControlPlaneReconciler) generateRKE2Config(ctx context.Context, rcp
 			Labels:          rke2.ControlPlaneLabelsForCluster(cluster.Name),
 			OwnerReferences: []metav1.OwnerReference{owner},
 		},
-		Spec: *spec,
+		Spec: *spec.DeepCopy(),
 	}
+	// The version may be set by the topology controller outside of the agent configuration.
+	bootstrapConfig.Spec.AgentConfig.Version = rcp.GetDesiredVersion()
 
 	if err := r.Client.Create(ctx, bootstrapConfig); err != nil {
 		return nil, errors.Wrap(err, "Failed to create bootstrap configuration")
@@ -350,17 +353,17 @@ func (r *RKE2ControlPlaneReconciler) generateRKE2Config(ctx context.Context, rcp
 }
 
 func (r *RKE2ControlPlaneReconciler) generateMachine(ctx context.Context, rcp *controlplanev1.RKE2ControlPlane, cluster *clusterv1.Cluster, infraRef, bootstrapRef *corev1.ObjectReference, failureDomain *string) error {
-	newVersion, err := bsutil.Rke2ToKubeVersion(rcp.Spec.AgentConfig.Version)
+	newVersion, err := bsutil.Rke2ToKubeVersion(rcp.GetDesiredVersion())
 	if err != nil {
 		return fmt.Errorf("failed to convert rke2 version to kubernetes version: %w", err)
 	}
 	logger := log.FromContext(ctx)
-	logger.Info("Version checking...", "rke2-version", rcp.Spec.AgentConfig.Version, "machine-version: ", newVersion)
+	logger.Info("Version checking...", "rke2-version", rcp.GetDesiredVersion(), "machine-version: ", newVersion)
 	machine := &clusterv1.Machine{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      names.SimpleNameGenerator.GenerateName(rcp.Name + "-"),
 			Namespace: rcp.Namespace,
-			Labels:    rke2.ControlPlaneLabelsForCluster(cluster.Name),
+			Labels:    machineLabels(rcp, cluster.Name),
 			OwnerReferences: []metav1.OwnerReference{
 				*metav1.NewControllerRef(rcp, controlplanev1.GroupVersion.WithKind("RKE2ControlPlane")),
 			},
@@ -373,7 +376,7 @@ func (r *RKE2ControlPlaneReconciler) generateMachine(ctx context.Context, rcp *c
 				ConfigRef: bootstrapRef,
 			},
 			FailureDomain:    failureDomain,
-			NodeDrainTimeout: rcp.Spec.NodeDrainTimeout,
+			NodeDrainTimeout: rcp.GetNodeDrainTimeout(),
 		},
 	}
 
@@ -385,10 +388,40 @@ func (r *RKE2ControlPlaneReconciler) generateMachine(ctx context.Context, rcp *c
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal cluster configuration")
 	}
-	machine.SetAnnotations(map[string]string{controlplanev1.RKE2ServerConfigurationAnnotation: string(serverConfig)})
+	annotations := map[string]string{}
+	for k, v := range rcp.Spec.MachineTemplate.ObjectMeta.Annotations {
+		annotations[k] = v
+	}
+	annotations[controlplanev1.RKE2ServerConfigurationAnnotation] = string(serverConfig)
+
+	// In case this machine is being created as a consequence of a remediation, then add an annotation
+	// tracking remediating data.
+	remediationData, isRemediation := rcp.Annotations[controlplanev1.RemediationInProgressAnnotation]
+	if isRemediation {
+		annotations[controlplanev1.RemediationForAnnotation] = remediationData
+	}
+	machine.SetAnnotations(annotations)
 
 	if err := r.Client.Create(ctx, machine); err != nil {
 		return errors.Wrap(err, "failed to create machine")
 	}
+
+	// Remove the annotation tracking that a remediation is in progress (the remediation completed when
+	// the replacement machine has been created above).
+	if isRemediation {
+		delete(rcp.Annotations, controlplanev1.RemediationInProgressAnnotation)
+	}
 	return nil
 }
+
+// machineLabels returns the labels of a control plane machine, including the labels of the RCP machine template.
+func machineLabels(rcp *controlplanev1.RKE2ControlPlane, clusterName string) map[string]string {
+	labels := map[string]string{}
+	for k, v := range rcp.Spec.MachineTemplate.ObjectMeta.Labels {
+		labels[k] = v
+	}
+	for k, v := range rke2.ControlPlaneLabelsForCluster(clusterName) {
+		labels[k] = v
+	}
+	return labels
+}
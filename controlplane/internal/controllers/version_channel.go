@@ -0,0 +1,206 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/util/version"
+)
+
+// reconcileVersionChannel pins the version of the control plane to an RKE2 version when it is a release channel,
+// resolving the channel from the catalogue. The control plane is only moved to versions it can be upgraded to directly
+// from the version it runs, i.e. the newest version of the catalogue up to the latest version of the channel which
+// doesn't skip Kubernetes minor versions. The pinned version then only changes when the channel is changed, or when the
+// catalogue has a newer version for the channel and ChannelAutoUpgrade is on; it never changes during a rollout.
+func (r *RKE2ControlPlaneReconciler) reconcileVersionChannel(ctx context.Context, cluster *clusterv1.Cluster, rcp *controlplanev1.RKE2ControlPlane) error {
+	logger := log.FromContext(ctx)
+
+	channel := rcp.GetVersionChannel()
+	if channel == "" {
+		rcp.Status.VersionChannel = nil
+		conditions.Delete(rcp, controlplanev1.VersionChannelResolvedCondition)
+		return nil
+	}
+
+	catalogue, latest, err := r.resolveVersionChannel(ctx, channel)
+	if err != nil {
+		conditions.MarkFalse(rcp, controlplanev1.VersionChannelResolvedCondition, controlplanev1.VersionChannelResolutionFailedReason,
+			clusterv1.ConditionSeverityWarning, err.Error())
+		if rcp.GetDesiredVersion() == "" {
+			return err
+		}
+		// Keep the version pinned so far.
+		logger.Error(err, "Failed to resolve the version channel, keeping the current version", "channel", channel, "version", rcp.GetDesiredVersion())
+		return nil
+	}
+
+	status := rcp.Status.VersionChannel
+	switch {
+	case status == nil || status.ResolvedVersion == "":
+		running, err := r.runningVersion(ctx, cluster, rcp)
+		if err != nil {
+			return err
+		}
+		if running == nil {
+			logger.Info("Pinning the version channel", "channel", channel, "version", latest)
+			rcp.Status.VersionChannel = &controlplanev1.VersionChannelStatus{Channel: channel, ResolvedVersion: latest}
+			break
+		}
+
+		// The control plane is already running, e.g. its version was changed from an RKE2 version to a channel.
+		target, err := catalogue.ResolveChannelUpgrade(channel, running)
+		if err != nil {
+			conditions.MarkFalse(rcp, controlplanev1.VersionChannelResolvedCondition, controlplanev1.VersionChannelUpgradeBlockedReason,
+				clusterv1.ConditionSeverityWarning, "Can't move to %s: %v", latest, err)
+			return err
+		}
+		logger.Info("Pinning the version channel", "channel", channel, "version", target, "latest", latest)
+		rcp.Status.VersionChannel = &controlplanev1.VersionChannelStatus{Channel: channel, ResolvedVersion: target}
+		if target != latest {
+			rcp.Status.VersionChannel.LatestVersion = latest
+		}
+	case status.ResolvedVersion == latest:
+		status.Channel = channel
+		status.LatestVersion = ""
+	case status.Channel == channel && !rcp.Spec.ChannelAutoUpgrade:
+		// The catalogue changed, the control plane only moves when asked to.
+		status.LatestVersion = latest
+	case len(rcp.Status.MachinesNeedingRollout) > 0:
+		status.LatestVersion = latest
+		conditions.MarkFalse(rcp, controlplanev1.VersionChannelResolvedCondition, controlplanev1.VersionChannelUpgradePendingReason,
+			clusterv1.ConditionSeverityInfo, "Waiting for the rollout to complete before moving to %s", latest)
+		return nil
+	default:
+		target, err := channelUpgradeTarget(catalogue, channel, status.ResolvedVersion)
+		if err != nil {
+			status.LatestVersion = latest
+			conditions.MarkFalse(rcp, controlplanev1.VersionChannelResolvedCondition, controlplanev1.VersionChannelUpgradeBlockedReason,
+				clusterv1.ConditionSeverityWarning, "Can't move to %s: %v", latest, err)
+			return nil
+		}
+
+		logger.Info("Moving to the latest version of the version channel", "channel", channel, "from", status.ResolvedVersion, "to", target)
+		r.recorder.Eventf(rcp, corev1.EventTypeNormal, "VersionChannelUpgrade", "Moving from %s to %s, the latest version of channel %s being %s",
+			status.ResolvedVersion, target, channel, latest)
+		rcp.Status.VersionChannel = &controlplanev1.VersionChannelStatus{Channel: channel, ResolvedVersion: target}
+		if target != latest {
+			rcp.Status.VersionChannel.LatestVersion = latest
+		}
+	}
+
+	conditions.MarkTrue(rcp, controlplanev1.VersionChannelResolvedCondition)
+	return nil
+}
+
+// resolveVersionChannel returns the channel catalogue and the latest version of a release channel in it.
+func (r *RKE2ControlPlaneReconciler) resolveVersionChannel(ctx context.Context, channel string) (*rke2.ChannelCatalogue, string, error) {
+	if r.ChannelCatalogue.Name == "" {
+		return nil, "", errors.Errorf("can't resolve channel %s, no channel catalogue is configured", channel)
+	}
+
+	catalogue, err := rke2.GetChannelCatalogue(ctx, r.Client, r.ChannelCatalogue)
+	if err != nil {
+		return nil, "", err
+	}
+	latest, err := catalogue.ResolveChannel(channel)
+	if err != nil {
+		return nil, "", err
+	}
+	return catalogue, latest, nil
+}
+
+// runningVersion returns the lowest RKE2 version the control plane machines run, or nil when there are no machines yet.
+// The RKE2 release matters as well as the Kubernetes version, so the control plane is never pinned to an older RKE2
+// release of the Kubernetes version it runs.
+func (r *RKE2ControlPlaneReconciler) runningVersion(ctx context.Context, cluster *clusterv1.Cluster, rcp *controlplanev1.RKE2ControlPlane) (*version.RKE2Version, error) {
+	machines, err := r.managementCluster.GetMachinesForCluster(ctx, util.ObjectKey(cluster),
+		collections.ControlPlaneMachines(cluster.Name), collections.OwnedMachines(rcp))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the control plane machines")
+	}
+	return rke2.LowestRKE2Version(ctx, r.Client, machines)
+}
+
+// channelUpgradeTarget returns the version of the catalogue the control plane pinned to a version of a channel moves to,
+// or an error when it can't move closer to the latest version of the channel.
+func channelUpgradeTarget(catalogue *rke2.ChannelCatalogue, channel, from string) (string, error) {
+	fromVersion, err := version.ParseRKE2Version(from)
+	if err != nil {
+		return "", err
+	}
+	target, err := catalogue.ResolveChannelUpgrade(channel, fromVersion)
+	if err != nil {
+		return "", err
+	}
+	if target == from {
+		latest, err := catalogue.ResolveChannel(channel)
+		if err != nil {
+			return "", err
+		}
+		return "", checkChannelUpgrade(from, latest)
+	}
+	return target, nil
+}
+
+// channelCatalogueToRKE2ControlPlanes maps the channel catalogue ConfigMap to the RKE2ControlPlanes whose version is a
+// release channel, so catalogue changes are picked up without waiting for a resync.
+func (r *RKE2ControlPlaneReconciler) channelCatalogueToRKE2ControlPlanes(o client.Object) []ctrl.Request {
+	if o.GetNamespace() != r.ChannelCatalogue.Namespace || o.GetName() != r.ChannelCatalogue.Name {
+		return nil
+	}
+
+	rcps := &controlplanev1.RKE2ControlPlaneList{}
+	if err := r.Client.List(context.TODO(), rcps); err != nil {
+		r.Log.Error(err, "Failed to list RKE2ControlPlanes")
+		return nil
+	}
+
+	requests := []ctrl.Request{}
+	for i := range rcps.Items {
+		if rcps.Items[i].GetVersionChannel() != "" {
+			requests = append(requests, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&rcps.Items[i])})
+		}
+	}
+	return requests
+}
+
+// checkChannelUpgrade checks the control plane can be upgraded from a version to another one, like the webhook does
+// for versions set in the spec.
+func checkChannelUpgrade(from, to string) error {
+	fromVersion, err := version.ParseRKE2Version(from)
+	if err != nil {
+		return err
+	}
+	toVersion, err := version.ParseRKE2Version(to)
+	if err != nil {
+		return err
+	}
+	return version.CheckUpgrade(fromVersion, toVersion)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
+)
+
+var _ = Describe("version channels", func() {
+	var (
+		catalogueKey = client.ObjectKey{Namespace: "rke2-system", Name: "channels"}
+		rcp          *controlplanev1.RKE2ControlPlane
+	)
+
+	// reconcilerWithCatalogue returns a reconciler with a catalogue of the stable channel and of the v1.24 to v1.26 channels.
+	reconcilerWithCatalogue := func(stable string, objects ...client.Object) *RKE2ControlPlaneReconciler {
+		catalogue := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: catalogueKey.Namespace, Name: catalogueKey.Name},
+			Data: map[string]string{
+				rke2.ChannelCatalogueDataKey: `{"data": [
+					{"name": "stable", "latest": "` + stable + `"},
+					{"name": "v1.24", "latest": "v1.24.13+rke2r1"},
+					{"name": "v1.25", "latest": "v1.25.9+rke2r1"},
+					{"name": "v1.26", "latest": "v1.26.4+rke2r1"}
+				]}`,
+			},
+		}
+		c := newFakeClient(append(objects, catalogue)...)
+		return &RKE2ControlPlaneReconciler{
+			Client:            c,
+			ChannelCatalogue:  catalogueKey,
+			recorder:          record.NewFakeRecorder(10),
+			managementCluster: &rke2.Management{Client: c},
+		}
+	}
+
+	// runningMachine returns a control plane machine of the RCP, and its RKE2Config with the given RKE2 version.
+	runningMachine := func(name, rke2Version string) []client.Object {
+		machine := newMachine(name)
+		machine.Labels = map[string]string{clusterv1.ClusterLabelName: "test", clusterv1.MachineControlPlaneLabelName: ""}
+		machine.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(rcp, controlplanev1.GroupVersion.WithKind("RKE2ControlPlane"))}
+		machine.Spec.Bootstrap.ConfigRef = &corev1.ObjectReference{Name: name}
+		config := &bootstrapv1.RKE2Config{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		config.Spec.AgentConfig.Version = rke2Version
+		return []client.Object{machine, config}
+	}
+
+	reconcile := func(r *RKE2ControlPlaneReconciler) error {
+		return r.reconcileVersionChannel(context.Background(), &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}, rcp)
+	}
+
+	BeforeEach(func() {
+		rcp = &controlplanev1.RKE2ControlPlane{
+			TypeMeta:   metav1.TypeMeta{APIVersion: controlplanev1.GroupVersion.String(), Kind: "RKE2ControlPlane"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec:       controlplanev1.RKE2ControlPlaneSpec{Version: "stable"},
+		}
+	})
+
+	It("should pin the version to the latest version of the channel", func() {
+		Expect(reconcile(reconcilerWithCatalogue("v1.25.9+rke2r1"))).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.25.9+rke2r1"))
+		Expect(conditions.IsTrue(rcp, controlplanev1.VersionChannelResolvedCondition)).To(BeTrue())
+	})
+
+	It("should fail until the channel can be resolved", func() {
+		r := &RKE2ControlPlaneReconciler{Client: newFakeClient()}
+		Expect(reconcile(r)).ToNot(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(BeEmpty())
+		Expect(conditions.GetReason(rcp, controlplanev1.VersionChannelResolvedCondition)).To(Equal(controlplanev1.VersionChannelResolutionFailedReason))
+	})
+
+	It("should stay pinned when the catalogue changes without auto-upgrade", func() {
+		rcp.Status.VersionChannel = &controlplanev1.VersionChannelStatus{Channel: "stable", ResolvedVersion: "v1.25.9+rke2r1"}
+
+		Expect(reconcile(reconcilerWithCatalogue("v1.26.4+rke2r1"))).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.25.9+rke2r1"))
+		Expect(rcp.Status.VersionChannel.LatestVersion).To(Equal("v1.26.4+rke2r1"))
+	})
+
+	It("should move to the latest version of the channel with auto-upgrade", func() {
+		rcp.Spec.ChannelAutoUpgrade = true
+		rcp.Status.VersionChannel = &controlplanev1.VersionChannelStatus{Channel: "stable", ResolvedVersion: "v1.25.9+rke2r1"}
+		r := reconcilerWithCatalogue("v1.26.4+rke2r1")
+
+		rcp.Status.MachinesNeedingRollout = []controlplanev1.MachineRolloutStatus{{Machine: "m1", Reasons: []string{"rolloutAfter"}}}
+		Expect(reconcile(r)).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.25.9+rke2r1"))
+		Expect(conditions.GetReason(rcp, controlplanev1.VersionChannelResolvedCondition)).To(Equal(controlplanev1.VersionChannelUpgradePendingReason))
+
+		rcp.Status.MachinesNeedingRollout = nil
+		Expect(reconcile(r)).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.26.4+rke2r1"))
+		Expect(rcp.Status.VersionChannel.LatestVersion).To(BeEmpty())
+	})
+
+	It("should move one minor version at a time", func() {
+		rcp.Spec.ChannelAutoUpgrade = true
+		rcp.Status.VersionChannel = &controlplanev1.VersionChannelStatus{Channel: "stable", ResolvedVersion: "v1.25.9+rke2r1"}
+		r := reconcilerWithCatalogue("v1.27.1+rke2r1")
+
+		Expect(reconcile(r)).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.26.4+rke2r1"))
+		Expect(rcp.Status.VersionChannel.LatestVersion).To(Equal("v1.27.1+rke2r1"))
+
+		Expect(reconcile(r)).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.27.1+rke2r1"))
+		Expect(rcp.Status.VersionChannel.LatestVersion).To(BeEmpty())
+	})
+
+	It("should not move to an older version", func() {
+		rcp.Spec.ChannelAutoUpgrade = true
+		rcp.Status.VersionChannel = &controlplanev1.VersionChannelStatus{Channel: "stable", ResolvedVersion: "v1.26.4+rke2r1"}
+
+		Expect(reconcile(reconcilerWithCatalogue("v1.25.9+rke2r1"))).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.26.4+rke2r1"))
+		Expect(conditions.GetReason(rcp, controlplanev1.VersionChannelResolvedCondition)).To(Equal(controlplanev1.VersionChannelUpgradeBlockedReason))
+	})
+
+	It("should pin a running control plane to a version it can be upgraded to", func() {
+		machines := append(runningMachine("m1", "v1.24.6+rke2r1"), runningMachine("m2", "v1.25.9+rke2r1")...)
+
+		Expect(reconcile(reconcilerWithCatalogue("v1.26.4+rke2r1", machines...))).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.25.9+rke2r1"))
+		Expect(rcp.Status.VersionChannel.LatestVersion).To(Equal("v1.26.4+rke2r1"))
+
+		rcp.Status.VersionChannel = nil
+		Expect(reconcile(reconcilerWithCatalogue("v1.25.9+rke2r1", runningMachine("m1", "v1.25.9+rke2r1")...))).To(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(Equal("v1.25.9+rke2r1"))
+		Expect(rcp.Status.VersionChannel.LatestVersion).To(BeEmpty())
+	})
+
+	It("should not pin a running control plane to an older version", func() {
+		Expect(reconcile(reconcilerWithCatalogue("v1.25.9+rke2r1", runningMachine("m1", "v1.26.4+rke2r1")...))).ToNot(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(BeEmpty())
+		Expect(conditions.GetReason(rcp, controlplanev1.VersionChannelResolvedCondition)).To(Equal(controlplanev1.VersionChannelUpgradeBlockedReason))
+
+		// A newer RKE2 release of the Kubernetes version of the channel is not downgraded either.
+		machine := runningMachine("m1", "v1.25.9+rke2r1")
+		machine[0].SetAnnotations(map[string]string{controlplanev1.RKE2VersionAnnotation: "v1.25.9+rke2r2"})
+		Expect(reconcile(reconcilerWithCatalogue("v1.25.9+rke2r1", machine...))).ToNot(Succeed())
+		Expect(rcp.GetDesiredVersion()).To(BeEmpty())
+	})
+
+	It("should map the catalogue to the control planes with a release channel", func() {
+		pinned := &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pinned"},
+			Spec:       controlplanev1.RKE2ControlPlaneSpec{Version: "v1.25.9+rke2r1"},
+		}
+		stable := &controlplanev1.RKE2ControlPlane{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "stable"},
+			Spec:       controlplanev1.RKE2ControlPlaneSpec{Version: "stable"},
+		}
+		r := reconcilerWithCatalogue("v1.25.9+rke2r1", pinned, stable)
+
+		catalogue := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: catalogueKey.Namespace, Name: catalogueKey.Name}}
+		Expect(r.channelCatalogueToRKE2ControlPlanes(catalogue)).To(ConsistOf(
+			ctrl.Request{NamespacedName: client.ObjectKeyFromObject(stable)},
+		))
+
+		other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: catalogueKey.Namespace, Name: "other"}}
+		Expect(r.channelCatalogueToRKE2ControlPlanes(other)).To(BeEmpty())
+	})
+
+	It("should clear the status when the version is no longer a channel", func() {
+		rcp.Spec.Version = "v1.25.9+rke2r1"
+		rcp.Status.VersionChannel = &controlplanev1.VersionChannelStatus{Channel: "stable", ResolvedVersion: "v1.25.9+rke2r1"}
+
+		Expect(reconcile(reconcilerWithCatalogue("v1.25.9+rke2r1"))).To(Succeed())
+		Expect(rcp.Status.VersionChannel).To(BeNil())
+		Expect(conditions.Has(rcp, controlplanev1.VersionChannelResolvedCondition)).To(BeFalse())
+	})
+})
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -30,6 +31,7 @@ import (
 	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
 	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
 	"github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/internal/controllers"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/rke2"
 	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -38,6 +40,7 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/certs"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -61,6 +64,9 @@ var (
 	webhookPort                 int
 	webhookCertDir              string
 	healthAddr                  string
+	etcdDialTimeout             time.Duration
+	kubeconfigRotationThreshold time.Duration
+	channelCatalogue            string
 )
 
 func init() {
@@ -109,6 +115,15 @@ func InitFlags(fs *pflag.FlagSet) {
 
 	fs.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
+
+	fs.DurationVar(&etcdDialTimeout, "etcd-dial-timeout-duration", 10*time.Second,
+		"Duration that the etcd client waits at most to establish a connection with etcd")
+
+	fs.DurationVar(&kubeconfigRotationThreshold, "kubeconfig-rotation-threshold", certs.ClientCertificateRenewalDuration,
+		"Remaining validity of the kubeconfig client certificate under which it is regenerated")
+
+	fs.StringVar(&channelCatalogue, "channel-catalogue", "",
+		fmt.Sprintf("ConfigMap holding the RKE2 release channel catalogue under the %s key, as namespace/name, used to resolve release channels set as versions", rke2.ChannelCatalogueDataKey))
 }
 
 func main() {
@@ -179,9 +194,22 @@ func setupChecks(mgr ctrl.Manager) {
 }
 
 func setupReconcilers(mgr ctrl.Manager) {
+	var channelCatalogueKey client.ObjectKey
+	if channelCatalogue != "" {
+		namespace, name, ok := strings.Cut(channelCatalogue, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(fmt.Errorf("invalid channel catalogue %q, expected namespace/name", channelCatalogue), "unable to create controller", "controller", "RKE2ControlPlane")
+			os.Exit(1)
+		}
+		channelCatalogueKey = client.ObjectKey{Namespace: namespace, Name: name}
+	}
+
 	if err := (&controllers.RKE2ControlPlaneReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                      mgr.GetClient(),
+		Scheme:                      mgr.GetScheme(),
+		EtcdDialTimeout:             etcdDialTimeout,
+		KubeconfigRotationThreshold: kubeconfigRotationThreshold,
+		ChannelCatalogue:            channelCatalogueKey,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RKE2ControlPlane")
 		os.Exit(1)
@@ -0,0 +1,46 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/etcd"
+)
+
+func TestNodeNameForMember(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(NodeNameForMember(&etcd.Member{Name: "cp-node-1-3b4d9c2e"})).To(Equal("cp-node-1"))
+	g.Expect(NodeNameForMember(&etcd.Member{Name: "cp-node-1"})).To(Equal("cp-node-1"))
+	g.Expect(NodeNameForMember(&etcd.Member{Name: ""})).To(BeEmpty())
+}
+
+func TestMemberForName(t *testing.T) {
+	g := NewWithT(t)
+
+	members := []*etcd.Member{
+		{ID: 1, Name: "cp-node-1-3b4d9c2e"},
+		{ID: 2, Name: "cp-node-2-0a1b2c3d"},
+	}
+
+	g.Expect(MemberForName(members, "cp-node-2")).To(Equal(members[1]))
+	g.Expect(MemberForName(members, "cp-node-1-3b4d9c2e")).To(Equal(members[0]))
+	g.Expect(MemberForName(members, "cp-node-3")).To(BeNil())
+}
@@ -21,6 +21,7 @@ import (
 	"crypto"
 	"crypto/x509"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +29,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -83,6 +85,8 @@ func generateKubeconfig(ctx context.Context, c client.Client, clusterName client
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to generate a kubeconfig")
 	}
+	// Trust the whole CA bundle, which contains both the old and the new CA during a CA rotation.
+	cfg.Clusters[clusterName.Name].CertificateAuthorityData = clusterCA.Data[secret.TLSCrtDataName]
 
 	out, err := clientcmd.Write(*cfg)
 	if err != nil {
@@ -91,6 +95,125 @@ func generateKubeconfig(ctx context.Context, c client.Client, clusterName client
 	return out, nil
 }
 
+// NeedsClientCertRotation returns whether any of the Kubeconfig secret's client certificates will expire before the given threshold.
+func NeedsClientCertRotation(configSecret *corev1.Secret, threshold time.Duration) (bool, error) {
+	config, err := load(configSecret)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	for _, authInfo := range config.AuthInfos {
+		cert, err := certs.DecodeCertPEM(authInfo.ClientCertificateData)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to decode kubeconfig client certificate")
+		}
+		if cert == nil || cert.NotAfter.Sub(now) < threshold {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// NeedsUpdate returns whether the Kubeconfig secret no longer matches the cluster, i.e. when the endpoint
+// or the cluster CA bundle stored in the ClusterCA secret changed, or when the client certificate is not
+// signed by the current ClientClusterCA, e.g. during a CA rotation.
+func NeedsUpdate(ctx context.Context, c client.Client, clusterName client.ObjectKey, configSecret *corev1.Secret, endpoint string) (bool, error) {
+	config, err := load(configSecret)
+	if err != nil {
+		return false, err
+	}
+
+	cluster, ok := config.Clusters[clusterName.Name]
+	if !ok {
+		return true, nil
+	}
+	if cluster.Server != fmt.Sprintf("https://%s", endpoint) {
+		return true, nil
+	}
+
+	serverCACerts, err := getCACerts(ctx, c, clusterName, secret.ClusterCA)
+	if err != nil {
+		return false, err
+	}
+	kubeconfigCACerts, err := certutil.ParseCertsPEM(cluster.CertificateAuthorityData)
+	if err != nil || !certsEqual(kubeconfigCACerts, serverCACerts) {
+		// A kubeconfig with an invalid CA can't be used, regenerate it.
+		return true, nil //nolint:nilerr
+	}
+
+	clientCACerts, err := getCACerts(ctx, c, clusterName, secret.ClientClusterCA)
+	if err != nil {
+		return false, err
+	}
+	for _, authInfo := range config.AuthInfos {
+		cert, err := certs.DecodeCertPEM(authInfo.ClientCertificateData)
+		if err != nil || cert == nil || cert.CheckSignatureFrom(clientCACerts[0]) != nil {
+			return true, nil //nolint:nilerr
+		}
+	}
+
+	return false, nil
+}
+
+// getCACerts returns the certificates of a CA secret, the first one being the signing CA.
+func getCACerts(ctx context.Context, c client.Client, clusterName client.ObjectKey, purpose secret.Purpose) ([]*x509.Certificate, error) {
+	s, err := secret.GetFromNamespacedName(ctx, c, clusterName, purpose)
+	if err != nil {
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			return nil, ErrDependentCertificateNotFound
+		}
+		return nil, err
+	}
+
+	caCerts, err := certutil.ParseCertsPEM(s.Data[secret.TLSCrtDataName])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode CA Cert")
+	}
+	return caCerts, nil
+}
+
+func certsEqual(a, b []*x509.Certificate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// RegenerateSecret creates and stores a new Kubeconfig with the given endpoint in the given secret.
+func RegenerateSecret(ctx context.Context, c client.Client, clusterName client.ObjectKey, configSecret *corev1.Secret, endpoint string) error {
+	out, err := generateKubeconfig(ctx, c, clusterName, fmt.Sprintf("https://%s", endpoint))
+	if err != nil {
+		return err
+	}
+
+	if configSecret.Data == nil {
+		configSecret.Data = map[string][]byte{}
+	}
+	configSecret.Data[secret.KubeconfigDataName] = out
+	return c.Update(ctx, configSecret)
+}
+
+// load parses the Kubeconfig stored in the given secret.
+func load(configSecret *corev1.Secret) (*api.Config, error) {
+	data, ok := configSecret.Data[secret.KubeconfigDataName]
+	if !ok {
+		return nil, errors.Errorf("missing key %q in secret data", secret.KubeconfigDataName)
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert kubeconfig Secret into a clientcmdapi.Config")
+	}
+	return config, nil
+}
+
 // New creates a new Kubeconfig using the cluster name and specified endpoint.
 func New(clusterName, endpoint string, clientCACert *x509.Certificate, clientCAKey crypto.Signer, serverCACert *x509.Certificate) (*api.Config, error) {
 
@@ -0,0 +1,109 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/secret"
+)
+
+func setupKubeconfig(t *testing.T) (client.Client, client.ObjectKey, secret.Certificates) {
+	t.Helper()
+	g := NewWithT(t)
+
+	clusterName := client.ObjectKey{Namespace: "example", Name: "test"}
+	certificates := secret.NewCertificatesForInitialControlPlane()
+	g.Expect(certificates.Generate()).To(Succeed())
+
+	c := fake.NewClientBuilder().Build()
+	owner := metav1.NewControllerRef(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "owner"}}, corev1.SchemeGroupVersion.WithKind("Secret"))
+	g.Expect(certificates.SaveGenerated(context.Background(), c, clusterName, *owner)).To(Succeed())
+	g.Expect(CreateSecretWithOwner(context.Background(), c, clusterName, "1.2.3.4:6443", metav1.OwnerReference{})).To(Succeed())
+
+	return c, clusterName, certificates
+}
+
+func getKubeconfigSecret(t *testing.T, c client.Client, clusterName client.ObjectKey) *corev1.Secret {
+	t.Helper()
+	g := NewWithT(t)
+
+	configSecret, err := secret.GetFromNamespacedName(context.Background(), c, clusterName, secret.Kubeconfig)
+	g.Expect(err).ToNot(HaveOccurred())
+	return configSecret
+}
+
+func TestNeedsClientCertRotation(t *testing.T) {
+	g := NewWithT(t)
+	c, clusterName, _ := setupKubeconfig(t)
+	configSecret := getKubeconfigSecret(t, c, clusterName)
+
+	g.Expect(NeedsClientCertRotation(configSecret, 24*time.Hour)).To(BeFalse())
+	g.Expect(NeedsClientCertRotation(configSecret, 2*365*24*time.Hour)).To(BeTrue())
+}
+
+func TestNeedsUpdate(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c, clusterName, certificates := setupKubeconfig(t)
+	configSecret := getKubeconfigSecret(t, c, clusterName)
+
+	g.Expect(NeedsUpdate(ctx, c, clusterName, configSecret, "1.2.3.4:6443")).To(BeFalse())
+	g.Expect(NeedsUpdate(ctx, c, clusterName, configSecret, "5.6.7.8:6443")).To(BeTrue())
+
+	// Replace the cluster CA.
+	clusterCA := certificates.GetByPurpose(secret.ClusterCA)
+	g.Expect(clusterCA.Generate()).To(Succeed())
+	caSecret, err := secret.GetFromNamespacedName(ctx, c, clusterName, secret.ClusterCA)
+	g.Expect(err).ToNot(HaveOccurred())
+	caSecret.Data = clusterCA.AsSecret(clusterName, metav1.OwnerReference{}).Data
+	g.Expect(c.Update(ctx, caSecret)).To(Succeed())
+
+	g.Expect(NeedsUpdate(ctx, c, clusterName, configSecret, "1.2.3.4:6443")).To(BeTrue())
+
+	g.Expect(RegenerateSecret(ctx, c, clusterName, configSecret, "1.2.3.4:6443")).To(Succeed())
+	g.Expect(NeedsUpdate(ctx, c, clusterName, getKubeconfigSecret(t, c, clusterName), "1.2.3.4:6443")).To(BeFalse())
+}
+
+func TestNeedsUpdateDuringCARotation(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	c, clusterName, _ := setupKubeconfig(t)
+	regenerate := func() {
+		g.Expect(RegenerateSecret(ctx, c, clusterName, getKubeconfigSecret(t, c, clusterName), "1.2.3.4:6443")).To(Succeed())
+		g.Expect(NeedsUpdate(ctx, c, clusterName, getKubeconfigSecret(t, c, clusterName), "1.2.3.4:6443")).To(BeFalse())
+	}
+
+	// The kubeconfig trusts the whole CA bundle.
+	g.Expect(secret.TrustNextCA(ctx, c, clusterName, secret.ClusterCA)).To(Succeed())
+	g.Expect(NeedsUpdate(ctx, c, clusterName, getKubeconfigSecret(t, c, clusterName), "1.2.3.4:6443")).To(BeTrue())
+	regenerate()
+
+	// The client certificate must be signed by the new client CA.
+	g.Expect(secret.TrustNextCA(ctx, c, clusterName, secret.ClientClusterCA)).To(Succeed())
+	g.Expect(secret.SignWithNextCA(ctx, c, clusterName, secret.ClientClusterCA)).To(Succeed())
+	g.Expect(NeedsUpdate(ctx, c, clusterName, getKubeconfigSecret(t, c, clusterName), "1.2.3.4:6443")).To(BeTrue())
+	regenerate()
+}
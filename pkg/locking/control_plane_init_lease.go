@@ -0,0 +1,271 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locking
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// DefaultInitLockTTL is how long a machine holding the init lock has to get a node, before another machine can take the lock over.
+const DefaultInitLockTTL = 30 * time.Minute
+
+// ControlPlaneInitLease uses a Lease to synchronize cluster initialization.
+// Unlike ControlPlaneInitMutex, the lock can be taken over when the holder machine still exists
+// but didn't get a node within the TTL, e.g. because the first server failed to bootstrap.
+type ControlPlaneInitLease struct {
+	client   client.Client
+	recorder record.EventRecorder
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewControlPlaneInitLease returns a lock that can be held by a control plane node before init,
+// and taken over by another one when the holder has no node after the given TTL.
+func NewControlPlaneInitLease(client client.Client, recorder record.EventRecorder, ttl time.Duration) *ControlPlaneInitLease {
+	return &ControlPlaneInitLease{
+		client:   client,
+		recorder: recorder,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+// Lock allows a control plane node to be the first and only node to initialize an RKE2 cluster.
+func (c *ControlPlaneInitLease) Lock(ctx context.Context, cluster *clusterv1.Cluster, machine *clusterv1.Machine) bool {
+	leaseName := leaseName(cluster.Name)
+	log := ctrl.LoggerFrom(ctx, "Lease", klog.KRef(cluster.Namespace, leaseName))
+
+	lease := &coordinationv1.Lease{}
+	err := c.client.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: leaseName}, lease)
+	switch {
+	case apierrors.IsNotFound(err):
+		// Locks created before the Lease was used are migrated, keeping their holder.
+		holder, acquireTime, err := c.legacyHolder(ctx, cluster)
+		if err != nil {
+			log.Error(err, "Failed to get the ConfigMap based init lock")
+			return false
+		}
+		migrated := holder != ""
+		if migrated {
+			log.Info("Migrating the ConfigMap based init lock", "holder", holder)
+		} else {
+			holder, acquireTime = machine.Name, metav1.NewMicroTime(c.now())
+		}
+
+		log.Info("Attempting to acquire the lock")
+		if err := c.client.Create(ctx, c.newLease(cluster, holder, acquireTime)); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				log.Info("Cannot acquire the init lock. The init lock has been acquired by someone else")
+			} else {
+				log.Error(err, "Error acquiring the init lock")
+			}
+			return false
+		}
+		if migrated {
+			c.deleteLegacyLock(ctx, log, cluster)
+		}
+		if holder != machine.Name {
+			log.Info(fmt.Sprintf("Waiting for Machine %s to initialize", holder))
+			return false
+		}
+		return true
+	case err != nil:
+		log.Error(err, "Failed to acquire init lock")
+		return false
+	}
+
+	holder := pointer.StringDeref(lease.Spec.HolderIdentity, "")
+	// The machine requesting the lock is the machine holding the lock, therefore the lock is acquired.
+	if holder == machine.Name {
+		return true
+	}
+
+	takeOverReason, err := c.takeOverReason(ctx, cluster, lease)
+	if err != nil {
+		log.Error(err, "Failed to get machine holding init lock")
+		return false
+	}
+	if takeOverReason == "" {
+		log.Info(fmt.Sprintf("Waiting for Machine %s to initialize", holder))
+		return false
+	}
+
+	// Update the Lease, the optimistic concurrency on the resource version making sure only one machine takes it over.
+	lease.Spec.HolderIdentity = pointer.String(machine.Name)
+	lease.Spec.AcquireTime = &metav1.MicroTime{Time: c.now()}
+	lease.Spec.RenewTime = lease.Spec.AcquireTime
+	lease.Spec.LeaseTransitions = pointer.Int32(pointer.Int32Deref(lease.Spec.LeaseTransitions, 0) + 1)
+	if err := c.client.Update(ctx, lease); err != nil {
+		if apierrors.IsConflict(err) {
+			log.Info("Cannot take over the init lock. The init lock has been taken over by someone else")
+		} else {
+			log.Error(err, "Error taking over the init lock")
+		}
+		return false
+	}
+
+	log.Info("Took over the init lock", "previousHolder", holder, "reason", takeOverReason)
+	if c.recorder != nil {
+		c.recorder.Eventf(cluster, corev1.EventTypeWarning, "InitLockTakeover",
+			"Machine %s took over the control plane init lock from Machine %s: %s", machine.Name, holder, takeOverReason)
+	}
+	return true
+}
+
+// Unlock releases the lock.
+func (c *ControlPlaneInitLease) Unlock(ctx context.Context, cluster *clusterv1.Cluster) bool {
+	leaseName := leaseName(cluster.Name)
+	log := ctrl.LoggerFrom(ctx, "Lease", klog.KRef(cluster.Namespace, leaseName))
+
+	lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: cluster.Namespace, Name: leaseName}}
+	if err := c.client.Delete(ctx, lease); err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "Error deleting the lease underlying the control plane init lock")
+		return false
+	}
+
+	// Also release locks created before the Lease was used.
+	return NewControlPlaneInitMutex(c.client).Unlock(ctx, cluster)
+}
+
+// takeOverReason returns why the lock can be taken over from its holder, or an empty string if it can't.
+func (c *ControlPlaneInitLease) takeOverReason(ctx context.Context, cluster *clusterv1.Cluster, lease *coordinationv1.Lease) (string, error) {
+	holder := pointer.StringDeref(lease.Spec.HolderIdentity, "")
+	if holder == "" {
+		return "the lock has no holder", nil
+	}
+
+	machine := &clusterv1.Machine{}
+	if err := c.client.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: holder}, machine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "the machine holding the lock does not exist", nil
+		}
+		return "", err
+	}
+
+	if holderExpired(lease, machine, c.ttl, c.now()) {
+		return fmt.Sprintf("the machine holding the lock has no node after %s", c.ttl), nil
+	}
+	return "", nil
+}
+
+// ExpiredInitLockHolder returns the machine holding the init lock of the cluster when it has no node after the TTL
+// of the lock, or nil. Such a machine can be replaced: the lock is then taken over by the next control plane machine.
+func ExpiredInitLockHolder(ctx context.Context, c client.Reader, cluster *clusterv1.Cluster) (*clusterv1.Machine, error) {
+	lease := &coordinationv1.Lease{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: leaseName(cluster.Name)}, lease); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	holder := pointer.StringDeref(lease.Spec.HolderIdentity, "")
+	if holder == "" {
+		return nil, nil
+	}
+	machine := &clusterv1.Machine{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: holder}, machine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ttl := time.Duration(pointer.Int32Deref(lease.Spec.LeaseDurationSeconds, int32(DefaultInitLockTTL.Seconds()))) * time.Second
+	if !holderExpired(lease, machine, ttl, time.Now()) {
+		return nil, nil
+	}
+	return machine, nil
+}
+
+// holderExpired returns true if the machine holding the lease has no node after the TTL.
+func holderExpired(lease *coordinationv1.Lease, machine *clusterv1.Machine, ttl time.Duration, now time.Time) bool {
+	if machine.Status.NodeRef != nil || lease.Spec.AcquireTime == nil {
+		return false
+	}
+	return now.After(lease.Spec.AcquireTime.Add(ttl))
+}
+
+func (c *ControlPlaneInitLease) newLease(cluster *clusterv1.Cluster, holder string, acquireTime metav1.MicroTime) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cluster.Namespace,
+			Name:      leaseName(cluster.Name),
+			Labels: map[string]string{
+				clusterv1.ClusterLabelName: cluster.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: cluster.APIVersion,
+					Kind:       cluster.Kind,
+					Name:       cluster.Name,
+					UID:        cluster.UID,
+				},
+			},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       pointer.String(holder),
+			LeaseDurationSeconds: pointer.Int32(int32(c.ttl.Seconds())),
+			AcquireTime:          &acquireTime,
+			RenewTime:            &acquireTime,
+		},
+	}
+}
+
+// legacyHolder returns the holder of a ConfigMap based init lock, with its creation time, if any.
+func (c *ControlPlaneInitLease) legacyHolder(ctx context.Context, cluster *clusterv1.Cluster) (string, metav1.MicroTime, error) {
+	sema := newSemaphore()
+	err := c.client.Get(ctx, client.ObjectKey{Namespace: cluster.Namespace, Name: configMapName(cluster.Name)}, sema.ConfigMap)
+	switch {
+	case apierrors.IsNotFound(err):
+		return "", metav1.MicroTime{}, nil
+	case err != nil:
+		return "", metav1.MicroTime{}, err
+	}
+
+	info, err := sema.information()
+	if err != nil {
+		return "", metav1.MicroTime{}, err
+	}
+	return info.MachineName, metav1.NewMicroTime(sema.CreationTimestamp.Time), nil
+}
+
+func (c *ControlPlaneInitLease) deleteLegacyLock(ctx context.Context, log logr.Logger, cluster *clusterv1.Cluster) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: cluster.Namespace, Name: configMapName(cluster.Name)}}
+	if err := c.client.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+		log.Error(err, "Error deleting the ConfigMap based init lock after migrating it")
+	}
+}
+
+func leaseName(clusterName string) string {
+	return fmt.Sprintf("%s-init-lock", clusterName)
+}
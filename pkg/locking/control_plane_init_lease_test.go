@@ -0,0 +1,237 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package locking
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func newTestInitLease(t *testing.T, objs ...client.Object) (*ControlPlaneInitLease, *record.FakeRecorder) {
+	t.Helper()
+	g := NewWithT(t)
+
+	scheme := runtime.NewScheme()
+	g.Expect(clusterv1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(corev1.AddToScheme(scheme)).To(Succeed())
+	g.Expect(coordinationv1.AddToScheme(scheme)).To(Succeed())
+
+	recorder := record.NewFakeRecorder(10)
+	return NewControlPlaneInitLease(fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(), recorder, 10*time.Minute), recorder
+}
+
+func testCluster() *clusterv1.Cluster {
+	return &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: clusterNamespace, Name: clusterName}}
+}
+
+func testMachine(name string, withNode bool) *clusterv1.Machine {
+	m := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Namespace: clusterNamespace, Name: name}}
+	if withNode {
+		m.Status.NodeRef = &corev1.ObjectReference{Name: name}
+	}
+	return m
+}
+
+func getLeaseHolder(t *testing.T, l *ControlPlaneInitLease) string {
+	t.Helper()
+	g := NewWithT(t)
+
+	lease := &coordinationv1.Lease{}
+	g.Expect(l.client.Get(ctx, client.ObjectKey{Namespace: clusterNamespace, Name: leaseName(clusterName)}, lease)).To(Succeed())
+	return pointer.StringDeref(lease.Spec.HolderIdentity, "")
+}
+
+func TestControlPlaneInitLease_Lock(t *testing.T) {
+	g := NewWithT(t)
+	first, second := testMachine("first", false), testMachine("second", false)
+	l, recorder := newTestInitLease(t, first, second)
+
+	g.Expect(l.Lock(ctx, testCluster(), first)).To(BeTrue())
+	g.Expect(l.Lock(ctx, testCluster(), first)).To(BeTrue())
+	g.Expect(l.Lock(ctx, testCluster(), second)).To(BeFalse())
+	g.Expect(getLeaseHolder(t, l)).To(Equal("first"))
+	g.Expect(recorder.Events).To(BeEmpty())
+}
+
+func TestControlPlaneInitLease_Takeover(t *testing.T) {
+	tests := []struct {
+		name           string
+		holder         *clusterv1.Machine
+		elapsed        time.Duration
+		shouldTakeOver bool
+	}{
+		{
+			name:           "should not take over the lock before the TTL",
+			holder:         testMachine("first", false),
+			elapsed:        5 * time.Minute,
+			shouldTakeOver: false,
+		},
+		{
+			name:           "should take over the lock when the holder has no node after the TTL",
+			holder:         testMachine("first", false),
+			elapsed:        15 * time.Minute,
+			shouldTakeOver: true,
+		},
+		{
+			name:           "should not take over the lock when the holder has a node",
+			holder:         testMachine("first", true),
+			elapsed:        15 * time.Minute,
+			shouldTakeOver: false,
+		},
+		{
+			name:           "should take over the lock when the holder does not exist",
+			elapsed:        time.Minute,
+			shouldTakeOver: true,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			objs := []client.Object{}
+			if tc.holder != nil {
+				objs = append(objs, tc.holder)
+			}
+			l, recorder := newTestInitLease(t, objs...)
+
+			start := time.Now()
+			l.now = func() time.Time { return start }
+			g.Expect(l.Lock(ctx, testCluster(), testMachine("first", false))).To(BeTrue())
+
+			l.now = func() time.Time { return start.Add(tc.elapsed) }
+			g.Expect(l.Lock(ctx, testCluster(), testMachine("second", false))).To(Equal(tc.shouldTakeOver))
+
+			if tc.shouldTakeOver {
+				g.Expect(getLeaseHolder(t, l)).To(Equal("second"))
+				g.Expect(recorder.Events).To(Receive(ContainSubstring("InitLockTakeover")))
+			} else {
+				g.Expect(getLeaseHolder(t, l)).To(Equal("first"))
+				g.Expect(recorder.Events).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestExpiredInitLockHolder(t *testing.T) {
+	tests := []struct {
+		name    string
+		holder  *clusterv1.Machine
+		elapsed time.Duration
+		expired bool
+	}{
+		{
+			name:    "should not return the holder before the TTL",
+			holder:  testMachine("first", false),
+			elapsed: 5 * time.Minute,
+			expired: false,
+		},
+		{
+			name:    "should return the holder without a node after the TTL",
+			holder:  testMachine("first", false),
+			elapsed: 15 * time.Minute,
+			expired: true,
+		},
+		{
+			name:    "should not return the holder with a node",
+			holder:  testMachine("first", true),
+			elapsed: 15 * time.Minute,
+			expired: false,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			l, _ := newTestInitLease(t, tc.holder)
+
+			holder, err := ExpiredInitLockHolder(ctx, l.client, testCluster())
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(holder).To(BeNil())
+
+			l.now = func() time.Time { return time.Now().Add(-tc.elapsed) }
+			g.Expect(l.Lock(ctx, testCluster(), testMachine("first", false))).To(BeTrue())
+
+			holder, err = ExpiredInitLockHolder(ctx, l.client, testCluster())
+			g.Expect(err).ToNot(HaveOccurred())
+			if tc.expired {
+				g.Expect(holder).ToNot(BeNil())
+				g.Expect(holder.Name).To(Equal("first"))
+			} else {
+				g.Expect(holder).To(BeNil())
+			}
+		})
+	}
+}
+
+func TestControlPlaneInitLease_MigrateConfigMap(t *testing.T) {
+	g := NewWithT(t)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              configMapName(clusterName),
+			Namespace:         clusterNamespace,
+			CreationTimestamp: metav1.Now(),
+		},
+		Data: map[string]string{
+			semaphoreInformationKey: "{\"machineName\":\"first\"}",
+		},
+	}
+	l, _ := newTestInitLease(t, configMap, testMachine("first", false))
+
+	g.Expect(l.Lock(ctx, testCluster(), testMachine("second", false))).To(BeFalse())
+	g.Expect(getLeaseHolder(t, l)).To(Equal("first"))
+	err := l.client.Get(ctx, client.ObjectKeyFromObject(configMap), &corev1.ConfigMap{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	g.Expect(l.Lock(ctx, testCluster(), testMachine("first", false))).To(BeTrue())
+}
+
+func TestControlPlaneInitLease_Unlock(t *testing.T) {
+	g := NewWithT(t)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: configMapName(clusterName), Namespace: clusterNamespace},
+		Data: map[string]string{
+			semaphoreInformationKey: "{\"machineName\":\"first\"}",
+		},
+	}
+	l, _ := newTestInitLease(t, configMap)
+
+	g.Expect(l.Lock(ctx, testCluster(), testMachine("first", false))).To(BeTrue())
+	g.Expect(l.Unlock(ctx, testCluster())).To(BeTrue())
+	g.Expect(l.Unlock(ctx, testCluster())).To(BeTrue())
+
+	err := l.client.Get(ctx, client.ObjectKey{Namespace: clusterNamespace, Name: leaseName(clusterName)}, &coordinationv1.Lease{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	err = l.client.Get(ctx, client.ObjectKeyFromObject(configMap), &corev1.ConfigMap{})
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+	g.Expect(l.Lock(ctx, testCluster(), testMachine("second", false))).To(BeTrue())
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/util/version"
+)
+
+// ChannelCatalogueDataKey is the key of the ConfigMap data holding the channel catalogue.
+const ChannelCatalogueDataKey = "channels.json"
+
+// ChannelCatalogue is a catalogue of RKE2 release channels, in the format served by the RKE2 channel server
+// (https://update.rke2.io/v1-release/channels), so its response can be stored in a ConfigMap for offline use.
+type ChannelCatalogue struct {
+	Data []Channel `json:"data"`
+}
+
+// Channel is an RKE2 release channel, e.g. stable, latest or v1.26.
+type Channel struct {
+	// Name is the name of the channel.
+	Name string `json:"name"`
+
+	// Latest is the latest RKE2 version of the channel.
+	Latest string `json:"latest"`
+}
+
+// GetChannelCatalogue reads the channel catalogue stored in a ConfigMap.
+func GetChannelCatalogue(ctx context.Context, c ctrlclient.Reader, key ctrlclient.ObjectKey) (*ChannelCatalogue, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(ctx, key, configMap); err != nil {
+		return nil, errors.Wrapf(err, "failed to get channel catalogue ConfigMap %s", key)
+	}
+
+	data, ok := configMap.Data[ChannelCatalogueDataKey]
+	if !ok {
+		return nil, errors.Errorf("channel catalogue ConfigMap %s has no %s key", key, ChannelCatalogueDataKey)
+	}
+	return ParseChannelCatalogue([]byte(data))
+}
+
+// ParseChannelCatalogue parses a channel catalogue, as served by the RKE2 channel server.
+func ParseChannelCatalogue(data []byte) (*ChannelCatalogue, error) {
+	catalogue := &ChannelCatalogue{}
+	if err := json.Unmarshal(data, catalogue); err != nil {
+		return nil, errors.Wrap(err, "failed to parse channel catalogue")
+	}
+	return catalogue, nil
+}
+
+// ResolveChannel returns the latest RKE2 version of a channel.
+func (c *ChannelCatalogue) ResolveChannel(name string) (string, error) {
+	for _, channel := range c.Data {
+		if channel.Name != name {
+			continue
+		}
+		if _, err := version.ParseRKE2Version(channel.Latest); err != nil {
+			return "", errors.Wrapf(err, "invalid latest version for channel %s", name)
+		}
+		return channel.Latest, nil
+	}
+	return "", errors.Errorf("channel %s not found in the channel catalogue", name)
+}
+
+// ResolveChannelUpgrade returns the newest RKE2 version of the catalogue, up to the latest version of a channel, which is a
+// valid upgrade from the given version. It's the latest version of the channel unless it skips Kubernetes minor versions,
+// in which case the latest version of an intermediate channel, e.g. v1.26, is returned so the upgrade can be done in steps.
+func (c *ChannelCatalogue) ResolveChannelUpgrade(name string, from *version.RKE2Version) (string, error) {
+	latest, err := c.ResolveChannel(name)
+	if err != nil {
+		return "", err
+	}
+	latestVersion, err := version.ParseRKE2Version(latest)
+	if err != nil {
+		return "", err
+	}
+
+	var newest *version.RKE2Version
+	for _, channel := range c.Data {
+		candidate, err := version.ParseRKE2Version(channel.Latest)
+		if err != nil || candidate.Compare(latestVersion) > 0 || version.CheckUpgrade(from, candidate) != nil {
+			continue
+		}
+		if newest == nil || candidate.Compare(newest) > 0 {
+			newest = candidate
+		}
+	}
+	if newest == nil {
+		return "", errors.Errorf("no version of channel %s up to %s is a valid upgrade from Kubernetes v%s", name, latest, from.Kubernetes)
+	}
+	return newest.String(), nil
+}
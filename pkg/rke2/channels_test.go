@@ -0,0 +1,116 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/util/version"
+)
+
+// testChannelCatalogue is an excerpt of the response of the RKE2 channel server.
+const testChannelCatalogue = `{
+  "type": "collection",
+  "resourceType": "channels",
+  "data": [
+    {"id": "stable", "type": "channel", "name": "stable", "latest": "v1.25.9+rke2r1"},
+    {"id": "latest", "type": "channel", "name": "latest", "latest": "v1.27.1+rke2r1"},
+    {"id": "v1.26", "type": "channel", "name": "v1.26", "latest": "v1.26.4+rke2r1", "latestRegexp": "v1\\.26\\..*", "excludeRegexp": "^[^+]+-"},
+    {"id": "broken", "type": "channel", "name": "broken", "latest": "1.26"}
+  ]
+}`
+
+var _ = Describe("ChannelCatalogue", func() {
+	It("should resolve the channels to their latest version", func() {
+		catalogue, err := ParseChannelCatalogue([]byte(testChannelCatalogue))
+		Expect(err).ToNot(HaveOccurred())
+
+		for channel, version := range map[string]string{
+			"stable": "v1.25.9+rke2r1",
+			"latest": "v1.27.1+rke2r1",
+			"v1.26":  "v1.26.4+rke2r1",
+		} {
+			resolved, err := catalogue.ResolveChannel(channel)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved).To(Equal(version))
+		}
+	})
+
+	It("should fail on unknown channels and invalid versions", func() {
+		catalogue, err := ParseChannelCatalogue([]byte(testChannelCatalogue))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = catalogue.ResolveChannel("testing")
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+		_, err = catalogue.ResolveChannel("broken")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should resolve the newest version which is a valid upgrade", func() {
+		catalogue, err := ParseChannelCatalogue([]byte(testChannelCatalogue))
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, tt := range []struct {
+			channel  string
+			from     string
+			expected string
+		}{
+			{channel: "latest", from: "v1.26.1+rke2r1", expected: "v1.27.1+rke2r1"},
+			{channel: "latest", from: "v1.25.6+rke2r1", expected: "v1.26.4+rke2r1"},
+			{channel: "stable", from: "v1.24.6+rke2r1", expected: "v1.25.9+rke2r1"},
+			{channel: "stable", from: "v1.25.9+rke2r1", expected: "v1.25.9+rke2r1"},
+		} {
+			from, err := version.ParseRKE2Version(tt.from)
+			Expect(err).ToNot(HaveOccurred())
+			resolved, err := catalogue.ResolveChannelUpgrade(tt.channel, from)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved).To(Equal(tt.expected), "channel %s from %s", tt.channel, tt.from)
+		}
+
+		for _, from := range []string{"v1.23.9+rke2r1", "v1.26.4+rke2r1"} {
+			fromVersion, err := version.ParseRKE2Version(from)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = catalogue.ResolveChannelUpgrade("stable", fromVersion)
+			Expect(err).To(HaveOccurred(), "from %s", from)
+		}
+	})
+
+	It("should read the catalogue from a ConfigMap", func() {
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+		key := client.ObjectKey{Namespace: "rke2-system", Name: "channels"}
+
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		_, err := GetChannelCatalogue(context.Background(), c, key)
+		Expect(err).To(HaveOccurred())
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Data:       map[string]string{ChannelCatalogueDataKey: testChannelCatalogue},
+		}).Build()
+		catalogue, err := GetChannelCatalogue(context.Background(), c, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(catalogue.Data).To(HaveLen(4))
+	})
+})
@@ -105,6 +105,7 @@ type RKE2ServerConfigOpts struct {
 	ServerURL            string
 	ServerConfig         controlplanev1.RKE2ServerConfig
 	AgentConfig          bootstrapv1.RKE2AgentConfig
+	MachineName          string
 	Ctx                  context.Context
 	Client               client.Client
 }
@@ -299,6 +300,7 @@ type rke2AgentConfig struct {
 	Selinux                       bool              `json:"selinux,omitempty"`
 	Server                        string            `json:"server,omitempty"`
 	Snapshotter                   string            `json:"snapshotter,omitempty"`
+	SystemDefaultRegistry         string            `json:"system-default-registry,omitempty"`
 	Token                         string            `json:"token,omitempty"` // TODO: generate the token?
 
 	// We don't expose these in the API
@@ -308,13 +310,14 @@ type rke2AgentConfig struct {
 
 	NodeExternalIp string `json:"node-external-ip,omitempty"` // TODO: infra provider can provider external ip, we should use it here and for TLS SAN.
 	NodeIp         string `json:"node-ip,omitempty"`          // TODO: obtain the node ip from the infra provider
-	NodeName       string `json:"node-name,omitempty"`        // TODO: figure our how to handle this using node name prefix from API
+	NodeName       string `json:"node-name,omitempty"`
 }
 
 type RKE2AgentConfigOpts struct {
 	ServerURL              string
 	Token                  string
 	AgentConfig            bootstrapv1.RKE2AgentConfig
+	MachineName            string
 	Ctx                    context.Context
 	Client                 client.Client
 	CloudProviderName      string
@@ -378,6 +381,7 @@ func newRKE2AgentConfig(opts RKE2AgentConfigOpts) (*rke2AgentConfig, []bootstrap
 	}
 	rke2AgentConfig.LbServerPort = opts.AgentConfig.LoadBalancerPort
 	rke2AgentConfig.NodeLabels = opts.AgentConfig.NodeLabels
+	rke2AgentConfig.NodeName = NodeNameForMachine(opts.AgentConfig.NodeNamePrefix, opts.MachineName)
 	rke2AgentConfig.NodeTaints = opts.AgentConfig.NodeTaints
 	rke2AgentConfig.Profile = string(opts.AgentConfig.CISProfile)
 	rke2AgentConfig.ProtectKernelDefaults = opts.AgentConfig.ProtectKernelDefaults
@@ -405,6 +409,7 @@ func newRKE2AgentConfig(opts RKE2AgentConfigOpts) (*rke2AgentConfig, []bootstrap
 	rke2AgentConfig.Selinux = opts.AgentConfig.EnableContainerdSElinux
 	rke2AgentConfig.Server = opts.ServerURL
 	rke2AgentConfig.Snapshotter = opts.AgentConfig.Snapshotter
+	rke2AgentConfig.SystemDefaultRegistry = opts.AgentConfig.SystemDefaultRegistry
 	if opts.AgentConfig.KubeProxy != nil {
 		rke2AgentConfig.KubeProxyArgs = opts.AgentConfig.KubeProxy.ExtraArgs
 		rke2AgentConfig.KubeProxyImage = opts.AgentConfig.KubeProxy.OverrideImage
@@ -416,6 +421,19 @@ func newRKE2AgentConfig(opts RKE2AgentConfigOpts) (*rke2AgentConfig, []bootstrap
 	return rke2AgentConfig, files, nil
 }
 
+// NodeNameForMachine returns the RKE2 node name of a machine, given the node name prefix of its configuration.
+// An empty prefix leaves the node name to RKE2, which defaults to the hostname. A prefix containing a jinja
+// expression, e.g. "{{ ds.meta_data.local_hostname }}", is used as is and rendered by cloud-init on the node.
+func NodeNameForMachine(prefix, machineName string) string {
+	if prefix == "" {
+		return ""
+	}
+	if bootstrapv1.IsJinjaExpression(prefix) {
+		return prefix
+	}
+	return prefix + machineName
+}
+
 func GenerateInitControlPlaneConfig(opts RKE2ServerConfigOpts) (*rke2ServerConfig, []bootstrapv1.File, error) {
 	if opts.Token == "" {
 		return nil, nil, fmt.Errorf("token is required")
@@ -430,6 +448,7 @@ func GenerateInitControlPlaneConfig(opts RKE2ServerConfigOpts) (*rke2ServerConfi
 		AgentConfig: opts.AgentConfig,
 		Client:      opts.Client,
 		Ctx:         opts.Ctx,
+		MachineName: opts.MachineName,
 		Token:       opts.Token,
 	})
 
@@ -460,6 +479,7 @@ func GenerateJoinControlPlaneConfig(opts RKE2ServerConfigOpts) (*rke2ServerConfi
 		AgentConfig: opts.AgentConfig,
 		Client:      opts.Client,
 		Ctx:         opts.Ctx,
+		MachineName: opts.MachineName,
 		ServerURL:   opts.ServerURL,
 		Token:       opts.Token,
 	})
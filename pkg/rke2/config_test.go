@@ -241,9 +241,10 @@ var _ = Describe("RKE2 Agent Config", func() {
 
 	BeforeEach(func() {
 		opts = &RKE2AgentConfigOpts{
-			ServerURL: "testurl",
-			Ctx:       context.Background(),
-			Token:     "testtoken",
+			ServerURL:   "testurl",
+			Ctx:         context.Background(),
+			Token:       "testtoken",
+			MachineName: "testmachine",
 			Client: fake.NewClientBuilder().WithObjects(
 				&corev1.ConfigMap{
 					ObjectMeta: metav1.ObjectMeta{
@@ -271,6 +272,7 @@ var _ = Describe("RKE2 Agent Config", func() {
 				LoadBalancerPort:      1234,
 				NodeLabels:            []string{"testlabel"},
 				NodeTaints:            []string{"testtaint"},
+				NodeNamePrefix:        "testprefix-",
 				CISProfile:            bootstrapv1.CIS1_23,
 				ProtectKernelDefaults: true,
 				ResolvConf: &corev1.ObjectReference{
@@ -280,6 +282,7 @@ var _ = Describe("RKE2 Agent Config", func() {
 				RuntimeImage:            "testimage",
 				EnableContainerdSElinux: true,
 				Snapshotter:             "testsnapshotter",
+				SystemDefaultRegistry:   "testregistry",
 				KubeProxy: &bootstrapv1.ComponentConfig{
 					ExtraArgs:     []string{"testarg"},
 					OverrideImage: "testimage",
@@ -303,6 +306,7 @@ var _ = Describe("RKE2 Agent Config", func() {
 		Expect(agentConfig.LbServerPort).To(Equal(opts.AgentConfig.LoadBalancerPort))
 		Expect(agentConfig.NodeLabels).To(Equal(opts.AgentConfig.NodeLabels))
 		Expect(agentConfig.NodeTaints).To(Equal(opts.AgentConfig.NodeTaints))
+		Expect(agentConfig.NodeName).To(Equal("testprefix-testmachine"))
 		Expect(agentConfig.Profile).To(Equal(string(opts.AgentConfig.CISProfile)))
 		Expect(agentConfig.ProtectKernelDefaults).To(Equal(opts.AgentConfig.ProtectKernelDefaults))
 		Expect(agentConfig.ResolvConf).To(Equal("/etc/rancher/rke2/resolv.conf"))
@@ -310,6 +314,7 @@ var _ = Describe("RKE2 Agent Config", func() {
 		Expect(agentConfig.Selinux).To(Equal(opts.AgentConfig.EnableContainerdSElinux))
 		Expect(agentConfig.Server).To(Equal(opts.ServerURL))
 		Expect(agentConfig.Snapshotter).To(Equal(opts.AgentConfig.Snapshotter))
+		Expect(agentConfig.SystemDefaultRegistry).To(Equal(opts.AgentConfig.SystemDefaultRegistry))
 		Expect(agentConfig.KubeProxyArgs).To(Equal(opts.AgentConfig.KubeProxy.ExtraArgs))
 		Expect(agentConfig.KubeProxyImage).To(Equal(opts.AgentConfig.KubeProxy.OverrideImage))
 		Expect(agentConfig.KubeProxyExtraMounts).To(Equal(opts.AgentConfig.KubeProxy.ExtraMounts))
@@ -329,3 +334,17 @@ var _ = Describe("RKE2 Agent Config", func() {
 		Expect(files[1].Permissions).To(Equal("0644"))
 	})
 })
+
+var _ = Describe("NodeNameForMachine", func() {
+	It("should not set a node name without prefix", func() {
+		Expect(NodeNameForMachine("", "testmachine")).To(BeEmpty())
+	})
+
+	It("should prepend the prefix to the machine name", func() {
+		Expect(NodeNameForMachine("testprefix-", "testmachine")).To(Equal("testprefix-testmachine"))
+	})
+
+	It("should use a jinja expression as is", func() {
+		Expect(NodeNameForMachine("{{ ds.meta_data.local_hostname }}", "testmachine")).To(Equal("{{ ds.meta_data.local_hostname }}"))
+	})
+})
@@ -18,6 +18,8 @@ package rke2
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
@@ -35,6 +37,7 @@ import (
 
 	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
 	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/util/version"
 	"sigs.k8s.io/cluster-api/controllers/external"
 	"sigs.k8s.io/cluster-api/util/collections"
 	"sigs.k8s.io/cluster-api/util/patch"
@@ -103,12 +106,13 @@ func (c *ControlPlane) FailureDomains() clusterv1.FailureDomains {
 
 // Version returns the RKE2ControlPlane's version.
 func (c *ControlPlane) Version() *string {
-	return &c.RCP.Spec.AgentConfig.Version
+	version := c.RCP.GetDesiredVersion()
+	return &version
 }
 
 // InfrastructureTemplate returns the RKE2ControlPlane's infrastructure template.
 func (c *ControlPlane) InfrastructureRef() *corev1.ObjectReference {
-	return &c.RCP.Spec.InfrastructureRef
+	return c.RCP.GetInfrastructureRef()
 }
 
 // AsOwnerReference returns an owner reference to the RKE2ControlPlane.
@@ -248,16 +252,94 @@ func (c *ControlPlane) HasDeletingMachine() bool {
 
 // MachinesNeedingRollout return a list of machines that need to be rolled out.
 func (c *ControlPlane) MachinesNeedingRollout() collections.Machines {
-	// Ignore machines to be deleted.
-	machines := c.Machines.Filter(collections.Not(collections.HasDeletionTimestamp))
+	rolloutReasons := c.MachinesRolloutReasons()
+	return c.Machines.Filter(func(machine *clusterv1.Machine) bool {
+		_, ok := rolloutReasons[machine.Name]
+		return ok
+	})
+}
+
+// MachinesRolloutReasons returns, for each machine that needs to be rolled out, the reasons why it does not match
+// the desired state of the control plane.
+func (c *ControlPlane) MachinesRolloutReasons() map[string][]string {
+	result := map[string][]string{}
+	for _, machine := range c.Machines {
+		// Ignore machines to be deleted.
+		if !machine.DeletionTimestamp.IsZero() {
+			continue
+		}
 
-	// Return machines if they are scheduled for rollout or if with an outdated configuration.
-	return machines.AnyFilter(
-		// Machines that are scheduled for rollout (RCP.Spec.UpgradeAfter set, the UpgradeAfter deadline is expired, and the machine was created before the deadline).
-		// collections.ShouldRolloutAfter(&c.reconciliationTime, c.RCP.Spec.RolloutAfter), // TODO: Add rollout after field to RKE2ControlPlane if we want to support this.
 		// Machines that do not match with RCP config.
-		collections.Not(matchesRCPConfiguration(c.infraResources, c.rke2Configs, c.RCP)),
-	)
+		reasons := rcpConfigurationDiff(c.infraResources, c.rke2Configs, c.RCP, machine)
+
+		// Machines that are scheduled for rollout (RCP.Spec.RolloutAfter set, the RolloutAfter deadline is expired, and the machine was created before the deadline).
+		if collections.ShouldRolloutAfter(&c.reconciliationTime, c.RCP.Spec.RolloutAfter)(machine) {
+			reasons = append(reasons, fmt.Sprintf("rolloutAfter: machine created before %s", c.RCP.Spec.RolloutAfter.UTC().Format(time.RFC3339)))
+		}
+
+		// Machines whose certificates expire within RCP.Spec.CertificatesExpiryDays.
+		if c.certificatesExpiring(machine) {
+			reasons = append(reasons, fmt.Sprintf("certificatesExpiryDays: certificates expire at %s",
+				machineCertificatesExpiry(machine).UTC().Format(time.RFC3339)))
+		}
+
+		// Machines created before the current phase of a CA rotation, which don't have the current CA files.
+		if rotation := c.RCP.Status.CARotation; rotation != nil && rotation.Phase != controlplanev1.CARotationCompleted &&
+			machine.CreationTimestamp.Before(&rotation.PhaseStartTime) {
+			reasons = append(reasons, fmt.Sprintf("caRotation: machine created before the %s phase", rotation.Phase))
+		}
+
+		if len(reasons) > 0 {
+			result[machine.Name] = reasons
+		}
+	}
+	return result
+}
+
+// MachinesWithExpiringCertificates returns the machines whose certificates expire within RCP.Spec.CertificatesExpiryDays.
+func (c *ControlPlane) MachinesWithExpiringCertificates() collections.Machines {
+	return c.Machines.Filter(c.certificatesExpiring)
+}
+
+// certificatesExpiring returns true if the certificates of the machine expire within RCP.Spec.CertificatesExpiryDays.
+func (c *ControlPlane) certificatesExpiring(machine *clusterv1.Machine) bool {
+	days := c.RCP.Spec.CertificatesExpiryDays
+	if days == nil {
+		return false
+	}
+	expiry := machineCertificatesExpiry(machine)
+	return expiry != nil && expiry.Before(c.reconciliationTime.Add(time.Duration(*days)*24*time.Hour))
+}
+
+// NextCertificatesExpiryRollout returns when the certificates of the next machine will enter the
+// RCP.Spec.CertificatesExpiryDays window, or nil if no machine is expected to enter it.
+func (c *ControlPlane) NextCertificatesExpiryRollout() *time.Time {
+	if c.RCP.Spec.CertificatesExpiryDays == nil {
+		return nil
+	}
+
+	var next *time.Time
+	for _, machine := range c.Machines {
+		expiry := machineCertificatesExpiry(machine)
+		if expiry == nil {
+			continue
+		}
+		deadline := expiry.Add(-time.Duration(*c.RCP.Spec.CertificatesExpiryDays) * 24 * time.Hour)
+		if deadline.After(c.reconciliationTime.Time) && (next == nil || deadline.Before(*next)) {
+			next = &deadline
+		}
+	}
+	return next
+}
+
+// MachinesNeedingInPlaceUpgrade returns the machines needing rollout only because of the RKE2 version,
+// which can be upgraded in place instead of being replaced.
+func (c *ControlPlane) MachinesNeedingInPlaceUpgrade() collections.Machines {
+	reasons := c.MachinesRolloutReasons()
+	return c.Machines.Filter(func(machine *clusterv1.Machine) bool {
+		machineReasons, ok := reasons[machine.Name]
+		return ok && len(machineReasons) == 1 && versionDiff(c.rke2Configs, c.RCP, machine) != ""
+	})
 }
 
 // UpToDateMachines returns the machines that are up to date with the control
@@ -302,6 +384,27 @@ func getRKE2Configs(ctx context.Context, cl client.Client, machines collections.
 	return result, nil
 }
 
+// LowestRKE2Version returns the lowest RKE2 version run by the machines, i.e. the version they were upgraded to in place
+// or the one of their RKE2Config, or nil when there are no machines. It fails when the version of a machine is not known.
+func LowestRKE2Version(ctx context.Context, cl client.Client, machines collections.Machines) (*version.RKE2Version, error) {
+	rke2Configs, err := getRKE2Configs(ctx, cl, machines)
+	if err != nil {
+		return nil, err
+	}
+
+	var lowest *version.RKE2Version
+	for _, machine := range machines {
+		machineVersion, err := version.ParseRKE2Version(machineRKE2Version(rke2Configs, machine))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the RKE2 version of machine %s", machine.Name)
+		}
+		if lowest == nil || machineVersion.Compare(lowest) < 0 {
+			lowest = machineVersion
+		}
+	}
+	return lowest, nil
+}
+
 // UnhealthyMachines returns the list of control plane machines marked as unhealthy by MHC.
 func (c *ControlPlane) UnhealthyMachines() collections.Machines {
 	return c.Machines.Filter(collections.HasUnhealthyCondition)
@@ -324,6 +427,11 @@ func (c *ControlPlane) PatchMachines(ctx context.Context) error {
 		if helper, ok := c.machinesPatchHelpers[machine.Name]; ok {
 			if err := helper.Patch(ctx, machine, patch.WithOwnedConditions{Conditions: []clusterv1.ConditionType{
 				controlplanev1.MachineAgentHealthyCondition,
+				controlplanev1.MachineAPIServerPodHealthyCondition,
+				controlplanev1.MachineControllerManagerPodHealthyCondition,
+				controlplanev1.MachineSchedulerPodHealthyCondition,
+				controlplanev1.MachineEtcdPodHealthyCondition,
+				controlplanev1.MachineCloudControllerManagerPodHealthyCondition,
 				controlplanev1.MachineEtcdMemberHealthyCondition,
 			}}); err != nil {
 				errList = append(errList, errors.Wrapf(err, "failed to patch machine %s", machine.Name))
@@ -0,0 +1,134 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+)
+
+var _ = Describe("MachinesNeedingRollout", func() {
+	var controlPlane *ControlPlane
+
+	BeforeEach(func() {
+		oldMachine := controlPlaneMachine("machine-old", "node-old")
+		oldMachine.CreationTimestamp = metav1.NewTime(time.Now().Add(-48 * time.Hour))
+		oldMachine.Spec.Version = &machineVersion
+
+		newMachine := controlPlaneMachine("machine-new", "node-new")
+		newMachine.CreationTimestamp = metav1.NewTime(time.Now().Add(-1 * time.Hour))
+		newMachine.Spec.Version = &machineVersion
+
+		controlPlane = &ControlPlane{
+			RCP: &controlplanev1.RKE2ControlPlane{
+				Spec: controlplanev1.RKE2ControlPlaneSpec{
+					RKE2ConfigSpec: bootstrapv1.RKE2ConfigSpec{
+						AgentConfig: bootstrapv1.RKE2AgentConfig{Version: "v1.24.6+rke2r1"},
+					},
+				},
+			},
+			Machines:           collections.FromMachines(oldMachine, newMachine),
+			reconciliationTime: metav1.Now(),
+		}
+	})
+
+	It("should not roll out up to date machines", func() {
+		Expect(controlPlane.MachinesNeedingRollout()).To(BeEmpty())
+	})
+
+	It("should roll out machines created before an expired rolloutAfter", func() {
+		rolloutAfter := metav1.NewTime(time.Now().Add(-24 * time.Hour))
+		controlPlane.RCP.Spec.RolloutAfter = &rolloutAfter
+
+		Expect(controlPlane.MachinesNeedingRollout().Names()).To(ConsistOf("machine-old"))
+		Expect(controlPlane.MachinesRolloutReasons()).To(HaveKeyWithValue("machine-old",
+			ConsistOf(HavePrefix("rolloutAfter: machine created before"))))
+	})
+
+	It("should not roll out machines before rolloutAfter is reached", func() {
+		rolloutAfter := metav1.NewTime(time.Now().Add(24 * time.Hour))
+		controlPlane.RCP.Spec.RolloutAfter = &rolloutAfter
+
+		Expect(controlPlane.MachinesNeedingRollout()).To(BeEmpty())
+	})
+
+	It("should roll out machines created before the current phase of a CA rotation", func() {
+		controlPlane.RCP.Status.CARotation = &controlplanev1.CARotationStatus{
+			Request:        "2023-01-01",
+			Phase:          controlplanev1.CARotationTrustingNewCA,
+			PhaseStartTime: metav1.NewTime(time.Now().Add(-24 * time.Hour)),
+		}
+
+		Expect(controlPlane.MachinesRolloutReasons()).To(HaveKeyWithValue("machine-old",
+			ConsistOf("caRotation: machine created before the TrustingNewCA phase")))
+		Expect(controlPlane.MachinesNeedingRollout().Names()).To(ConsistOf("machine-old"))
+
+		controlPlane.RCP.Status.CARotation.Phase = controlplanev1.CARotationCompleted
+		Expect(controlPlane.MachinesNeedingRollout()).To(BeEmpty())
+	})
+
+	It("should roll out machines whose certificates expire within certificatesExpiryDays", func() {
+		days := int32(30)
+		controlPlane.RCP.Spec.CertificatesExpiryDays = &days
+		controlPlane.Machines["machine-old"].Annotations = map[string]string{
+			clusterv1.MachineCertificatesExpiryDateAnnotation: time.Now().Add(10 * 24 * time.Hour).UTC().Format(time.RFC3339),
+		}
+		expiry := metav1.NewTime(time.Now().Add(300 * 24 * time.Hour))
+		controlPlane.Machines["machine-new"].Status.CertificatesExpiryDate = &expiry
+
+		Expect(controlPlane.MachinesNeedingRollout().Names()).To(ConsistOf("machine-old"))
+		Expect(controlPlane.MachinesRolloutReasons()).To(HaveKeyWithValue("machine-old",
+			ConsistOf(HavePrefix("certificatesExpiryDays: certificates expire at"))))
+		Expect(controlPlane.MachinesWithExpiringCertificates().Names()).To(ConsistOf("machine-old"))
+
+		Expect(*controlPlane.NextCertificatesExpiryRollout()).To(BeTemporally("~", expiry.Add(-30*24*time.Hour), time.Second))
+
+		controlPlane.RCP.Spec.CertificatesExpiryDays = nil
+		Expect(controlPlane.MachinesNeedingRollout()).To(BeEmpty())
+		Expect(controlPlane.MachinesWithExpiringCertificates()).To(BeEmpty())
+		Expect(controlPlane.NextCertificatesExpiryRollout()).To(BeNil())
+	})
+
+	It("should only upgrade in place machines differing by their version", func() {
+		oldVersion := "v1.24.5"
+		controlPlane.Machines["machine-old"].Spec.Version = &oldVersion
+		Expect(controlPlane.MachinesNeedingInPlaceUpgrade().Names()).To(ConsistOf("machine-old"))
+
+		rolloutAfter := metav1.NewTime(time.Now().Add(-24 * time.Hour))
+		controlPlane.RCP.Spec.RolloutAfter = &rolloutAfter
+		Expect(controlPlane.MachinesNeedingRollout().Names()).To(ConsistOf("machine-old"))
+		Expect(controlPlane.MachinesNeedingInPlaceUpgrade()).To(BeEmpty())
+	})
+
+	It("should upgrade in place machines running an older RKE2 release", func() {
+		controlPlane.rke2Configs = map[string]*bootstrapv1.RKE2Config{
+			"machine-old": {Spec: bootstrapv1.RKE2ConfigSpec{AgentConfig: bootstrapv1.RKE2AgentConfig{Version: "v1.24.6+rke2r1"}}},
+			"machine-new": {Spec: bootstrapv1.RKE2ConfigSpec{AgentConfig: bootstrapv1.RKE2AgentConfig{Version: "v1.24.6+rke2r2"}}},
+		}
+		controlPlane.RCP.Spec.AgentConfig.Version = "v1.24.6+rke2r2"
+
+		Expect(controlPlane.MachinesNeedingRollout().Names()).To(ConsistOf("machine-old"))
+		Expect(controlPlane.MachinesNeedingInPlaceUpgrade().Names()).To(ConsistOf("machine-old"))
+	})
+})
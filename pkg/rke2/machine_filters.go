@@ -2,7 +2,10 @@ package rke2
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -16,71 +19,156 @@ import (
 // matchesRCPConfiguration returns a filter to find all machines that matches with RCP config and do not require any rollout.
 // Kubernetes version, infrastructure template, and RKE2Config field need to be equivalent.
 func matchesRCPConfiguration(infraConfigs map[string]*unstructured.Unstructured, machineConfigs map[string]*bootstrapv1.RKE2Config, rcp *controlplanev1.RKE2ControlPlane) func(machine *clusterv1.Machine) bool {
-	return collections.And(
-		matchesKubernetesVersion(rcp.Spec.AgentConfig.Version),
-		matchesRKE2BootstrapConfig(machineConfigs, rcp),
-		matchesTemplateClonedFrom(infraConfigs, rcp),
-	)
+	return func(machine *clusterv1.Machine) bool {
+		return len(rcpConfigurationDiff(infraConfigs, machineConfigs, rcp, machine)) == 0
+	}
+}
+
+// rcpConfigurationDiff returns the reasons why a machine does not match with RCP config, i.e. the Kubernetes version,
+// the RKE2Config fields and the infrastructure template that differ; it is empty when the machine does not require any rollout.
+func rcpConfigurationDiff(infraConfigs map[string]*unstructured.Unstructured, machineConfigs map[string]*bootstrapv1.RKE2Config, rcp *controlplanev1.RKE2ControlPlane, machine *clusterv1.Machine) []string {
+	if machine == nil {
+		return nil
+	}
+
+	reasons := []string{}
+	if reason := versionDiff(machineConfigs, rcp, machine); reason != "" {
+		reasons = append(reasons, reason)
+	}
+	reasons = append(reasons, rke2BootstrapConfigDiff(machineConfigs, rcp, machine)...)
+	if !matchesTemplateClonedFrom(infraConfigs, rcp)(machine) {
+		infraObj := infraConfigs[machine.Name]
+		reasons = append(reasons, fmt.Sprintf("infrastructure template: %s -> %s",
+			infraObj.GetAnnotations()[clusterv1.TemplateClonedFromNameAnnotation], rcp.GetInfrastructureRef().Name))
+	}
+	return reasons
+}
+
+// versionDiff returns why the version of the machine differs from the desired version of the RCP, or an empty string.
+// The Kubernetes version of the machine is compared first, then its RKE2 version, which also differs on a new RKE2
+// release of the same Kubernetes version, e.g. v1.24.6+rke2r1 -> v1.24.6+rke2r2.
+func versionDiff(machineConfigs map[string]*bootstrapv1.RKE2Config, rcp *controlplanev1.RKE2ControlPlane, machine *clusterv1.Machine) string {
+	desiredVersion := rcp.GetDesiredVersion()
+	if !matchesKubernetesVersion(desiredVersion)(machine) {
+		machineVersion := "<none>"
+		if machine.Spec.Version != nil {
+			machineVersion = *machine.Spec.Version
+		}
+		return fmt.Sprintf("version: %s -> %s", machineVersion, desiredVersion)
+	}
+
+	if machineVersion := machineRKE2Version(machineConfigs, machine); machineVersion != "" && desiredVersion != "" &&
+		machineVersion != desiredVersion {
+		return fmt.Sprintf("version: %s -> %s", machineVersion, desiredVersion)
+	}
+	return ""
+}
+
+// machineRKE2Version returns the RKE2 version of a machine: the version it was upgraded to in place if any,
+// or the version of its RKE2Config; it is empty when it is not known.
+func machineRKE2Version(machineConfigs map[string]*bootstrapv1.RKE2Config, machine *clusterv1.Machine) string {
+	if version, ok := machine.GetAnnotations()[controlplanev1.RKE2VersionAnnotation]; ok {
+		return version
+	}
+	if machineConfig, found := machineConfigs[machine.Name]; found {
+		return machineConfig.Spec.AgentConfig.Version
+	}
+	return ""
 }
 
 // matchesRKE2BootstrapConfig checks if machine's RKE2ConfigSpec is equivalent with RCP's RKE2ConfigSpec.
 func matchesRKE2BootstrapConfig(machineConfigs map[string]*bootstrapv1.RKE2Config, rcp *controlplanev1.RKE2ControlPlane) collections.Func {
 	return func(machine *clusterv1.Machine) bool {
+		return len(rke2BootstrapConfigDiff(machineConfigs, rcp, machine)) == 0
+	}
+}
 
-		if machine == nil {
-			return true
-		}
-
-		// Check if RCP and machine RKE2Config matche, if not return
-		if match := matchServerConfig(rcp, machine); !match {
-			return false
-		}
+// rke2BootstrapConfigDiff returns the server config and agent config fields that differ between the machine and the RCP.
+func rke2BootstrapConfigDiff(machineConfigs map[string]*bootstrapv1.RKE2Config, rcp *controlplanev1.RKE2ControlPlane, machine *clusterv1.Machine) []string {
+	if machine == nil {
+		return nil
+	}
 
-		bootstrapRef := machine.Spec.Bootstrap.ConfigRef
-		if bootstrapRef == nil {
-			// Missing bootstrap reference should not be considered as unmatching.
-			// This is a safety precaution to avoid selecting machines that are broken, which in the future should be remediated separately.
-			return true
-		}
+	// Check if RCP and machine RKE2Config matche, if not return
+	if diff := serverConfigDiff(rcp, machine); len(diff) > 0 {
+		return diff
+	}
 
-		machineConfig, found := machineConfigs[machine.Name]
-		if !found {
-			// Return true here because failing to get KubeadmConfig should not be considered as unmatching.
-			// This is a safety precaution to avoid rolling out machines if the client or the api-server is misbehaving.
-			return true
-		}
+	bootstrapRef := machine.Spec.Bootstrap.ConfigRef
+	if bootstrapRef == nil {
+		// Missing bootstrap reference should not be considered as unmatching.
+		// This is a safety precaution to avoid selecting machines that are broken, which in the future should be remediated separately.
+		return nil
+	}
 
-		// Check if RCP AgentConfig and machineBootstrapConfig matches
-		return reflect.DeepEqual(machineConfig.Spec.AgentConfig, rcp.Spec.AgentConfig)
+	machineConfig, found := machineConfigs[machine.Name]
+	if !found {
+		// Return true here because failing to get KubeadmConfig should not be considered as unmatching.
+		// This is a safety precaution to avoid rolling out machines if the client or the api-server is misbehaving.
+		return nil
 	}
+
+	// Check if RCP AgentConfig and machineBootstrapConfig matches, the version being compared separately
+	agentConfig := rcp.Spec.AgentConfig
+	agentConfig.Version = machineConfig.Spec.AgentConfig.Version
+	return fieldsDiff("agentConfig", machineConfig.Spec.AgentConfig, agentConfig)
 }
 
 // matchServerConfig checks if RKE2Configs in the ControlPlane object and the machine annotation match
 func matchServerConfig(rcp *controlplanev1.RKE2ControlPlane, machine *clusterv1.Machine) bool {
+	return len(serverConfigDiff(rcp, machine)) == 0
+}
+
+// serverConfigDiff returns the server config fields that differ between the RCP and the machine annotation.
+func serverConfigDiff(rcp *controlplanev1.RKE2ControlPlane, machine *clusterv1.Machine) []string {
 	machineServerConfigStr, ok := machine.GetAnnotations()[controlplanev1.RKE2ServerConfigurationAnnotation]
 	if !ok {
 		// We don't have enough information to make a decision; don't' trigger a roll out.
-		return true
+		return nil
 	}
 
 	machineServerConfig := &controlplanev1.RKE2ServerConfig{}
 	// RKE2ServerConfig annotation is not correct, need to rollout new machine
 	if err := json.Unmarshal([]byte(machineServerConfigStr), &machineServerConfig); err != nil {
-		return false
+		return []string{fmt.Sprintf("serverConfig: invalid %s annotation", controlplanev1.RKE2ServerConfigurationAnnotation)}
 	}
 
 	if machineServerConfig == nil {
 		machineServerConfig = &controlplanev1.RKE2ServerConfig{}
 	}
 
-	rcpServerConfig := &rcp.Spec.ServerConfig
-	if rcpServerConfig == nil {
-		rcpServerConfig = &controlplanev1.RKE2ServerConfig{}
-	}
-
 	// Compare and return
-	return reflect.DeepEqual(machineServerConfig, rcpServerConfig)
+	return fieldsDiff("serverConfig", *machineServerConfig, rcp.Spec.ServerConfig)
+}
+
+// fieldsDiff compares the top level fields of two structs of the same type, and returns a description of each
+// field that differs, prefixed by the given path and named after its json tag. Scalar values are included.
+func fieldsDiff(path string, current, desired interface{}) []string {
+	currentValue := reflect.ValueOf(current)
+	desiredValue := reflect.ValueOf(desired)
+
+	diff := []string{}
+	for i := 0; i < currentValue.NumField(); i++ {
+		field := currentValue.Type().Field(i)
+		if !field.IsExported() || reflect.DeepEqual(currentValue.Field(i).Interface(), desiredValue.Field(i).Interface()) {
+			continue
+		}
 
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+
+		switch field.Type.Kind() { //nolint:exhaustive
+		case reflect.String:
+			diff = append(diff, fmt.Sprintf("%s.%s: %q -> %q", path, name, currentValue.Field(i).Interface(), desiredValue.Field(i).Interface()))
+		case reflect.Bool, reflect.Int, reflect.Int32, reflect.Int64:
+			diff = append(diff, fmt.Sprintf("%s.%s: %v -> %v", path, name, currentValue.Field(i).Interface(), desiredValue.Field(i).Interface()))
+		default:
+			diff = append(diff, fmt.Sprintf("%s.%s changed", path, name))
+		}
+	}
+	return diff
 }
 
 // matchesTemplateClonedFrom returns a filter to find all machines that match a given RCP infra template.
@@ -106,8 +194,9 @@ func matchesTemplateClonedFrom(infraConfigs map[string]*unstructured.Unstructure
 		}
 
 		// Check if the machine's infrastructure reference has been created from the current RCP infrastructure template.
-		if clonedFromName != rcp.Spec.InfrastructureRef.Name ||
-			clonedFromGroupKind != rcp.Spec.InfrastructureRef.GroupVersionKind().GroupKind().String() {
+		infraRef := rcp.GetInfrastructureRef()
+		if clonedFromName != infraRef.Name ||
+			clonedFromGroupKind != infraRef.GroupVersionKind().GroupKind().String() {
 			return false
 		}
 		return true
@@ -131,3 +220,17 @@ func matchesKubernetesVersion(kubernetesVersion string) func(*clusterv1.Machine)
 		return bsutil.CompareVersions(*machine.Spec.Version, rcpKubeVersion)
 	}
 }
+
+// machineCertificatesExpiry returns the expiry of the machine certificates, as recorded in the certificates-expiry
+// annotation by the control plane controller, or nil if it is not known yet.
+func machineCertificatesExpiry(machine *clusterv1.Machine) *time.Time {
+	if value, ok := machine.Annotations[clusterv1.MachineCertificatesExpiryDateAnnotation]; ok {
+		if expiry, err := time.Parse(time.RFC3339, value); err == nil {
+			return &expiry
+		}
+	}
+	if machine.Status.CertificatesExpiryDate != nil {
+		return &machine.Status.CertificatesExpiryDate.Time
+	}
+	return nil
+}
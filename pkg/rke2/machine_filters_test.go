@@ -99,3 +99,100 @@ var _ = Describe("maching Kubernetes Version", func() {
 		Expect(len(matches)).To(Equal(1))
 	})
 })
+
+var _ = Describe("matching the version set by the topology controller", func() {
+	It("should use spec.version over the agent config version", func() {
+		topologyRCP := rcp.DeepCopy()
+		topologyRCP.Spec.Version = "v1.25.6+rke2r1"
+
+		machineCollection := collections.FromMachines(&machine)
+		Expect(machineCollection.AnyFilter(matchesKubernetesVersion(topologyRCP.GetDesiredVersion()))).To(BeEmpty())
+
+		topologyRCP.Spec.Version = "v1.24.6+rke2r1"
+		topologyRCP.Spec.AgentConfig.Version = ""
+		Expect(machineCollection.AnyFilter(matchesKubernetesVersion(topologyRCP.GetDesiredVersion()))).To(HaveLen(1))
+	})
+
+	It("should not compare the agent config version", func() {
+		topologyRCP := rcp.DeepCopy()
+		topologyRCP.Spec.Version = "v1.24.6+rke2r1"
+		topologyRCP.Spec.AgentConfig.Version = ""
+
+		machineConfigs := map[string]*bootstrapv1.RKE2Config{
+			"machine-test": {
+				Spec: bootstrapv1.RKE2ConfigSpec{
+					AgentConfig: bootstrapv1.RKE2AgentConfig{
+						Version:    "v1.24.6+rke2r1",
+						NodeLabels: []string{"hello=world"},
+					},
+				},
+			},
+		}
+		machineCollection := collections.FromMachines(&machine)
+		Expect(machineCollection.AnyFilter(matchesRKE2BootstrapConfig(machineConfigs, topologyRCP))).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("rcpConfigurationDiff", func() {
+	It("should not report any difference for a matching machine", func() {
+		Expect(rcpConfigurationDiff(nil, nil, &rcp, &machine)).To(BeEmpty())
+	})
+
+	It("should report the version and the server config fields that changed", func() {
+		changedRCP := rcp.DeepCopy()
+		changedRCP.Spec.AgentConfig.Version = "v1.25.6+rke2r1"
+		changedRCP.Spec.ServerConfig.CNI = "canal"
+		changedRCP.Spec.ServerConfig.TLSSan = []string{"example.com"}
+
+		Expect(rcpConfigurationDiff(nil, nil, changedRCP, &machine)).To(ConsistOf(
+			"version: v1.24.6 -> v1.25.6+rke2r1",
+			`serverConfig.cni: "calico" -> "canal"`,
+			"serverConfig.tlsSan changed",
+		))
+	})
+
+	It("should report a new RKE2 release of the same Kubernetes version", func() {
+		machineConfigs := map[string]*bootstrapv1.RKE2Config{
+			"machine-test": {
+				Spec: bootstrapv1.RKE2ConfigSpec{
+					AgentConfig: bootstrapv1.RKE2AgentConfig{
+						Version:    "v1.24.6+rke2r1",
+						NodeLabels: []string{"hello=world"},
+					},
+				},
+			},
+		}
+		changedRCP := rcp.DeepCopy()
+		changedRCP.Spec.AgentConfig.Version = "v1.24.6+rke2r2"
+
+		Expect(rcpConfigurationDiff(nil, machineConfigs, changedRCP, &machine)).To(ConsistOf(
+			"version: v1.24.6+rke2r1 -> v1.24.6+rke2r2",
+		))
+
+		// The version a machine was upgraded to in place takes precedence over the version of its RKE2Config.
+		upgraded := machine.DeepCopy()
+		upgraded.Annotations[controlplanev1.RKE2VersionAnnotation] = "v1.24.6+rke2r2"
+		Expect(rcpConfigurationDiff(nil, machineConfigs, changedRCP, upgraded)).To(BeEmpty())
+	})
+
+	It("should report the agent config fields that changed", func() {
+		machineConfigs := map[string]*bootstrapv1.RKE2Config{
+			"machine-test": {
+				Spec: bootstrapv1.RKE2ConfigSpec{
+					AgentConfig: bootstrapv1.RKE2AgentConfig{
+						Version:    "v1.24.6+rke2r1",
+						NodeLabels: []string{"hello=world"},
+					},
+				},
+			},
+		}
+		changedRCP := rcp.DeepCopy()
+		changedRCP.Spec.AgentConfig.NodeLabels = []string{"hello=rke2"}
+		changedRCP.Spec.AgentConfig.Snapshotter = "native"
+
+		Expect(rcpConfigurationDiff(nil, machineConfigs, changedRCP, &machine)).To(ConsistOf(
+			"agentConfig.nodeLabels changed",
+			`agentConfig.snapshotter: "" -> "native"`,
+		))
+	})
+})
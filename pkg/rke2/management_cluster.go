@@ -17,17 +17,31 @@ limitations under the License.
 package rke2
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/client-go/kubernetes/scheme"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/cluster-api/util/certs"
 	"sigs.k8s.io/cluster-api/util/collections"
 
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/secret"
 )
 
 // ManagementCluster defines all behaviors necessary for something to function as a management cluster.
@@ -40,7 +54,17 @@ type ManagementCluster interface {
 
 // Management holds operations on the management cluster.
 type Management struct {
-	Client ctrlclient.Reader
+	Client          ctrlclient.Reader
+	EtcdDialTimeout time.Duration
+
+	etcdClientCertsLock sync.Mutex
+	etcdClientCerts     map[ctrlclient.ObjectKey]etcdClientCert
+}
+
+// etcdClientCert is an etcd client certificate generated for a cluster, along with the etcd CA which signed it.
+type etcdClientCert struct {
+	cert   tls.Certificate
+	caData []byte
 }
 
 // RemoteClusterConnectionError represents a failure to connect to a remote cluster
@@ -83,6 +107,13 @@ func (m *Management) GetMachinesForCluster(ctx context.Context, cluster ctrlclie
 const (
 	// RKE2ControlPlaneControllerName defines the controller used when creating clients.
 	RKE2ControlPlaneControllerName = "rke2-controlplane-controller"
+
+	// etcdServerName is the name the etcd serving certificates are verified against; the etcd pods are reached through
+	// a port-forward, and the serving certificates RKE2 generates are valid for localhost, which etcd listens on.
+	etcdServerName = "localhost"
+
+	// etcdClientCertRenewBefore is how long before its expiry a cached etcd client certificate is regenerated.
+	etcdClientCertRenewBefore = 24 * time.Hour
 )
 
 // GetWorkloadCluster builds a cluster object.
@@ -99,7 +130,131 @@ func (m *Management) GetWorkloadCluster(ctx context.Context, clusterKey ctrlclie
 		return nil, &RemoteClusterConnectionError{Name: clusterKey.String(), Err: err}
 	}
 
-	return &Workload{
-		Client: c,
-	}, nil
+	workload := &Workload{
+		Client:     c,
+		restConfig: restConfig,
+	}
+
+	// Retrieves the etcd CA key pair; clusters created before the provider generated the etcd CA don't have it,
+	// in which case the workload cluster is returned without an etcd client generator.
+	crtData, keyData, err := m.getEtcdCAKeyPair(ctx, clusterKey)
+	if err != nil {
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			return workload, nil
+		}
+		return nil, err
+	}
+
+	clientCert, err := m.getEtcdClientCert(clusterKey, crtData, keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	workload.etcdClientGenerator = NewEtcdClientGenerator(restConfig, newEtcdTLSConfig(crtData, clientCert), m.EtcdDialTimeout)
+
+	return workload, nil
+}
+
+func (m *Management) getEtcdCAKeyPair(ctx context.Context, clusterKey ctrlclient.ObjectKey) ([]byte, []byte, error) {
+	etcdCASecret := &corev1.Secret{}
+	etcdCAObjectKey := ctrlclient.ObjectKey{
+		Namespace: clusterKey.Namespace,
+		Name:      secret.Name(clusterKey.Name, secret.EtcdCA),
+	}
+	if err := m.Client.Get(ctx, etcdCAObjectKey, etcdCASecret); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to get secret; etcd CA bundle %s/%s", etcdCAObjectKey.Namespace, etcdCAObjectKey.Name)
+	}
+	crtData, ok := etcdCASecret.Data[secret.TLSCrtDataName]
+	if !ok {
+		return nil, nil, errors.Errorf("etcd tls crt does not exist for cluster %s/%s", clusterKey.Namespace, clusterKey.Name)
+	}
+	keyData, ok := etcdCASecret.Data[secret.TLSKeyDataName]
+	if !ok {
+		return nil, nil, errors.Errorf("etcd tls key does not exist for cluster %s/%s", clusterKey.Namespace, clusterKey.Name)
+	}
+	return crtData, keyData, nil
+}
+
+// getEtcdClientCert returns the etcd client certificate of a cluster, generating a new one when the etcd CA changed,
+// or when the one generated before is about to expire.
+func (m *Management) getEtcdClientCert(clusterKey ctrlclient.ObjectKey, caCertData, caKeyData []byte) (tls.Certificate, error) {
+	m.etcdClientCertsLock.Lock()
+	defer m.etcdClientCertsLock.Unlock()
+
+	if cached, ok := m.etcdClientCerts[clusterKey]; ok && bytes.Equal(cached.caData, caCertData) &&
+		time.Until(cached.cert.Leaf.NotAfter) > etcdClientCertRenewBefore {
+		return cached.cert, nil
+	}
+
+	clientCert, err := generateClientCert(caCertData, caKeyData)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if m.etcdClientCerts == nil {
+		m.etcdClientCerts = map[ctrlclient.ObjectKey]etcdClientCert{}
+	}
+	m.etcdClientCerts[clusterKey] = etcdClientCert{cert: clientCert, caData: caCertData}
+	return clientCert, nil
+}
+
+// newEtcdTLSConfig returns the TLS configuration of the etcd clients, which verifies the etcd serving certificates
+// against the etcd CA.
+func newEtcdTLSConfig(caCertData []byte, clientCert tls.Certificate) *tls.Config {
+	caPool := x509.NewCertPool()
+	caPool.AppendCertsFromPEM(caCertData)
+	return &tls.Config{
+		RootCAs:      caPool,
+		Certificates: []tls.Certificate{clientCert},
+		ServerName:   etcdServerName,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// generateClientCert generates an etcd client certificate signed by the etcd CA.
+func generateClientCert(caCertEncoded, caKeyEncoded []byte) (tls.Certificate, error) {
+	privKey, err := certs.NewPrivateKey()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	caCert, err := certs.DecodeCertPEM(caCertEncoded)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	caKey, err := certs.DecodePrivateKeyPEM(caKeyEncoded)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	x509Cert, err := newClientCert(caCert, privKey, caKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPair, err := tls.X509KeyPair(certs.EncodeCertPEM(x509Cert), certs.EncodePrivateKeyPEM(privKey))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPair.Leaf = x509Cert
+	return keyPair, nil
+}
+
+func newClientCert(caCert *x509.Certificate, key *rsa.PrivateKey, caKey crypto.Signer) (*x509.Certificate, error) {
+	now := time.Now().UTC()
+
+	tmpl := x509.Certificate{
+		SerialNumber: new(big.Int).SetInt64(0),
+		Subject: pkix.Name{
+			CommonName: "cluster-api.x-k8s.io",
+		},
+		NotBefore:   now.Add(time.Minute * -5),
+		NotAfter:    now.Add(time.Hour * 24 * 365 * 10), // 10 years
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	b, err := x509.CreateCertificate(rand.Reader, &tmpl, caCert, key.Public(), caKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create signed client certificate: %+v", tmpl)
+	}
+
+	c, err := x509.ParseCertificate(b)
+	return c, errors.WithStack(err)
 }
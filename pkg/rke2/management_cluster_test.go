@@ -0,0 +1,148 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/cluster-api/util/certs"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("etcd client TLS configuration", func() {
+	var (
+		caCert     *x509.Certificate
+		caKey      *rsa.PrivateKey
+		caData     []byte
+		clientCert tls.Certificate
+	)
+
+	newCA := func() (*x509.Certificate, *rsa.PrivateKey, []byte) {
+		key, err := certs.NewPrivateKey()
+		Expect(err).ToNot(HaveOccurred())
+		tmpl := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "etcd-server-ca"},
+			NotBefore:             time.Now().Add(-time.Minute),
+			NotAfter:              time.Now().Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+		Expect(err).ToNot(HaveOccurred())
+		cert, err := x509.ParseCertificate(der)
+		Expect(err).ToNot(HaveOccurred())
+		return cert, key, certs.EncodeCertPEM(cert)
+	}
+
+	// newServingCert returns an etcd serving certificate signed by the CA, valid for the given DNS names.
+	newServingCert := func(dnsNames ...string) tls.Certificate {
+		key, err := certs.NewPrivateKey()
+		Expect(err).ToNot(HaveOccurred())
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "etcd-server"},
+			DNSNames:     dnsNames,
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, key.Public(), caKey)
+		Expect(err).ToNot(HaveOccurred())
+		cert, err := tls.X509KeyPair(certs.EncodeCertPEM(&x509.Certificate{Raw: der}), certs.EncodePrivateKeyPEM(key))
+		Expect(err).ToNot(HaveOccurred())
+		return cert
+	}
+
+	// handshake connects to a TLS server with the etcd client TLS configuration.
+	handshake := func(serverCert tls.Certificate) error {
+		listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			MinVersion:   tls.VersionTLS12,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				_ = conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}
+		}()
+
+		conn, err := tls.Dial("tcp", listener.Addr().String(), newEtcdTLSConfig(caData, clientCert))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	BeforeEach(func() {
+		caCert, caKey, caData = newCA()
+
+		var err error
+		clientCert, err = generateClientCert(caData, certs.EncodePrivateKeyPEM(caKey))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should verify the etcd serving certificates", func() {
+		Expect(handshake(newServingCert("localhost", "node-1"))).To(Succeed())
+		Expect(handshake(newServingCert("node-1"))).ToNot(Succeed())
+
+		// A certificate signed by another CA.
+		caCert, caKey, _ = newCA()
+		Expect(handshake(newServingCert("localhost"))).ToNot(Succeed())
+	})
+
+	It("should cache the etcd client certificates", func() {
+		m := &Management{}
+		clusterKey := client.ObjectKey{Namespace: "default", Name: "test"}
+		caKeyData := certs.EncodePrivateKeyPEM(caKey)
+
+		cert, err := m.getEtcdClientCert(clusterKey, caData, caKeyData)
+		Expect(err).ToNot(HaveOccurred())
+		cached, err := m.getEtcdClientCert(clusterKey, caData, caKeyData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached.Certificate).To(Equal(cert.Certificate))
+
+		other, err := m.getEtcdClientCert(client.ObjectKey{Namespace: "default", Name: "other"}, caData, caKeyData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(other.Certificate).ToNot(Equal(cert.Certificate))
+
+		// The certificate is regenerated when it's about to expire.
+		cached.Leaf.NotAfter = time.Now().Add(time.Hour)
+		m.etcdClientCerts[clusterKey] = etcdClientCert{cert: cached, caData: caData}
+		renewed, err := m.getEtcdClientCert(clusterKey, caData, caKeyData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(renewed.Certificate).ToNot(Equal(cert.Certificate))
+
+		// The certificate is regenerated when the etcd CA changes.
+		_, newCAKey, newCAData := newCA()
+		rotated, err := m.getEtcdClientCert(clusterKey, newCAData, certs.EncodePrivateKeyPEM(newCAKey))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rotated.Certificate).ToNot(Equal(renewed.Certificate))
+	})
+})
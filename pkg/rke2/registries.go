@@ -16,6 +16,12 @@ limitations under the License.
 package rke2
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -42,79 +48,248 @@ func GenerateRegistries(rke2ConfigRegistry RKE2ConfigRegistry) (*Registry, []boo
 		}
 	}
 
-	for configName, regConfig := range rke2ConfigRegistry.Registry.Configs {
-		tlsSecret := corev1.Secret{}
-		authSecret := corev1.Secret{}
-		err := rke2ConfigRegistry.Client.Get(
-			rke2ConfigRegistry.Ctx,
-			types.NamespacedName{
-				Name:      regConfig.TLS.TLSConfigSecret.Name,
-				Namespace: regConfig.TLS.TLSConfigSecret.Namespace,
-			},
-			&tlsSecret,
-		)
+	// Configs are processed in a stable order, so that the generated files do not change between reconciliations.
+	configNames := make([]string, 0, len(rke2ConfigRegistry.Registry.Configs))
+	for configName := range rke2ConfigRegistry.Registry.Configs {
+		configNames = append(configNames, configName)
+	}
+	sort.Strings(configNames)
+
+	registry.Configs = make(map[string]RegistryConfig)
+	for _, configName := range configNames {
+		regConfig := rke2ConfigRegistry.Registry.Configs[configName]
 
+		tlsConfig, tlsFiles, err := generateRegistryTLS(rke2ConfigRegistry, configName, regConfig.TLS)
 		if err != nil {
-			rke2ConfigRegistry.Logger.Error(err, "TLS Config Secret for the registry was not found!")
 			return &Registry{}, []bootstrapv1.File{}, err
 		}
+		files = append(files, tlsFiles...)
 
-		for _, secretEntry := range []string{"tls.crt", "tls.key", "ca.crt"} {
-			if tlsSecret.Data[secretEntry] == nil {
-				rke2ConfigRegistry.Logger.Error(err, "TLS Config Secret for the registry is missing entries!", "secret-missing-entry", secretEntry)
-				return &Registry{}, []bootstrapv1.File{}, err
+		authData, err := generateRegistryAuth(rke2ConfigRegistry, configName, regConfig.AuthSecret)
+		if err != nil {
+			return &Registry{}, []bootstrapv1.File{}, err
+		}
+
+		registry.Configs[configName] = RegistryConfig{
+			TLS:  tlsConfig,
+			Auth: authData,
+		}
+	}
+
+	// System images are pulled from the system default registry itself: when credentials are supplied for it,
+	// make sure containerd has a mirror entry for that registry, so that these credentials are used.
+	// Configs may be keyed by a URL or a host with the HTTPS port, so they are compared by host.
+	if host := rke2ConfigRegistry.SystemDefaultRegistry; host != "" {
+		for _, configName := range configNames {
+			if registryHost(configName) != registryHost(host) {
+				continue
+			}
+			if _, ok := registry.Mirrors[host]; !ok {
+				registry.Mirrors[host] = Mirror{
+					Endpoint: []string{"https://" + host},
+				}
 			}
-			files = append(files, bootstrapv1.File{
-				Path:    registryCertsPath + "/" + secretEntry,
-				Content: string(tlsSecret.Data[secretEntry]),
-			})
+			break
 		}
+	}
 
-		err = rke2ConfigRegistry.Client.Get(
-			rke2ConfigRegistry.Ctx,
-			types.NamespacedName{
-				Name:      regConfig.AuthSecret.Name,
-				Namespace: regConfig.AuthSecret.Namespace,
-			},
-			&authSecret,
-		)
+	return registry, files, nil
 
-		if err != nil {
-			rke2ConfigRegistry.Logger.Error(err, "Auth Config Secret for the registry was not found!")
-			return &Registry{}, []bootstrapv1.File{}, err
+}
+
+// generateRegistryTLS generates the TLS configuration of a registry, and the files holding its TLS material.
+// Each registry gets its own directory, as registries may use different CAs and client certificates.
+func generateRegistryTLS(rke2ConfigRegistry RKE2ConfigRegistry, configName string, tls bootstrapv1.TLSConfig) (*TLSConfig, []bootstrapv1.File, error) {
+	if tls.TLSConfigSecret.Name == "" {
+		if !tls.InsecureSkipVerify {
+			return nil, nil, nil
 		}
+		return &TLSConfig{InsecureSkipVerify: true}, nil, nil
+	}
+
+	tlsSecret := corev1.Secret{}
+	if err := rke2ConfigRegistry.Client.Get(
+		rke2ConfigRegistry.Ctx,
+		types.NamespacedName{
+			Name:      tls.TLSConfigSecret.Name,
+			Namespace: tls.TLSConfigSecret.Namespace,
+		},
+		&tlsSecret,
+	); err != nil {
+		rke2ConfigRegistry.Logger.Error(err, "TLS Config Secret for the registry was not found!", "registry", configName)
+		return nil, nil, fmt.Errorf("failed to get TLS config secret %s/%s for registry %s: %w", tls.TLSConfigSecret.Namespace, tls.TLSConfigSecret.Name, configName, err)
+	}
 
-		isBasicAuth := authSecret.Data["username"] != nil && authSecret.Data["password"] != nil
-		isTokenAuth := authSecret.Data["identity-token"] != nil
+	hasCA := tlsSecret.Data["ca.crt"] != nil
+	hasCert := tlsSecret.Data["tls.crt"] != nil
+	hasKey := tlsSecret.Data["tls.key"] != nil
 
-		ok := isBasicAuth || isTokenAuth
+	var missingEntries []string
+	switch {
+	case hasCert && !hasKey:
+		missingEntries = []string{"tls.key"}
+	case hasKey && !hasCert:
+		missingEntries = []string{"tls.crt"}
+	case !hasCA && !hasCert:
+		missingEntries = []string{"ca.crt", "tls.crt", "tls.key"}
+	}
+	if len(missingEntries) > 0 {
+		err := fmt.Errorf("TLS config secret %s/%s for registry %s is missing entries: %s",
+			tls.TLSConfigSecret.Namespace, tls.TLSConfigSecret.Name, configName, strings.Join(missingEntries, ", "))
+		rke2ConfigRegistry.Logger.Error(err, "TLS Config Secret for the registry is missing entries!", "registry", configName, "secret-entries", bsutil.GetMapKeysAsString(tlsSecret.Data))
+		return nil, nil, err
+	}
 
-		if !ok {
-			rke2ConfigRegistry.Logger.Error(err, "Auth Secret for the registry is missing entries! Possible entries are: (\"username\" AND \"password\") OR \"identity-token\" ", "secret-entries", bsutil.GetMapKeysAsString(authSecret.Data))
-			return &Registry{}, []bootstrapv1.File{}, err
+	certsPath := registryCertsPath + "/" + registryDirName(configName)
+	tlsConfig := &TLSConfig{
+		InsecureSkipVerify: tls.InsecureSkipVerify,
+	}
+	files := []bootstrapv1.File{}
+	for _, secretEntry := range []string{"tls.crt", "tls.key", "ca.crt"} {
+		if tlsSecret.Data[secretEntry] == nil {
+			continue
 		}
+		path := certsPath + "/" + secretEntry
+		files = append(files, bootstrapv1.File{
+			Path:    path,
+			Content: string(tlsSecret.Data[secretEntry]),
+		})
+		switch secretEntry {
+		case "tls.crt":
+			tlsConfig.CertFile = path
+		case "tls.key":
+			tlsConfig.KeyFile = path
+		case "ca.crt":
+			tlsConfig.CAFile = path
+		}
+	}
+
+	return tlsConfig, files, nil
+}
+
+// generateRegistryAuth generates the authentication configuration of a registry.
+func generateRegistryAuth(rke2ConfigRegistry RKE2ConfigRegistry, configName string, authSecretRef corev1.ObjectReference) (*AuthConfig, error) {
+	if authSecretRef.Name == "" {
+		return nil, nil
+	}
 
-		authData := &AuthConfig{}
-		if isBasicAuth {
-			authData.Username = string(authSecret.Data["username"])
-			authData.Password = string(authSecret.Data["password"])
+	authSecret := corev1.Secret{}
+	if err := rke2ConfigRegistry.Client.Get(
+		rke2ConfigRegistry.Ctx,
+		types.NamespacedName{
+			Name:      authSecretRef.Name,
+			Namespace: authSecretRef.Namespace,
+		},
+		&authSecret,
+	); err != nil {
+		rke2ConfigRegistry.Logger.Error(err, "Auth Config Secret for the registry was not found!", "registry", configName)
+		return nil, fmt.Errorf("failed to get auth secret %s/%s for registry %s: %w", authSecretRef.Namespace, authSecretRef.Name, configName, err)
+	}
+
+	if dockerConfig, ok := authSecret.Data[corev1.DockerConfigJsonKey]; ok {
+		authData, err := authFromDockerConfigJSON(dockerConfig, configName)
+		if err != nil {
+			err = fmt.Errorf("invalid docker config auth secret %s/%s for registry %s: %w", authSecretRef.Namespace, authSecretRef.Name, configName, err)
+			rke2ConfigRegistry.Logger.Error(err, "Docker config Auth Secret for the registry is invalid!", "registry", configName)
+			return nil, err
 		}
-		if isTokenAuth {
-			authData.IdentityToken = string(authSecret.Data["identity-token"])
+		return authData, nil
+	}
+
+	isBasicAuth := authSecret.Data["username"] != nil && authSecret.Data["password"] != nil
+	isTokenAuth := authSecret.Data["identity-token"] != nil
+
+	if !isBasicAuth && !isTokenAuth {
+		err := fmt.Errorf("auth secret %s/%s for registry %s is missing entries: (username and password) or identity-token", authSecretRef.Namespace, authSecretRef.Name, configName)
+		rke2ConfigRegistry.Logger.Error(err, "Auth Secret for the registry is missing entries! Possible entries are: (\"username\" AND \"password\") OR \"identity-token\" ", "registry", configName, "secret-entries", bsutil.GetMapKeysAsString(authSecret.Data))
+		return nil, err
+	}
+
+	authData := &AuthConfig{}
+	if isBasicAuth {
+		authData.Username = string(authSecret.Data["username"])
+		authData.Password = string(authSecret.Data["password"])
+	}
+	if isTokenAuth {
+		authData.IdentityToken = string(authSecret.Data["identity-token"])
+	}
+
+	return authData, nil
+}
+
+// dockerConfigJSON is the content of a kubernetes.io/dockerconfigjson Secret.
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+// dockerConfigEntry holds the credentials of a registry in a docker config.
+type dockerConfigEntry struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// authFromDockerConfigJSON returns the credentials of the docker config auths entry matching the registry host.
+func authFromDockerConfigJSON(data []byte, configName string) (*AuthConfig, error) {
+	dockerConfig := dockerConfigJSON{}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", corev1.DockerConfigJsonKey, err)
+	}
+
+	host := registryHost(configName)
+	for server, entry := range dockerConfig.Auths {
+		if registryHost(server) != host {
+			continue
 		}
 
-		registry.Configs = make(map[string]RegistryConfig)
-		registry.Configs[configName] = RegistryConfig{
-			TLS: &TLSConfig{
-				InsecureSkipVerify: regConfig.TLS.InsecureSkipVerify,
-				CAFile:             registryCertsPath + "/" + "ca.crt",
-				CertFile:           registryCertsPath + "/" + "tls.crt",
-				KeyFile:            registryCertsPath + "/" + "tls.key",
-			},
-			Auth: authData,
+		authData := &AuthConfig{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			IdentityToken: entry.IdentityToken,
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode auth field of %s entry: %w", server, err)
+			}
+			username, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return nil, fmt.Errorf("auth field of %s entry is not in the username:password format", server)
+			}
+			authData.Username = username
+			authData.Password = password
+		}
+		if (authData.Username == "" || authData.Password == "") && authData.IdentityToken == "" {
+			return nil, fmt.Errorf("%s entry has no credentials", server)
 		}
+		return authData, nil
 	}
 
-	return registry, files, nil
+	return nil, fmt.Errorf("no auths entry found for registry host %s", host)
+}
+
+// registryHost returns the host, and port if any but the default HTTPS one, of a registry name or URL.
+func registryHost(name string) string {
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+	if i := strings.Index(name, "/"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimSuffix(name, ":443")
+	// Docker Hub credentials are usually stored under its legacy index address.
+	if name == "index.docker.io" || name == "registry-1.docker.io" {
+		return "docker.io"
+	}
+	return name
+}
 
+// registryDirName returns the name of the directory holding the TLS files of a registry.
+func registryDirName(configName string) string {
+	name := configName
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+	return strings.NewReplacer("/", "_", ":", "_").Replace(strings.TrimSuffix(name, "/"))
 }
@@ -17,6 +17,7 @@ package rke2
 
 import (
 	"context"
+	"encoding/base64"
 	"strings"
 
 	. "github.com/onsi/ginkgo"
@@ -126,19 +127,225 @@ var _ = Describe("RKE2RegistryConfig", func() {
 			}
 			Expect(found).To(BeTrue())
 			Expect(file.Content).To(Equal(tlsMap[fileNameArray[position]]))
-			Expect(file.Path).To(Equal(registryCertsPath + "/" + fileNameArray[position]))
+			Expect(file.Path).To(Equal(registryCertsPath + "/test-registry/" + fileNameArray[position]))
 		}
 		Expect(len(registryResult.Mirrors["docker.io"].Endpoint)).To(Equal(1))
 		Expect(registryResult.Mirrors["docker.io"].Endpoint[0]).To(Equal("https://test-registry"))
 		Expect(registryResult.Mirrors["docker.io"].Rewrite["/path-test"]).To(Equal("/new-path-test"))
 		Expect(registryResult.Configs["https://test-registry"].Auth.Username).To(Equal("test-username"))
 		Expect(registryResult.Configs["https://test-registry"].Auth.Password).To(Equal("test-password"))
-		Expect(registryResult.Configs["https://test-registry"].TLS.CAFile).To(Equal(registryCertsPath + "/" + "ca.crt"))
-		Expect(registryResult.Configs["https://test-registry"].TLS.CertFile).To(Equal(registryCertsPath + "/" + "tls.crt"))
-		Expect(registryResult.Configs["https://test-registry"].TLS.KeyFile).To(Equal(registryCertsPath + "/" + "tls.key"))
+		Expect(registryResult.Configs["https://test-registry"].TLS.CAFile).To(Equal(registryCertsPath + "/test-registry/ca.crt"))
+		Expect(registryResult.Configs["https://test-registry"].TLS.CertFile).To(Equal(registryCertsPath + "/test-registry/tls.crt"))
+		Expect(registryResult.Configs["https://test-registry"].TLS.KeyFile).To(Equal(registryCertsPath + "/test-registry/tls.key"))
 		Expect(registryResult.Configs["https://test-registry"].TLS.InsecureSkipVerify).To(BeTrue())
 
 	})
+
+	It("should generate TLS files and configuration for each registry", func() {
+		rke2ConfigReg.Registry.Configs = map[string]bootstrapv1.RegistryConfig{
+			"registry-a.example.com": rke2ConfigReg.Registry.Configs["https://test-registry"],
+			"registry-b.example.com:5000": {
+				TLS: bootstrapv1.TLSConfig{
+					TLSConfigSecret: corev1.ObjectReference{
+						Namespace: "test-ns",
+						Name:      "test-ca-secret",
+					},
+				},
+			},
+			"registry-c.example.com": {},
+		}
+		rke2ConfigReg.Client = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-auth-secret",
+					Namespace: "test-ns",
+				},
+				Data: map[string][]byte{
+					"identity-token": []byte("test-token"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-tls-secret",
+					Namespace: "test-ns",
+				},
+				Data: map[string][]byte{
+					"tls.crt": []byte("certificate-test"),
+					"tls.key": []byte("cert-key-test"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-ca-secret",
+					Namespace: "test-ns",
+				},
+				Data: map[string][]byte{
+					"ca.crt": []byte("ca-cert-test"),
+				},
+			},
+		).Build()
+
+		registryResult, files, err := GenerateRegistries(rke2ConfigReg)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(registryResult.Configs).To(HaveLen(3))
+
+		Expect(files).To(HaveLen(3))
+		Expect(files[0].Path).To(Equal(registryCertsPath + "/registry-a.example.com/tls.crt"))
+		Expect(files[1].Path).To(Equal(registryCertsPath + "/registry-a.example.com/tls.key"))
+		Expect(files[2].Path).To(Equal(registryCertsPath + "/registry-b.example.com_5000/ca.crt"))
+		Expect(files[2].Content).To(Equal("ca-cert-test"))
+
+		registryA := registryResult.Configs["registry-a.example.com"]
+		Expect(registryA.Auth.IdentityToken).To(Equal("test-token"))
+		Expect(registryA.TLS.CertFile).To(Equal(files[0].Path))
+		Expect(registryA.TLS.KeyFile).To(Equal(files[1].Path))
+		Expect(registryA.TLS.CAFile).To(BeEmpty())
+
+		registryB := registryResult.Configs["registry-b.example.com:5000"]
+		Expect(registryB.Auth).To(BeNil())
+		Expect(registryB.TLS.CAFile).To(Equal(files[2].Path))
+		Expect(registryB.TLS.CertFile).To(BeEmpty())
+
+		registryC := registryResult.Configs["registry-c.example.com"]
+		Expect(registryC.Auth).To(BeNil())
+		Expect(registryC.TLS).To(BeNil())
+	})
+
+	It("should report the missing TLS secret entries", func() {
+		rke2ConfigReg.Client = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-tls-secret",
+					Namespace: "test-ns",
+				},
+				Data: map[string][]byte{
+					"tls.crt": []byte("certificate-test"),
+				},
+			},
+		).Build()
+
+		_, _, err := GenerateRegistries(rke2ConfigReg)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("test-ns/test-tls-secret"))
+		Expect(err.Error()).To(ContainSubstring("tls.key"))
+	})
+
+	It("should report the missing auth secret", func() {
+		rke2ConfigReg.Client = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-tls-secret",
+					Namespace: "test-ns",
+				},
+				Data: map[string][]byte{
+					"ca.crt": []byte("ca-cert-test"),
+				},
+			},
+		).Build()
+
+		_, _, err := GenerateRegistries(rke2ConfigReg)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("test-ns/test-auth-secret"))
+	})
+
+	It("should use credentials from a dockerconfigjson secret", func() {
+		rke2ConfigReg.Registry.Configs = map[string]bootstrapv1.RegistryConfig{
+			"registry.example.com": {
+				AuthSecret: corev1.ObjectReference{
+					Namespace: "test-ns",
+					Name:      "test-pull-secret",
+				},
+			},
+			"docker.io": {
+				AuthSecret: corev1.ObjectReference{
+					Namespace: "test-ns",
+					Name:      "test-pull-secret",
+				},
+			},
+		}
+		rke2ConfigReg.Client = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pull-secret",
+					Namespace: "test-ns",
+				},
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{` +
+						`"https://registry.example.com/v2/":{"auth":"` + base64.StdEncoding.EncodeToString([]byte("test-username:test:password")) + `"},` +
+						`"https://index.docker.io/v1/":{"username":"hub-username","password":"hub-password"}}}`),
+				},
+			},
+		).Build()
+
+		registryResult, _, err := GenerateRegistries(rke2ConfigReg)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(registryResult.Configs["registry.example.com"].Auth.Username).To(Equal("test-username"))
+		Expect(registryResult.Configs["registry.example.com"].Auth.Password).To(Equal("test:password"))
+		Expect(registryResult.Configs["docker.io"].Auth.Username).To(Equal("hub-username"))
+		Expect(registryResult.Configs["docker.io"].Auth.Password).To(Equal("hub-password"))
+	})
+
+	It("should fail when the dockerconfigjson secret has no entry for the registry", func() {
+		rke2ConfigReg.Registry.Configs = map[string]bootstrapv1.RegistryConfig{
+			"registry.example.com": {
+				AuthSecret: corev1.ObjectReference{
+					Namespace: "test-ns",
+					Name:      "test-pull-secret",
+				},
+			},
+		}
+		rke2ConfigReg.Client = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pull-secret",
+					Namespace: "test-ns",
+				},
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"other.example.com":{"username":"u","password":"p"}}}`),
+				},
+			},
+		).Build()
+
+		_, _, err := GenerateRegistries(rke2ConfigReg)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("registry.example.com"))
+	})
+
+	It("should add a mirror for the system default registry when credentials are supplied", func() {
+		rke2ConfigReg.SystemDefaultRegistry = "registry.example.com"
+		rke2ConfigReg.Registry.Configs = map[string]bootstrapv1.RegistryConfig{
+			"registry.example.com": rke2ConfigReg.Registry.Configs["https://test-registry"],
+		}
+
+		registryResult, _, err := GenerateRegistries(rke2ConfigReg)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(registryResult.Mirrors).To(HaveLen(2))
+		Expect(registryResult.Mirrors["registry.example.com"].Endpoint).To(Equal([]string{"https://registry.example.com"}))
+		Expect(registryResult.Configs["registry.example.com"].Auth.Username).To(Equal("test-username"))
+	})
+
+	It("should add a mirror for the system default registry when credentials are supplied for its URL", func() {
+		rke2ConfigReg.SystemDefaultRegistry = "registry.example.com"
+		config := rke2ConfigReg.Registry.Configs["https://test-registry"]
+		for _, configName := range []string{"https://registry.example.com", "registry.example.com:443", "https://registry.example.com:443/"} {
+			rke2ConfigReg.Registry.Configs = map[string]bootstrapv1.RegistryConfig{configName: config}
+
+			registryResult, _, err := GenerateRegistries(rke2ConfigReg)
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(registryResult.Mirrors["registry.example.com"].Endpoint).To(Equal([]string{"https://registry.example.com"}), configName)
+		}
+	})
+
+	It("should not add a mirror for the system default registry without credentials", func() {
+		rke2ConfigReg.SystemDefaultRegistry = "registry.example.com"
+
+		registryResult, _, err := GenerateRegistries(rke2ConfigReg)
+		Expect(err).To(Not(HaveOccurred()))
+		Expect(registryResult.Mirrors).To(HaveLen(1))
+		Expect(registryResult.Mirrors).ToNot(HaveKey("registry.example.com"))
+	})
 },
 )
 
@@ -53,9 +53,9 @@ type AuthConfig struct {
 
 // TLSConfig contains the CA/Cert/Key used for a registry
 type TLSConfig struct {
-	CAFile             string `toml:"ca_file" yaml:"ca_file"`
-	CertFile           string `toml:"cert_file" yaml:"cert_file"`
-	KeyFile            string `toml:"key_file" yaml:"key_file"`
+	CAFile             string `toml:"ca_file" yaml:"ca_file,omitempty"`
+	CertFile           string `toml:"cert_file" yaml:"cert_file,omitempty"`
+	KeyFile            string `toml:"key_file" yaml:"key_file,omitempty"`
 	InsecureSkipVerify bool   `toml:"insecure_skip_verify" yaml:"insecure_skip_verify"`
 }
 
@@ -80,8 +80,9 @@ type RegistryConfig struct {
 // RKE2ConfigRegistry is a wrapper around the Registry struct to provide
 // the client, context and a logger to the Registry struct
 type RKE2ConfigRegistry struct {
-	Registry bootstrapv1.Registry
-	Client   client.Client
-	Ctx      context.Context
-	Logger   logr.Logger
+	Registry              bootstrapv1.Registry
+	SystemDefaultRegistry string
+	Client                client.Client
+	Ctx                   context.Context
+	Logger                logr.Logger
 }
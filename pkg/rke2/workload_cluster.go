@@ -18,15 +18,22 @@ package rke2
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	bootstrapv1 "github.com/rancher-sandbox/cluster-api-provider-rke2/bootstrap/api/v1alpha1"
 	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/etcd"
+	etcdutil "github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/etcd/util"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/proxy"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/collections"
@@ -35,6 +42,7 @@ import (
 )
 
 const (
+	apiServerPort             = 6443
 	kubeProxyKey              = "kube-proxy"
 	rke2ConfigKey             = "rke2-config"
 	labelNodeRoleControlPlane = "node-role.kubernetes.io/master"
@@ -54,9 +62,15 @@ type WorkloadCluster interface {
 	UpdateEtcdConditions(ctx context.Context, controlPlane *ControlPlane)
 	// Upgrade related tasks.
 
-	//	RemoveEtcdMemberForMachine(ctx context.Context, machine *clusterv1.Machine) error
+	RemoveEtcdMemberForMachine(ctx context.Context, machine *clusterv1.Machine) error
+	ForwardEtcdLeadership(ctx context.Context, machine *clusterv1.Machine, leaderCandidate *clusterv1.Machine) error
+	EtcdMembers(ctx context.Context) ([]string, error)
+	GetAPIServerCertificateExpiry(ctx context.Context, nodeName string) (*time.Time, error)
 
-	//	ForwardEtcdLeadership(ctx context.Context, machine *clusterv1.Machine, leaderCandidate *clusterv1.Machine) error
+	// In-place upgrade related tasks.
+	EnsureUpgradePlan(ctx context.Context, version, registry string, agents bool) error
+	DeleteUpgradePlans(ctx context.Context) error
+	NodeVersions(ctx context.Context) (map[string]string, error)
 	//	AllowBootstrapTokensToGetNodes(ctx context.Context) error
 
 	// State recovery tasks.
@@ -65,8 +79,9 @@ type WorkloadCluster interface {
 
 // Workload defines operations on workload clusters.
 type Workload struct {
-	Client ctrlclient.Client
-	//etcdClientGenerator etcdClientFor
+	Client              ctrlclient.Client
+	restConfig          *rest.Config
+	etcdClientGenerator etcdClientFor
 }
 
 // ClusterStatus holds stats information about the cluster.
@@ -109,6 +124,66 @@ func (w *Workload) ClusterStatus(ctx context.Context) (ClusterStatus, error) {
 	return status, nil
 }
 
+// machineForNode returns the machine corresponding to a node, using the machine NodeRef when set.
+// Machines without a NodeRef yet are matched by the node name derived from the machine name, if any.
+func machineForNode(controlPlane *ControlPlane, nodeName string) *clusterv1.Machine {
+	for _, m := range controlPlane.Machines {
+		if m.Status.NodeRef != nil && m.Status.NodeRef.Name == nodeName {
+			return m
+		}
+	}
+
+	prefix := controlPlane.RCP.Spec.AgentConfig.NodeNamePrefix
+	if prefix == "" || bootstrapv1.IsJinjaExpression(prefix) {
+		return nil
+	}
+	for _, m := range controlPlane.Machines {
+		if m.Status.NodeRef == nil && NodeNameForMachine(prefix, m.Name) == nodeName {
+			return m
+		}
+	}
+	return nil
+}
+
+// GetAPIServerCertificateExpiry returns the expiry of the serving certificate of the kube-apiserver running on the given node,
+// which is renewed together with the other RKE2 leaf certificates of the server.
+func (w *Workload) GetAPIServerCertificateExpiry(ctx context.Context, nodeName string) (*time.Time, error) {
+	p := proxy.Proxy{
+		Kind:       "pods",
+		Namespace:  metav1.NamespaceSystem,
+		KubeConfig: w.restConfig,
+		Port:       apiServerPort,
+	}
+
+	dialer, err := proxy.NewDialer(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get certificate expiry for kube-apiserver on Node/%s: failed to create dialer", nodeName)
+	}
+
+	rawConn, err := dialer.DialContextWithAddr(ctx, staticPodName("kube-apiserver", nodeName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get certificate expiry for kube-apiserver on Node/%s: unable to dial to kube-apiserver", nodeName)
+	}
+
+	// The kube-apiserver is reached through a port-forward, only the expiry of its certificate is of interest here.
+	conn := tls.Client(rawConn, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec
+	if err := conn.HandshakeContext(ctx); err != nil {
+		_ = rawConn.Close()
+		return nil, errors.Wrapf(err, "unable to get certificate expiry for kube-apiserver on Node/%s: TLS handshake with the kube-apiserver failed", nodeName)
+	}
+	defer conn.Close()
+
+	peerCertificates := conn.ConnectionState().PeerCertificates
+	if len(peerCertificates) == 0 {
+		return nil, errors.Errorf("unable to get certificate expiry for kube-apiserver on Node/%s: no peer certificate", nodeName)
+	}
+	return &peerCertificates[0].NotAfter, nil
+}
+
+func staticPodName(component, nodeName string) string {
+	return fmt.Sprintf("%s-%s", component, nodeName)
+}
+
 func hasProvisioningMachine(machines collections.Machines) bool {
 	for _, machine := range machines {
 		if machine.Status.NodeRef == nil {
@@ -128,29 +203,76 @@ func nodeHasUnreachableTaint(node corev1.Node) bool {
 	return false
 }
 
+// staticPodComponent is a control plane component RKE2 runs as a static pod named <component>-<node> on each server.
+type staticPodComponent struct {
+	name      string
+	condition clusterv1.ConditionType
+}
+
+// controlPlaneComponents returns the control plane components RKE2 runs as static pods on servers,
+// and the conditions of the components which are disabled and should not be reported.
+func controlPlaneComponents(rcp *controlplanev1.RKE2ControlPlane) (enabled []staticPodComponent, disabled []clusterv1.ConditionType) {
+	isDisabled := func(component controlplanev1.DisabledKubernetesComponent) bool {
+		for _, c := range rcp.Spec.ServerConfig.DisableComponents.KubernetesComponents {
+			if c == component {
+				return true
+			}
+		}
+		return false
+	}
+
+	enabled = []staticPodComponent{
+		{name: "kube-apiserver", condition: controlplanev1.MachineAPIServerPodHealthyCondition},
+		{name: "kube-controller-manager", condition: controlplanev1.MachineControllerManagerPodHealthyCondition},
+		{name: "etcd", condition: controlplanev1.MachineEtcdPodHealthyCondition},
+	}
+
+	if isDisabled(controlplanev1.Scheduler) {
+		disabled = append(disabled, controlplanev1.MachineSchedulerPodHealthyCondition)
+	} else {
+		enabled = append(enabled, staticPodComponent{name: "kube-scheduler", condition: controlplanev1.MachineSchedulerPodHealthyCondition})
+	}
+
+	// RKE2 only runs its own cloud controller manager when no other cloud provider is configured.
+	cloudProviderName := rcp.Spec.ServerConfig.CloudProviderName
+	if isDisabled(controlplanev1.CloudController) || (cloudProviderName != "" && cloudProviderName != "rke2") {
+		disabled = append(disabled, controlplanev1.MachineCloudControllerManagerPodHealthyCondition)
+	} else {
+		enabled = append(enabled, staticPodComponent{name: "cloud-controller-manager", condition: controlplanev1.MachineCloudControllerManagerPodHealthyCondition})
+	}
+
+	return enabled, disabled
+}
+
 // UpdateAgentConditions is responsible for updating machine conditions reflecting the status of all the control plane
 // components running in a static pod generated by RKE2. This operation is best effort, in the sense that in case
 // of problems in retrieving the pod status, it sets the condition to Unknown state without returning any error.
 func (w *Workload) UpdateAgentConditions(ctx context.Context, controlPlane *ControlPlane) {
+	components, disabledConditions := controlPlaneComponents(controlPlane.RCP)
+
 	allMachinePodConditions := []clusterv1.ConditionType{
 		controlplanev1.MachineAgentHealthyCondition,
 	}
+	for _, component := range components {
+		allMachinePodConditions = append(allMachinePodConditions, component.condition)
+	}
 
-	/** TODO: figure out etcd
-	if controlPlane.IsEtcdManaged() {
-		allMachinePodConditions = append(allMachinePodConditions, controlplanev1.MachineEtcdPodHealthyCondition)
+	// Components that have been disabled are not reported anymore.
+	for i := range controlPlane.Machines {
+		for _, condition := range disabledConditions {
+			conditions.Delete(controlPlane.Machines[i], condition)
+		}
 	}
-	**/
 
 	// NOTE: this fun uses control plane nodes from the workload cluster as a source of truth for the current state.
 	controlPlaneNodes, err := w.getControlPlaneNodes(ctx)
 	if err != nil {
-		//for i := range controlPlane.Machines {
-		//machine := controlPlane.Machines[i]
-		//for _, condition := range allMachinePodConditions {
-		//conditions.MarkUnknown(machine, condition, controlplanev1.PodInspectionFailedReason, "Failed to get the node which is hosting this component")
-		//}
-		//}
+		for i := range controlPlane.Machines {
+			machine := controlPlane.Machines[i]
+			for _, condition := range allMachinePodConditions {
+				conditions.MarkUnknown(machine, condition, controlplanev1.PodInspectionFailedReason, "Failed to get the node which is hosting this component")
+			}
+		}
 		conditions.MarkUnknown(controlPlane.RCP, controlplanev1.ControlPlaneComponentsHealthyCondition, controlplanev1.ControlPlaneComponentsInspectionFailedReason, "Failed to list nodes which are hosting control plane components")
 		return
 	}
@@ -160,13 +282,7 @@ func (w *Workload) UpdateAgentConditions(ctx context.Context, controlPlane *Cont
 
 	for _, node := range controlPlaneNodes.Items {
 		// Search for the machine corresponding to the node.
-		var machine *clusterv1.Machine
-		for _, m := range controlPlane.Machines {
-			if m.Status.NodeRef != nil && m.Status.NodeRef.Name == node.Name {
-				machine = m
-				break
-			}
-		}
+		machine := machineForNode(controlPlane, node.Name)
 
 		// If there is no machine corresponding to a node, determine if this is an error or not.
 		if machine == nil {
@@ -197,29 +313,16 @@ func (w *Workload) UpdateAgentConditions(ctx context.Context, controlPlane *Cont
 			continue
 		}
 
-		targetnode := corev1.Node{}
-		nodeKey := ctrlclient.ObjectKey{
-			Namespace: metav1.NamespaceSystem,
-			Name:      node.Name,
+		// The agent is healthy when it reports the node as ready.
+		if util.IsNodeReady(&node) {
+			conditions.MarkTrue(machine, controlplanev1.MachineAgentHealthyCondition)
+		} else {
+			conditions.MarkFalse(machine, controlplanev1.MachineAgentHealthyCondition, controlplanev1.NodeNotReadyReason, clusterv1.ConditionSeverityWarning, "Node %s is not ready", node.Name)
 		}
 
-		if err := w.Client.Get(ctx, nodeKey, &targetnode); err != nil {
-			// If there is an error getting the Pod, do not set any conditions.
-			if apierrors.IsNotFound(err) {
-				conditions.MarkFalse(machine, controlplanev1.MachineAgentHealthyCondition, controlplanev1.PodMissingReason, clusterv1.ConditionSeverityError, "Node %s is missing", nodeKey.Name)
-
-				return
-			}
-			conditions.MarkUnknown(machine, controlplanev1.MachineAgentHealthyCondition, controlplanev1.PodInspectionFailedReason, "Failed to get node status")
-			return
+		for _, component := range components {
+			w.updateStaticPodCondition(ctx, machine, node, component.name, component.condition)
 		}
-
-		for _, condition := range targetnode.Status.Conditions {
-			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
-				conditions.MarkTrue(machine, controlplanev1.MachineAgentHealthyCondition)
-			}
-		}
-
 	}
 
 	// If there are provisioned machines without corresponding nodes, report this as a failing conditions with SeverityError.
@@ -257,10 +360,136 @@ func (w *Workload) UpdateAgentConditions(ctx context.Context, controlPlane *Cont
 // updateStaticPodCondition is responsible for updating machine conditions reflecting the status of a component running
 // in a static pod generated by RKE2. This operation is best effort, in the sense that in case of problems
 // in retrieving the pod status, it sets the condition to Unknown state without returning any error.
-//func (w *Workload) updateStaticPodCondition(ctx context.Context, machine *clusterv1.Machine, node corev1.Node, component string, staticPodCondition clusterv1.ConditionType) {
-//// not really needed for rke2 so just set true
-//conditions.MarkTrue(machine, staticPodCondition)
-//}
+func (w *Workload) updateStaticPodCondition(ctx context.Context, machine *clusterv1.Machine, node corev1.Node, component string, staticPodCondition clusterv1.ConditionType) {
+	// If node ready is unknown there is a good chance that kubelet is not updating mirror pods, so we consider pod status
+	// to be unknown as well without further investigations.
+	if nodeReadyUnknown(node) {
+		conditions.MarkUnknown(machine, staticPodCondition, controlplanev1.PodInspectionFailedReason, "Node Ready condition is unknown, pod data might be stale")
+		return
+	}
+
+	podKey := ctrlclient.ObjectKey{
+		Namespace: metav1.NamespaceSystem,
+		Name:      staticPodName(component, node.Name),
+	}
+
+	pod := corev1.Pod{}
+	if err := w.Client.Get(ctx, podKey, &pod); err != nil {
+		// If there is an error getting the Pod, do not set any conditions.
+		if apierrors.IsNotFound(err) {
+			conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodMissingReason, clusterv1.ConditionSeverityError, "Pod %s is missing", podKey.Name)
+			return
+		}
+		conditions.MarkUnknown(machine, staticPodCondition, controlplanev1.PodInspectionFailedReason, "Failed to get pod status")
+		return
+	}
+
+	switch pod.Status.Phase {
+	case corev1.PodPending:
+		// PodPending means the pod has been accepted by the system, but one or more of the containers
+		// has not been started. This logic is trying to surface more details about what is happening in this phase.
+
+		// Check if the container is still to be scheduled
+		// NOTE: This should never happen for static pods, however this check is implemented for completeness.
+		if podCondition(pod, corev1.PodScheduled) != corev1.ConditionTrue {
+			conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodProvisioningReason, clusterv1.ConditionSeverityInfo, "Waiting to be scheduled")
+			return
+		}
+
+		// Check if the container is still running init containers
+		// NOTE: As of today there are not init containers in static pods generated by RKE2, however this check is implemented for completeness.
+		if podCondition(pod, corev1.PodInitialized) != corev1.ConditionTrue {
+			conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodProvisioningReason, clusterv1.ConditionSeverityInfo, "Running init containers")
+			return
+		}
+
+		// If there are no error from containers, report provisioning without further details.
+		conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodProvisioningReason, clusterv1.ConditionSeverityInfo, "")
+	case corev1.PodRunning:
+		// PodRunning means the pod has been bound to a node and all of the containers have been started.
+		// At least one container is still running or is in the process of being restarted.
+		// This logic is trying to determine if we are actually running or if we are in an intermediate state
+		// like e.g. a container is retarted.
+
+		// PodReady condition means the pod is able to service requests
+		if podCondition(pod, corev1.PodReady) == corev1.ConditionTrue {
+			conditions.MarkTrue(machine, staticPodCondition)
+			return
+		}
+
+		// Surface wait message from containers.
+		// Exception: Since default "restartPolicy" = "Always", a container that exited with error will be in waiting state (not terminated state)
+		// with "CrashLoopBackOff" reason and its LastTerminationState will be non-nil.
+		var containerWaitingMessages []string
+		terminatedWithError := false
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.LastTerminationState.Terminated != nil && containerStatus.LastTerminationState.Terminated.ExitCode != 0 {
+				terminatedWithError = true
+			}
+			if containerStatus.State.Waiting != nil {
+				containerWaitingMessages = append(containerWaitingMessages, containerStatus.State.Waiting.Reason)
+			}
+		}
+		if len(containerWaitingMessages) > 0 {
+			if terminatedWithError {
+				conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodFailedReason, clusterv1.ConditionSeverityError, strings.Join(containerWaitingMessages, ", "))
+				return
+			}
+			// Note: Some error cases cannot be caught when container state == "Waiting",
+			// e.g., "waiting.reason: ErrImagePull" is an error, but since LastTerminationState does not exist, this cannot be differentiated from "PodProvisioningReason"
+			conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodProvisioningReason, clusterv1.ConditionSeverityInfo, strings.Join(containerWaitingMessages, ", "))
+			return
+		}
+
+		// Surface errors message from containers.
+		var containerTerminatedMessages []string
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.State.Terminated != nil {
+				containerTerminatedMessages = append(containerTerminatedMessages, containerStatus.State.Terminated.Reason)
+			}
+		}
+		if len(containerTerminatedMessages) > 0 {
+			conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodFailedReason, clusterv1.ConditionSeverityError, strings.Join(containerTerminatedMessages, ", "))
+			return
+		}
+
+		// If the pod is not yet ready, most probably it is waiting for startup or readiness probes.
+		// Report this as part of the provisioning process because the corresponding control plane component is not ready yet.
+		conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodProvisioningReason, clusterv1.ConditionSeverityInfo, "Waiting for startup or readiness probes")
+	case corev1.PodSucceeded:
+		// PodSucceeded means that all containers in the pod have voluntarily terminated
+		// with a container exit code of 0, and the system is not going to restart any of these containers.
+		// NOTE: This should never happen for the static pods running control plane components.
+		conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodFailedReason, clusterv1.ConditionSeverityError, "All the containers have been terminated")
+	case corev1.PodFailed:
+		// PodFailed means that all containers in the pod have terminated, and at least one container has
+		// terminated in a failure (exited with a non-zero exit code or was stopped by the system).
+		// NOTE: This should never happen for the static pods running control plane components.
+		conditions.MarkFalse(machine, staticPodCondition, controlplanev1.PodFailedReason, clusterv1.ConditionSeverityError, "All the containers have been terminated")
+	case corev1.PodUnknown:
+		// PodUnknown means that for some reason the state of the pod could not be obtained, typically due
+		// to an error in communicating with the host of the pod.
+		conditions.MarkUnknown(machine, staticPodCondition, controlplanev1.PodInspectionFailedReason, "Pod is reporting unknown status")
+	}
+}
+
+func nodeReadyUnknown(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionUnknown
+		}
+	}
+	return false
+}
+
+func podCondition(pod corev1.Pod, condition corev1.PodConditionType) corev1.ConditionStatus {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == condition {
+			return c.Status
+		}
+	}
+	return corev1.ConditionUnknown
+}
 
 type aggregateFromMachinesToRCPInput struct {
 	controlPlane      *ControlPlane
@@ -352,6 +581,15 @@ func (w *Workload) UpdateEtcdConditions(ctx context.Context, controlPlane *Contr
 }
 
 func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane *ControlPlane) {
+	// Clusters without an etcd CA on the management cluster can't be inspected; don't report etcd health for them.
+	if w.etcdClientGenerator == nil {
+		conditions.MarkUnknown(controlPlane.RCP, controlplanev1.EtcdClusterHealthyCondition, controlplanev1.EtcdClusterInspectionUnavailableReason, "The etcd CA of the cluster is not available, etcd health can't be inspected")
+		for _, m := range controlPlane.Machines {
+			conditions.Delete(m, controlplanev1.MachineEtcdMemberHealthyCondition)
+		}
+		return
+	}
+
 	// NOTE: This methods uses control plane nodes only to get in contact with etcd but then it relies on etcd
 	// as ultimate source of truth for the list of members and for their health.
 	controlPlaneNodes, err := w.getControlPlaneNodes(ctx)
@@ -363,13 +601,18 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 		return
 	}
 
+	// Update conditions for etcd members on the nodes.
+	var (
+		// rcpErrors is used to store errors that can't be reported on any machine.
+		rcpErrors []string
+		// clusterID is used to store and compare the etcd's cluster id.
+		clusterID *uint64
+		// members is used to store the list of etcd members and compare with all the other nodes in the cluster.
+		members []*etcd.Member
+	)
+
 	for _, node := range controlPlaneNodes.Items {
-		var machine *clusterv1.Machine
-		for _, m := range controlPlane.Machines {
-			if m.Status.NodeRef != nil && m.Status.NodeRef.Name == node.Name {
-				machine = m
-			}
-		}
+		machine := machineForNode(controlPlane, node.Name)
 
 		if machine == nil {
 			// If there are machines still provisioning there is the chance that a chance that a node might be linked to a machine soon,
@@ -377,6 +620,7 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 			if hasProvisioningMachine(controlPlane.Machines) {
 				continue
 			}
+			rcpErrors = append(rcpErrors, fmt.Sprintf("Control plane node %s does not have a corresponding machine", node.Name))
 			continue
 		}
 
@@ -386,7 +630,149 @@ func (w *Workload) updateManagedEtcdConditions(ctx context.Context, controlPlane
 			continue
 		}
 
+		// If the node is Unreachable, the etcd member can't be reached either; leave to MHC the decision about the node health.
+		if nodeHasUnreachableTaint(node) {
+			conditions.MarkUnknown(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberInspectionFailedReason, "Node is unreachable")
+			continue
+		}
+
+		currentMembers, err := w.getCurrentEtcdMembers(ctx, machine, node.Name)
+		if err != nil {
+			continue
+		}
+
+		// Check if the list of members IDs reported is the same as all other members.
+		// NOTE: the first member reporting this information is the baseline for this information.
+		if members == nil {
+			members = currentMembers
+		}
+		if !etcdutil.MemberEqual(members, currentMembers) {
+			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "etcd member reports the cluster is composed by members %s, but all previously seen etcd members are reporting %s", etcdutil.MemberNames(currentMembers), etcdutil.MemberNames(members))
+			continue
+		}
+
+		// Retrieve the member and check for alarms.
+		member := etcdutil.MemberForName(currentMembers, node.Name)
+		if member == nil {
+			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberMissingReason, clusterv1.ConditionSeverityError, "etcd member for node %s is not part of the etcd cluster", node.Name)
+			continue
+		}
+		alarmList := []string{}
+		for _, alarm := range member.Alarms {
+			if alarm != etcd.AlarmOK {
+				alarmList = append(alarmList, etcd.AlarmTypeName[alarm])
+			}
+		}
+		if len(alarmList) > 0 {
+			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberAlarmReason, clusterv1.ConditionSeverityError, "Etcd member reports alarms: %s", strings.Join(alarmList, ", "))
+			continue
+		}
+
+		// Check if the member belongs to the same cluster as all other members.
+		// NOTE: the first member reporting this information is the baseline for this information.
+		if clusterID == nil {
+			clusterID = &member.ClusterID
+		}
+		if *clusterID != member.ClusterID {
+			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "etcd member has cluster ID %d, but all previously seen etcd members have cluster ID %d", member.ClusterID, *clusterID)
+			continue
+		}
+
 		conditions.MarkTrue(machine, controlplanev1.MachineEtcdMemberHealthyCondition)
 	}
 
+	// Make sure that the list of etcd members and machines is consistent.
+	orphanErrors := compareMachinesAndMembers(controlPlane, members)
+
+	// Orphan members are reported with their own reason, unless there are other problems to report.
+	unhealthyReason := controlplanev1.EtcdClusterUnhealthyReason
+	if len(orphanErrors) > 0 && len(rcpErrors) == 0 && !hasMachineWithError(controlPlane, controlplanev1.MachineEtcdMemberHealthyCondition) {
+		unhealthyReason = controlplanev1.EtcdClusterOrphanMemberReason
+	}
+
+	// Aggregate components error from machines at RCP level
+	aggregateFromMachinesToRCP(aggregateFromMachinesToRCPInput{
+		controlPlane:      controlPlane,
+		machineConditions: []clusterv1.ConditionType{controlplanev1.MachineEtcdMemberHealthyCondition},
+		rcpErrors:         append(rcpErrors, orphanErrors...),
+		condition:         controlplanev1.EtcdClusterHealthyCondition,
+		unhealthyReason:   unhealthyReason,
+		unknownReason:     controlplanev1.EtcdClusterUnknownReason,
+		note:              "etcd member",
+	})
+}
+
+func (w *Workload) getCurrentEtcdMembers(ctx context.Context, machine *clusterv1.Machine, nodeName string) ([]*etcd.Member, error) {
+	// Create the etcd Client for the etcd Pod scheduled on the Node
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, []string{nodeName})
+	if err != nil {
+		conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnreachableReason, clusterv1.ConditionSeverityError, "Failed to connect to the etcd pod on the %s node: %s", nodeName, err)
+		return nil, errors.Wrapf(err, "failed to get current etcd members: failed to connect to the etcd pod on the %s node", nodeName)
+	}
+	defer etcdClient.Close()
+
+	// While creating a new client, forFirstAvailableNode retrieves the status for the endpoint; check if the endpoint has errors.
+	if len(etcdClient.Errors) > 0 {
+		conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Etcd member status reports errors: %s", strings.Join(etcdClient.Errors, ", "))
+		return nil, errors.Errorf("failed to get current etcd members: etcd member status reports errors: %s", strings.Join(etcdClient.Errors, ", "))
+	}
+
+	// Gets the list etcd members known by this member.
+	currentMembers, err := etcdClient.Members(ctx)
+	if err != nil {
+		// NB. We should never be in here, given that we just received answer to the etcd calls included in forFirstAvailableNode;
+		// however, we are considering the calls to Members a signal of etcd not being stable.
+		conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberUnhealthyReason, clusterv1.ConditionSeverityError, "Failed get answer from the etcd member on the %s node", nodeName)
+		return nil, errors.Errorf("failed to get current etcd members: failed get answer from the etcd member on the %s node", nodeName)
+	}
+
+	return currentMembers, nil
+}
+
+// compareMachinesAndMembers marks machines without an etcd member, and returns an error for each etcd member without a machine.
+func compareMachinesAndMembers(controlPlane *ControlPlane, members []*etcd.Member) []string {
+	// NOTE: We run this check only if we actually know the list of members, otherwise the first for loop
+	// could generate a false negative when reporting missing etcd members.
+	if members == nil {
+		return nil
+	}
+
+	// Check Machine -> Etcd member.
+	for _, machine := range controlPlane.Machines {
+		if machine.Status.NodeRef == nil || !machine.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if etcdutil.MemberForName(members, machine.Status.NodeRef.Name) == nil {
+			conditions.MarkFalse(machine, controlplanev1.MachineEtcdMemberHealthyCondition, controlplanev1.EtcdMemberMissingReason, clusterv1.ConditionSeverityError, "Missing etcd member")
+		}
+	}
+
+	// Check Etcd member -> Machine.
+	orphanErrors := []string{}
+	for _, member := range members {
+		// Learners are members still joining the cluster, their machine might not have a node yet.
+		if member.IsLearner {
+			continue
+		}
+		if member.Name != "" && machineForNode(controlPlane, etcdutil.NodeNameForMember(member)) != nil {
+			continue
+		}
+		name := member.Name
+		if name == "" {
+			name = fmt.Sprintf("%d (Name not yet assigned)", member.ID)
+		}
+		orphanErrors = append(orphanErrors, fmt.Sprintf("etcd member %s does not have a corresponding machine", name))
+	}
+	return orphanErrors
+}
+
+// hasMachineWithError returns true if any machine reports the condition as false with error severity.
+func hasMachineWithError(controlPlane *ControlPlane, condition clusterv1.ConditionType) bool {
+	for _, machine := range controlPlane.Machines {
+		if conditions.IsFalse(machine, condition) && conditions.GetSeverity(machine, condition) != nil &&
+			*conditions.GetSeverity(machine, condition) == clusterv1.ConditionSeverityError {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/etcd"
+	etcdutil "github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/etcd/util"
+)
+
+var (
+	// ErrEtcdClientUnavailable is returned when the etcd CA of the cluster is not available on the management cluster,
+	// which is the case for clusters created before the provider started to generate it.
+	ErrEtcdClientUnavailable = errors.New("etcd CA is not available for the cluster, cannot connect to etcd")
+)
+
+type etcdClientFor interface {
+	forFirstAvailableNode(ctx context.Context, nodeNames []string) (*etcd.Client, error)
+	forLeader(ctx context.Context, nodeNames []string) (*etcd.Client, error)
+}
+
+// RemoveEtcdMemberForMachine removes the etcd member from the target cluster's etcd cluster.
+// Removing the last remaining member of the cluster is not supported.
+func (w *Workload) RemoveEtcdMemberForMachine(ctx context.Context, machine *clusterv1.Machine) error {
+	if machine == nil || machine.Status.NodeRef == nil {
+		// Nothing to do, no node for Machine
+		return nil
+	}
+	return w.removeMemberForNode(ctx, machine.Status.NodeRef.Name)
+}
+
+func (w *Workload) removeMemberForNode(ctx context.Context, name string) error {
+	if w.etcdClientGenerator == nil {
+		return ErrEtcdClientUnavailable
+	}
+
+	controlPlaneNodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(controlPlaneNodes.Items) < 2 {
+		return ErrControlPlaneMinNodes
+	}
+
+	// Exclude node being removed from etcd client node list
+	var remainingNodes []string
+	for _, n := range controlPlaneNodes.Items {
+		if n.Name != name {
+			remainingNodes = append(remainingNodes, n.Name)
+		}
+	}
+	etcdClient, err := w.etcdClientGenerator.forFirstAvailableNode(ctx, remainingNodes)
+	if err != nil {
+		return errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	// List etcd members. This checks that the member is healthy, because the request goes through consensus.
+	members, err := etcdClient.Members(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list etcd members using etcd client")
+	}
+	member := etcdutil.MemberForName(members, name)
+
+	// The member has already been removed, return immediately
+	if member == nil {
+		return nil
+	}
+
+	if err := etcdClient.RemoveMember(ctx, member.ID); err != nil {
+		return errors.Wrap(err, "failed to remove member from etcd")
+	}
+
+	return nil
+}
+
+// ForwardEtcdLeadership forwards etcd leadership to the first follower.
+func (w *Workload) ForwardEtcdLeadership(ctx context.Context, machine *clusterv1.Machine, leaderCandidate *clusterv1.Machine) error {
+	if machine == nil || machine.Status.NodeRef == nil {
+		return nil
+	}
+	if leaderCandidate == nil {
+		return errors.New("leader candidate cannot be nil")
+	}
+	if leaderCandidate.Status.NodeRef == nil {
+		return errors.New("leader has no node reference")
+	}
+	if w.etcdClientGenerator == nil {
+		return ErrEtcdClientUnavailable
+	}
+
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list control plane nodes")
+	}
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	etcdClient, err := w.etcdClientGenerator.forLeader(ctx, nodeNames)
+	if err != nil {
+		return errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	members, err := etcdClient.Members(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list etcd members using etcd client")
+	}
+
+	currentMember := etcdutil.MemberForName(members, machine.Status.NodeRef.Name)
+	if currentMember == nil || currentMember.ID != etcdClient.LeaderID {
+		// nothing to do, this is not the etcd leader
+		return nil
+	}
+
+	// Move the leader to the provided candidate.
+	nextLeader := etcdutil.MemberForName(members, leaderCandidate.Status.NodeRef.Name)
+	if nextLeader == nil {
+		return errors.Errorf("failed to get etcd member from node %q", leaderCandidate.Status.NodeRef.Name)
+	}
+	if err := etcdClient.MoveLeader(ctx, nextLeader.ID); err != nil {
+		return errors.Wrapf(err, "failed to move leader")
+	}
+	return nil
+}
+
+// EtcdMembers returns the names of the nodes hosting the current set of members in an etcd cluster.
+//
+// NOTE: This methods uses control plane machines/nodes only to get in contact with etcd,
+// but then it relies on etcd as ultimate source of truth for the list of members.
+// This is intended to allow informed decisions on actions impacting etcd quorum.
+func (w *Workload) EtcdMembers(ctx context.Context) ([]string, error) {
+	if w.etcdClientGenerator == nil {
+		return nil, ErrEtcdClientUnavailable
+	}
+
+	nodes, err := w.getControlPlaneNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list control plane nodes")
+	}
+	nodeNames := make([]string, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	etcdClient, err := w.etcdClientGenerator.forLeader(ctx, nodeNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create etcd client")
+	}
+	defer etcdClient.Close()
+
+	members, err := etcdClient.Members(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list etcd members using etcd client")
+	}
+
+	names := []string{}
+	for _, member := range members {
+		names = append(names, etcdutil.NodeNameForMember(member))
+	}
+	return names, nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/etcd"
+)
+
+func controlPlaneMachine(name, nodeName string) *clusterv1.Machine {
+	m := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "example",
+		},
+	}
+	if nodeName != "" {
+		m.Status.NodeRef = &corev1.ObjectReference{Name: nodeName}
+	}
+	return m
+}
+
+var _ = Describe("etcd conditions", func() {
+	var controlPlane *ControlPlane
+
+	BeforeEach(func() {
+		controlPlane = &ControlPlane{
+			RCP: &controlplanev1.RKE2ControlPlane{},
+			Machines: collections.FromMachines(
+				controlPlaneMachine("machine-1", "node-1"),
+				controlPlaneMachine("machine-2", "node-2"),
+			),
+		}
+	})
+
+	It("should not report etcd health when the etcd CA is not available", func() {
+		w := &Workload{}
+		conditions.MarkTrue(controlPlane.Machines["machine-1"], controlplanev1.MachineEtcdMemberHealthyCondition)
+
+		w.UpdateEtcdConditions(context.Background(), controlPlane)
+
+		Expect(conditions.GetReason(controlPlane.RCP, controlplanev1.EtcdClusterHealthyCondition)).To(Equal(controlplanev1.EtcdClusterInspectionUnavailableReason))
+		Expect(conditions.Has(controlPlane.Machines["machine-1"], controlplanev1.MachineEtcdMemberHealthyCondition)).To(BeFalse())
+	})
+
+	It("should mark machines without an etcd member", func() {
+		members := []*etcd.Member{
+			{ID: 1, Name: "node-1-3b4d9c2e"},
+		}
+
+		orphans := compareMachinesAndMembers(controlPlane, members)
+
+		Expect(orphans).To(BeEmpty())
+		Expect(conditions.GetReason(controlPlane.Machines["machine-2"], controlplanev1.MachineEtcdMemberHealthyCondition)).To(Equal(controlplanev1.EtcdMemberMissingReason))
+		Expect(conditions.Has(controlPlane.Machines["machine-1"], controlplanev1.MachineEtcdMemberHealthyCondition)).To(BeFalse())
+	})
+
+	It("should report etcd members without a machine", func() {
+		members := []*etcd.Member{
+			{ID: 1, Name: "node-1-3b4d9c2e"},
+			{ID: 2, Name: "node-2-0a1b2c3d"},
+			{ID: 3, Name: "node-3-11223344"},
+			{ID: 4, Name: "node-4-55667788", IsLearner: true},
+		}
+
+		orphans := compareMachinesAndMembers(controlPlane, members)
+
+		Expect(orphans).To(ConsistOf("etcd member node-3-11223344 does not have a corresponding machine"))
+	})
+})
+
+func controlPlaneNode(name string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{labelNodeRoleControlPlane: "true"},
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func staticPod(component, nodeName string, phase corev1.PodPhase, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      staticPodName(component, nodeName),
+			Namespace: metav1.NamespaceSystem,
+		},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+		},
+	}
+}
+
+var _ = Describe("control plane components conditions", func() {
+	var controlPlane *ControlPlane
+
+	BeforeEach(func() {
+		controlPlane = &ControlPlane{
+			RCP:      &controlplanev1.RKE2ControlPlane{},
+			Machines: collections.FromMachines(controlPlaneMachine("machine-1", "node-1")),
+		}
+	})
+
+	It("should report the status of the static pods of each control plane component", func() {
+		w := &Workload{Client: fake.NewClientBuilder().WithObjects(
+			controlPlaneNode("node-1"),
+			staticPod("kube-apiserver", "node-1", corev1.PodRunning, corev1.ConditionTrue),
+			staticPod("kube-controller-manager", "node-1", corev1.PodRunning, corev1.ConditionTrue),
+			staticPod("kube-scheduler", "node-1", corev1.PodRunning, corev1.ConditionTrue),
+			staticPod("etcd", "node-1", corev1.PodFailed, corev1.ConditionFalse),
+		).Build()}
+
+		w.UpdateAgentConditions(context.Background(), controlPlane)
+
+		machine := controlPlane.Machines["machine-1"]
+		Expect(conditions.IsTrue(machine, controlplanev1.MachineAgentHealthyCondition)).To(BeTrue())
+		Expect(conditions.IsTrue(machine, controlplanev1.MachineAPIServerPodHealthyCondition)).To(BeTrue())
+		Expect(conditions.IsTrue(machine, controlplanev1.MachineControllerManagerPodHealthyCondition)).To(BeTrue())
+		Expect(conditions.IsTrue(machine, controlplanev1.MachineSchedulerPodHealthyCondition)).To(BeTrue())
+		Expect(conditions.GetReason(machine, controlplanev1.MachineEtcdPodHealthyCondition)).To(Equal(controlplanev1.PodFailedReason))
+		Expect(conditions.GetReason(machine, controlplanev1.MachineCloudControllerManagerPodHealthyCondition)).To(Equal(controlplanev1.PodMissingReason))
+		Expect(conditions.GetReason(controlPlane.RCP, controlplanev1.ControlPlaneComponentsHealthyCondition)).To(Equal(controlplanev1.ControlPlaneComponentsUnhealthyReason))
+	})
+
+	It("should not report the status of disabled components", func() {
+		controlPlane.RCP.Spec.ServerConfig.DisableComponents.KubernetesComponents = []controlplanev1.DisabledKubernetesComponent{
+			controlplanev1.Scheduler,
+			controlplanev1.CloudController,
+		}
+		machine := controlPlane.Machines["machine-1"]
+		conditions.MarkTrue(machine, controlplanev1.MachineSchedulerPodHealthyCondition)
+
+		objs := []client.Object{controlPlaneNode("node-1")}
+		for _, component := range []string{"kube-apiserver", "kube-controller-manager", "etcd"} {
+			objs = append(objs, staticPod(component, "node-1", corev1.PodRunning, corev1.ConditionTrue))
+		}
+		w := &Workload{Client: fake.NewClientBuilder().WithObjects(objs...).Build()}
+
+		w.UpdateAgentConditions(context.Background(), controlPlane)
+
+		Expect(conditions.Has(machine, controlplanev1.MachineSchedulerPodHealthyCondition)).To(BeFalse())
+		Expect(conditions.Has(machine, controlplanev1.MachineCloudControllerManagerPodHealthyCondition)).To(BeFalse())
+		Expect(conditions.IsTrue(controlPlane.RCP, controlplanev1.ControlPlaneComponentsHealthyCondition)).To(BeTrue())
+	})
+
+	It("should not expect the RKE2 cloud controller manager with another cloud provider", func() {
+		controlPlane.RCP.Spec.ServerConfig.CloudProviderName = "aws"
+
+		components, disabled := controlPlaneComponents(controlPlane.RCP)
+
+		Expect(components).To(HaveLen(4))
+		Expect(disabled).To(ConsistOf(controlplanev1.MachineCloudControllerManagerPodHealthyCondition))
+	})
+})
@@ -0,0 +1,155 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// UpgradePlanNamespace is the namespace of the system-upgrade-controller Plans, where the controller is deployed.
+	UpgradePlanNamespace = "system-upgrade"
+
+	// ServerUpgradePlanName is the name of the Plan upgrading the RKE2 servers.
+	ServerUpgradePlanName = "rke2-server-upgrade"
+
+	// AgentUpgradePlanName is the name of the Plan upgrading the RKE2 agents, once the servers are upgraded.
+	AgentUpgradePlanName = "rke2-agent-upgrade"
+
+	upgradePlanServiceAccount = "system-upgrade"
+	rke2UpgradeImage          = "rancher/rke2-upgrade"
+)
+
+var (
+	// ErrUpgradePlansUnavailable is returned when the system-upgrade-controller is not deployed in the workload cluster.
+	ErrUpgradePlansUnavailable = errors.New("system-upgrade-controller Plans are not available in the workload cluster")
+
+	planGVK = schema.GroupVersionKind{Group: "upgrade.cattle.io", Version: "v1", Kind: "Plan"}
+)
+
+// EnsureUpgradePlan creates or updates the system-upgrade-controller Plan upgrading the RKE2 servers, or the agents,
+// to the given version. The agent Plan waits for the server Plan to complete before upgrading a node.
+func (w *Workload) EnsureUpgradePlan(ctx context.Context, version, registry string, agents bool) error {
+	desired := upgradePlan(version, registry, agents)
+
+	plan := &unstructured.Unstructured{}
+	plan.SetGroupVersionKind(planGVK)
+	err := w.Client.Get(ctx, ctrlclient.ObjectKeyFromObject(desired), plan)
+	switch {
+	case apierrors.IsNotFound(err):
+		err = w.Client.Create(ctx, desired)
+	case err == nil:
+		plan.Object["spec"] = desired.Object["spec"]
+		err = w.Client.Update(ctx, plan)
+	}
+
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		// Either the Plan CRD or the system-upgrade namespace is missing.
+		return errors.Wrapf(ErrUpgradePlansUnavailable, "failed to apply Plan %s: %v", desired.GetName(), err)
+	}
+	return errors.Wrapf(err, "failed to apply Plan %s", desired.GetName())
+}
+
+// DeleteUpgradePlans deletes the system-upgrade-controller Plans upgrading RKE2.
+func (w *Workload) DeleteUpgradePlans(ctx context.Context) error {
+	for _, name := range []string{AgentUpgradePlanName, ServerUpgradePlanName} {
+		plan := &unstructured.Unstructured{}
+		plan.SetGroupVersionKind(planGVK)
+		plan.SetNamespace(UpgradePlanNamespace)
+		plan.SetName(name)
+		if err := w.Client.Delete(ctx, plan); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return errors.Wrapf(err, "failed to delete Plan %s", name)
+		}
+	}
+	return nil
+}
+
+// NodeVersions returns the RKE2 version reported by each node, i.e. the kubelet version.
+func (w *Workload) NodeVersions(ctx context.Context) (map[string]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := w.Client.List(ctx, nodes); err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	versions := map[string]string{}
+	for _, node := range nodes.Items {
+		versions[node.Name] = node.Status.NodeInfo.KubeletVersion
+	}
+	return versions, nil
+}
+
+// upgradePlan returns the Plan upgrading the RKE2 servers or agents, as documented for automated RKE2 upgrades.
+func upgradePlan(version, registry string, agents bool) *unstructured.Unstructured {
+	image := rke2UpgradeImage
+	if registry != "" {
+		image = fmt.Sprintf("%s/%s", registry, rke2UpgradeImage)
+	}
+
+	name := ServerUpgradePlanName
+	selector := map[string]interface{}{
+		"key":      labelNodeRoleControlPlane,
+		"operator": "In",
+		"values":   []interface{}{"true"},
+	}
+	spec := map[string]interface{}{
+		"concurrency":        int64(1),
+		"cordon":             true,
+		"serviceAccountName": upgradePlanServiceAccount,
+		"version":            version,
+		"upgrade": map[string]interface{}{
+			"image": image,
+		},
+		// The servers may be tainted, e.g. with CriticalAddonsOnly.
+		"tolerations": []interface{}{
+			map[string]interface{}{"operator": "Exists"},
+		},
+	}
+
+	if agents {
+		name = AgentUpgradePlanName
+		selector = map[string]interface{}{
+			"key":      labelNodeRoleControlPlane,
+			"operator": "DoesNotExist",
+		}
+		spec["prepare"] = map[string]interface{}{
+			"image": image,
+			"args":  []interface{}{"prepare", ServerUpgradePlanName},
+		}
+		spec["drain"] = map[string]interface{}{
+			"force": true,
+		}
+		delete(spec, "tolerations")
+	}
+	spec["nodeSelector"] = map[string]interface{}{
+		"matchExpressions": []interface{}{selector},
+	}
+
+	plan := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	plan.SetGroupVersionKind(planGVK)
+	plan.SetNamespace(UpgradePlanNamespace)
+	plan.SetName(name)
+	return plan
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 SUSE.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rke2
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("in-place upgrade Plans", func() {
+	getPlan := func(w *Workload, name string) *unstructured.Unstructured {
+		plan := &unstructured.Unstructured{}
+		plan.SetGroupVersionKind(planGVK)
+		Expect(w.Client.Get(context.Background(), client.ObjectKey{Namespace: UpgradePlanNamespace, Name: name}, plan)).To(Succeed())
+		return plan
+	}
+	planField := func(plan *unstructured.Unstructured, fields ...string) interface{} {
+		value, found, err := unstructured.NestedFieldCopy(plan.Object, fields...)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		return value
+	}
+
+	It("should create and update the server and agent Plans", func() {
+		w := &Workload{Client: fake.NewClientBuilder().Build()}
+
+		Expect(w.EnsureUpgradePlan(context.Background(), "v1.24.6+rke2r1", "", false)).To(Succeed())
+		Expect(w.EnsureUpgradePlan(context.Background(), "v1.25.3+rke2r1", "registry.example.com", false)).To(Succeed())
+		server := getPlan(w, ServerUpgradePlanName)
+		Expect(planField(server, "spec", "version")).To(Equal("v1.25.3+rke2r1"))
+		Expect(planField(server, "spec", "upgrade", "image")).To(Equal("registry.example.com/rancher/rke2-upgrade"))
+
+		Expect(w.EnsureUpgradePlan(context.Background(), "v1.25.3+rke2r1", "", true)).To(Succeed())
+		agent := getPlan(w, AgentUpgradePlanName)
+		Expect(planField(agent, "spec", "prepare", "args")).To(Equal([]interface{}{"prepare", ServerUpgradePlanName}))
+
+		Expect(w.DeleteUpgradePlans(context.Background())).To(Succeed())
+		Expect(w.DeleteUpgradePlans(context.Background())).To(Succeed())
+	})
+
+	It("should report the version of each node", func() {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.25.3+rke2r1"}},
+		}
+		w := &Workload{Client: fake.NewClientBuilder().WithObjects(node).Build()}
+
+		Expect(w.NodeVersions(context.Background())).To(Equal(map[string]string{"node-1": "v1.25.3+rke2r1"}))
+	})
+})
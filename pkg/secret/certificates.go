@@ -56,6 +56,12 @@ const (
 	// EtcdCA is the secret name suffix for the Etcd CA
 	EtcdCA Purpose = "etcd"
 
+	// EtcdPeerCA is the secret name suffix for the Etcd peer CA.
+	EtcdPeerCA Purpose = "etcd-peer"
+
+	// RequestHeaderCA is the secret name suffix for the request header CA, used by the apiserver to authenticate proxies.
+	RequestHeaderCA Purpose = "request-header-ca"
+
 	// ClusterCA is the secret name suffix for APIServer CA.
 	ClusterCA = Purpose("ca")
 
@@ -80,7 +86,8 @@ type Purpose string
 
 // Certificate represents a single certificate CA.
 type Certificate struct {
-	Generated         bool
+	Generated bool
+	// External is set for certificates provided by the user instead of generated by the provider.
 	External          bool
 	Purpose           Purpose
 	KeyPair           *certs.KeyPair
@@ -105,6 +112,26 @@ func NewCertificatesForInitialControlPlane() Certificates {
 			CertFile: filepath.Join(certificatesDir, "client-ca.crt"),
 			KeyFile:  filepath.Join(certificatesDir, "client-ca.key"),
 		},
+		&Certificate{
+			Purpose:  EtcdCA,
+			CertFile: filepath.Join(certificatesDir, "etcd", "server-ca.crt"),
+			KeyFile:  filepath.Join(certificatesDir, "etcd", "server-ca.key"),
+		},
+		&Certificate{
+			Purpose:  EtcdPeerCA,
+			CertFile: filepath.Join(certificatesDir, "etcd", "peer-ca.crt"),
+			KeyFile:  filepath.Join(certificatesDir, "etcd", "peer-ca.key"),
+		},
+		&Certificate{
+			Purpose:  RequestHeaderCA,
+			CertFile: filepath.Join(certificatesDir, "request-header-ca.crt"),
+			KeyFile:  filepath.Join(certificatesDir, "request-header-ca.key"),
+		},
+		// RKE2 only reads the service account private key, the public key is stored in the secret only.
+		&Certificate{
+			Purpose: ServiceAccount,
+			KeyFile: filepath.Join(certificatesDir, "service.key"),
+		},
 	}
 
 	return certificates
@@ -144,6 +171,15 @@ func (c Certificates) Lookup(ctx context.Context, ctrlclient client.Client, clus
 		if err != nil {
 			return err
 		}
+
+		// Secrets not generated by the provider are provided by the user, e.g. from an enterprise PKI:
+		// they are never overwritten, and must be valid before being used.
+		if IsExternal(s) {
+			certificate.External = true
+			if err := validateExternalKeyPair(certificate.Purpose, kp, time.Now()); err != nil {
+				return errors.WithMessagef(err, "secret %s", key.Name)
+			}
+		}
 		certificate.KeyPair = kp
 	}
 	return nil
@@ -217,6 +253,11 @@ func (c Certificates) LookupOrGenerate(ctx context.Context, ctrlclient client.Cl
 	return nil
 }
 
+// IsExternal returns true for certificate secrets provided by the user, i.e. not generated by the provider.
+func IsExternal(s *corev1.Secret) bool {
+	return metav1.GetControllerOf(s) == nil
+}
+
 // AsSecret converts a single certificate into a Kubernetes secret.
 func (c *Certificate) AsSecret(clusterName client.ObjectKey, owner metav1.OwnerReference) *corev1.Secret {
 	s := &corev1.Secret{
@@ -243,7 +284,10 @@ func (c *Certificate) AsSecret(clusterName client.ObjectKey, owner metav1.OwnerR
 // AsFiles converts the certificate to a slice of Files that may have 0, 1 or 2 Files.
 func (c *Certificate) AsFiles() []bootstrapv1.File {
 	out := make([]bootstrapv1.File, 0)
-	if len(c.KeyPair.Cert) > 0 {
+	if c.KeyPair == nil {
+		return out
+	}
+	if len(c.KeyPair.Cert) > 0 && c.CertFile != "" {
 		out = append(out, bootstrapv1.File{
 			Path:        c.CertFile,
 			Owner:       rootOwnerValue,
@@ -251,7 +295,7 @@ func (c *Certificate) AsFiles() []bootstrapv1.File {
 			Content:     string(c.KeyPair.Cert),
 		})
 	}
-	if len(c.KeyPair.Key) > 0 {
+	if len(c.KeyPair.Key) > 0 && c.KeyFile != "" {
 		out = append(out, bootstrapv1.File{
 			Path:        c.KeyFile,
 			Owner:       rootOwnerValue,
@@ -268,29 +312,13 @@ func Name(cluster string, suffix Purpose) string {
 }
 
 // AsFiles converts a slice of certificates into bootstrap files.
+// Certificates that have not been looked up or generated are skipped, e.g. the CAs that RKE2 generated itself
+// on clusters created before the provider managed them.
 func (c Certificates) AsFiles() []bootstrapv1.File {
-	clusterCA := c.GetByPurpose(ClusterCA)
-	clientClusterCA := c.GetByPurpose(ClientClusterCA)
-
-	etcdCA := c.GetByPurpose(EtcdCA)
-
 	certFiles := make([]bootstrapv1.File, 0)
-	if clusterCA != nil {
-		certFiles = append(certFiles, clusterCA.AsFiles()...)
-	}
-	if clientClusterCA != nil {
-		certFiles = append(certFiles, clientClusterCA.AsFiles()...)
-	}
-	if etcdCA != nil {
-		certFiles = append(certFiles, etcdCA.AsFiles()...)
-	}
-
-	// these will only exist if external etcd was defined and supplied by the user
-	apiserverEtcdClientCert := c.GetByPurpose(APIServerEtcdClient)
-	if apiserverEtcdClientCert != nil {
-		certFiles = append(certFiles, apiserverEtcdClientCert.AsFiles()...)
+	for _, certificate := range c {
+		certFiles = append(certFiles, certificate.AsFiles()...)
 	}
-
 	return certFiles
 }
 
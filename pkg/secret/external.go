@@ -0,0 +1,152 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api/util/certs"
+)
+
+// ErrInvalidExternalCertificate is returned when a certificate provided by the user, e.g. an intermediate CA
+// issued by an enterprise PKI, can't be used by the cluster.
+var ErrInvalidExternalCertificate = errors.New("invalid external certificate")
+
+// caPurposes are the certificates used as certificate authorities by RKE2.
+var caPurposes = map[Purpose]bool{
+	ClusterCA:       true,
+	ClientClusterCA: true,
+	EtcdCA:          true,
+	EtcdPeerCA:      true,
+	RequestHeaderCA: true,
+}
+
+// validateExternalKeyPair checks that a key pair provided by the user can be used for the given purpose.
+// CAs may contain a certificate chain, the first certificate being the CA used to sign certificates, which
+// must be valid at the given time, flagged as a CA allowed to sign certificates, and match the private key.
+func validateExternalKeyPair(purpose Purpose, kp *certs.KeyPair, now time.Time) error {
+	switch {
+	case purpose == ServiceAccount:
+		return validateServiceAccountKeyPair(kp)
+	case caPurposes[purpose]:
+		return validateCAKeyPair(kp, now)
+	default:
+		return nil
+	}
+}
+
+func validateCAKeyPair(kp *certs.KeyPair, now time.Time) error {
+	chain, err := decodeCertChain(kp.Cert)
+	if err != nil {
+		return errors.Wrap(ErrInvalidExternalCertificate, err.Error())
+	}
+	ca := chain[0]
+
+	if !ca.BasicConstraintsValid || !ca.IsCA {
+		return errors.Wrapf(ErrInvalidExternalCertificate, "certificate %q is not a CA", ca.Subject.CommonName)
+	}
+	if ca.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return errors.Wrapf(ErrInvalidExternalCertificate, "certificate %q is not allowed to sign certificates", ca.Subject.CommonName)
+	}
+	if now.Before(ca.NotBefore) || now.After(ca.NotAfter) {
+		return errors.Wrapf(ErrInvalidExternalCertificate, "certificate %q is only valid from %s to %s",
+			ca.Subject.CommonName, ca.NotBefore.Format(time.RFC3339), ca.NotAfter.Format(time.RFC3339))
+	}
+	for i := 1; i < len(chain); i++ {
+		if err := chain[i-1].CheckSignatureFrom(chain[i]); err != nil {
+			return errors.Wrapf(ErrInvalidExternalCertificate, "certificate %q is not signed by the next certificate of the chain %q",
+				chain[i-1].Subject.CommonName, chain[i].Subject.CommonName)
+		}
+	}
+
+	key, err := decodePrivateKey(kp.Key)
+	if err != nil {
+		return err
+	}
+	if !publicKeysEqual(key.Public(), ca.PublicKey) {
+		return errors.Wrapf(ErrInvalidExternalCertificate, "private key does not match certificate %q", ca.Subject.CommonName)
+	}
+	return nil
+}
+
+func validateServiceAccountKeyPair(kp *certs.KeyPair) error {
+	key, err := decodePrivateKey(kp.Key)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(kp.Cert)
+	if block == nil {
+		return errors.Wrap(ErrInvalidExternalCertificate, "unable to decode service account public key")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return errors.Wrapf(ErrInvalidExternalCertificate, "unable to parse service account public key: %v", err)
+	}
+	if !publicKeysEqual(key.Public(), pub) {
+		return errors.Wrap(ErrInvalidExternalCertificate, "service account private key does not match the public key")
+	}
+	return nil
+}
+
+// decodeCertChain decodes all the certificates of a PEM encoded certificate chain.
+func decodeCertChain(encoded []byte) ([]*x509.Certificate, error) {
+	chain := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, encoded = pem.Decode(encoded)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse certificate")
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return chain, nil
+}
+
+func decodePrivateKey(encoded []byte) (crypto.Signer, error) {
+	if len(encoded) == 0 {
+		return nil, errors.Wrap(ErrInvalidExternalCertificate, "private key is missing")
+	}
+	key, err := certs.DecodePrivateKeyPEM(encoded)
+	if err != nil || key == nil {
+		return nil, errors.Wrap(ErrInvalidExternalCertificate, "unable to decode private key")
+	}
+	return key, nil
+}
+
+// publicKeysEqual compares public keys, all the public key types of the standard library implementing Equal.
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	if k, ok := a.(interface{ Equal(crypto.PublicKey) bool }); ok {
+		return k.Equal(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
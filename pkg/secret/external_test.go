@@ -0,0 +1,147 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/certs"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newIntermediateCA returns a key pair with an intermediate CA issued by a root CA, and the chain in the certificate.
+func newIntermediateCA(t *testing.T, tmpl x509.Certificate) *certs.KeyPair {
+	t.Helper()
+	g := NewWithT(t)
+
+	rootCert, rootKey, err := newCertificateAuthority()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	key, err := certs.NewPrivateKey()
+	g.Expect(err).ToNot(HaveOccurred())
+
+	tmpl.SerialNumber = big.NewInt(1)
+	tmpl.Subject = pkix.Name{CommonName: "intermediate"}
+	b, err := x509.CreateCertificate(rand.Reader, &tmpl, rootCert, key.Public(), rootKey)
+	g.Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(b)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	return &certs.KeyPair{
+		Cert: append(certs.EncodeCertPEM(cert), certs.EncodeCertPEM(rootCert)...),
+		Key:  certs.EncodePrivateKeyPEM(key),
+	}
+}
+
+func validIntermediateCATemplate() x509.Certificate {
+	return x509.Certificate{
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+}
+
+func TestValidateExternalCA(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Now()
+
+	generated, err := generateCACert()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(validateExternalKeyPair(ClusterCA, generated, now)).To(Succeed())
+
+	intermediate := newIntermediateCA(t, validIntermediateCATemplate())
+	g.Expect(validateExternalKeyPair(ClusterCA, intermediate, now)).To(Succeed())
+
+	expiredTmpl := validIntermediateCATemplate()
+	expiredTmpl.NotAfter = now.Add(-time.Minute)
+	g.Expect(validateExternalKeyPair(EtcdCA, newIntermediateCA(t, expiredTmpl), now)).To(MatchError(ContainSubstring("is only valid from")))
+
+	leafTmpl := validIntermediateCATemplate()
+	leafTmpl.IsCA = false
+	g.Expect(validateExternalKeyPair(EtcdCA, newIntermediateCA(t, leafTmpl), now)).To(MatchError(ContainSubstring("is not a CA")))
+
+	noCertSignTmpl := validIntermediateCATemplate()
+	noCertSignTmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	g.Expect(validateExternalKeyPair(EtcdCA, newIntermediateCA(t, noCertSignTmpl), now)).To(MatchError(ContainSubstring("not allowed to sign")))
+
+	mismatch := &certs.KeyPair{Cert: intermediate.Cert, Key: generated.Key}
+	err = validateExternalKeyPair(ClientClusterCA, mismatch, now)
+	g.Expect(err).To(MatchError(ContainSubstring("private key does not match")))
+	g.Expect(errors.Is(err, ErrInvalidExternalCertificate)).To(BeTrue())
+
+	noKey := &certs.KeyPair{Cert: intermediate.Cert}
+	g.Expect(validateExternalKeyPair(RequestHeaderCA, noKey, now)).To(MatchError(ContainSubstring("private key is missing")))
+}
+
+func TestValidateExternalServiceAccount(t *testing.T) {
+	g := NewWithT(t)
+
+	sa, err := generateServiceAccountKeys()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(validateExternalKeyPair(ServiceAccount, sa, time.Now())).To(Succeed())
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	g.Expect(err).ToNot(HaveOccurred())
+	mismatch := &certs.KeyPair{Cert: sa.Cert, Key: certs.EncodePrivateKeyPEM(other)}
+	g.Expect(validateExternalKeyPair(ServiceAccount, mismatch, time.Now())).To(MatchError(ContainSubstring("does not match")))
+}
+
+func TestLookupExternalCertificates(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	clusterName := client.ObjectKey{Namespace: "example", Name: "test"}
+
+	generated := &Certificate{Purpose: ClientClusterCA}
+	g.Expect(generated.Generate()).To(Succeed())
+	external := &Certificate{Purpose: ClusterCA, KeyPair: newIntermediateCA(t, validIntermediateCATemplate())}
+
+	c := fake.NewClientBuilder().WithObjects(
+		generated.AsSecret(clusterName, *metav1.NewControllerRef(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "owner"}}, corev1.SchemeGroupVersion.WithKind("Secret"))),
+		external.AsSecret(clusterName, metav1.OwnerReference{}),
+	).Build()
+
+	certificates := NewCertificatesForInitialControlPlane()
+	g.Expect(certificates.LookupOrGenerate(ctx, c, clusterName, metav1.OwnerReference{})).To(Succeed())
+	g.Expect(certificates.GetByPurpose(ClusterCA).External).To(BeTrue())
+	g.Expect(certificates.GetByPurpose(ClusterCA).KeyPair.Cert).To(Equal(external.KeyPair.Cert))
+	g.Expect(certificates.GetByPurpose(ClientClusterCA).External).To(BeFalse())
+	g.Expect(certificates.GetByPurpose(EtcdCA).Generated).To(BeTrue())
+
+	// An invalid external CA is reported, and never overwritten.
+	invalid := &corev1.Secret{}
+	g.Expect(c.Get(ctx, client.ObjectKey{Namespace: "example", Name: Name("test", ClusterCA)}, invalid)).To(Succeed())
+	invalid.Data[TLSKeyDataName] = generated.KeyPair.Key
+	g.Expect(c.Update(ctx, invalid)).To(Succeed())
+
+	err := NewCertificatesForInitialControlPlane().LookupOrGenerate(ctx, c, clusterName, metav1.OwnerReference{})
+	g.Expect(errors.Is(err, ErrInvalidExternalCertificate)).To(BeTrue())
+	g.Expect(err.Error()).To(ContainSubstring(Name("test", ClusterCA)))
+}
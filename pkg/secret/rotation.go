@@ -0,0 +1,141 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// NextTLSCrtDataName is the key used to store the certificate of the CA replacing the current one during a CA rotation.
+	NextTLSCrtDataName = "next-tls.crt"
+
+	// NextTLSKeyDataName is the key used to store the private key of the CA replacing the current one during a CA rotation.
+	NextTLSKeyDataName = "next-tls.key"
+
+	// PreviousTLSCrtDataName is the key used to store the certificate of the CA being replaced during a CA rotation.
+	PreviousTLSCrtDataName = "previous-tls.crt"
+)
+
+// RotatedCAs are the CAs rotated by a cluster CA rotation.
+var RotatedCAs = []Purpose{ClusterCA, ClientClusterCA}
+
+// A CA rotation goes through three steps, each of them followed by the rollout of the control plane machines
+// so every server gets the updated CA files:
+//   - TrustNextCA generates the next CA and adds it to the CA bundle, the current CA still being used for signing.
+//   - SignWithNextCA switches signing to the next CA, the previous CA still being trusted.
+//   - DropPreviousCA removes the previous CA from the CA bundle.
+// Each step is idempotent, so it can be retried until the rotation status is recorded.
+
+// TrustNextCA generates the next CA, and adds it after the current CA in the CA bundle.
+func TrustNextCA(ctx context.Context, c client.Client, clusterName client.ObjectKey, purpose Purpose) error {
+	s, err := getGeneratedCA(ctx, c, clusterName, purpose)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.Data[NextTLSCrtDataName]; ok {
+		return nil
+	}
+
+	next, err := generateCACert()
+	if err != nil {
+		return err
+	}
+
+	current := s.Data[TLSCrtDataName]
+	s.Data[PreviousTLSCrtDataName] = current
+	s.Data[NextTLSCrtDataName] = next.Cert
+	s.Data[NextTLSKeyDataName] = next.Key
+	s.Data[TLSCrtDataName] = bundle(current, next.Cert)
+	return errors.Wrapf(c.Update(ctx, s), "failed to add the next CA to secret %s", s.Name)
+}
+
+// SignWithNextCA makes the next CA the signing CA, i.e. the first of the CA bundle, the previous CA still being trusted.
+func SignWithNextCA(ctx context.Context, c client.Client, clusterName client.ObjectKey, purpose Purpose) error {
+	s, err := getRotatingCA(ctx, c, clusterName, purpose)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(s.Data[TLSKeyDataName], s.Data[NextTLSKeyDataName]) {
+		return nil
+	}
+
+	s.Data[TLSCrtDataName] = bundle(s.Data[NextTLSCrtDataName], s.Data[PreviousTLSCrtDataName])
+	s.Data[TLSKeyDataName] = s.Data[NextTLSKeyDataName]
+	return errors.Wrapf(c.Update(ctx, s), "failed to sign with the next CA in secret %s", s.Name)
+}
+
+// DropPreviousCA removes the previous CA from the CA bundle, completing the rotation.
+func DropPreviousCA(ctx context.Context, c client.Client, clusterName client.ObjectKey, purpose Purpose) error {
+	s, err := getGeneratedCA(ctx, c, clusterName, purpose)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.Data[NextTLSCrtDataName]; !ok {
+		return nil
+	}
+	if !bytes.Equal(s.Data[TLSKeyDataName], s.Data[NextTLSKeyDataName]) {
+		return errors.Errorf("can't drop the previous CA from secret %s, it is still used for signing", s.Name)
+	}
+
+	s.Data[TLSCrtDataName] = s.Data[NextTLSCrtDataName]
+	delete(s.Data, NextTLSCrtDataName)
+	delete(s.Data, NextTLSKeyDataName)
+	delete(s.Data, PreviousTLSCrtDataName)
+	return errors.Wrapf(c.Update(ctx, s), "failed to drop the previous CA from secret %s", s.Name)
+}
+
+// getGeneratedCA returns the secret of a CA generated by the provider; CAs provided by the user are never rotated.
+func getGeneratedCA(ctx context.Context, c client.Client, clusterName client.ObjectKey, purpose Purpose) (*corev1.Secret, error) {
+	s, err := GetFromNamespacedName(ctx, c, clusterName, purpose)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %s CA secret", purpose)
+	}
+	if IsExternal(s) {
+		return nil, errors.Errorf("secret %s contains an external CA, it can't be rotated", s.Name)
+	}
+	if s.Data == nil {
+		s.Data = map[string][]byte{}
+	}
+	return s, nil
+}
+
+func getRotatingCA(ctx context.Context, c client.Client, clusterName client.ObjectKey, purpose Purpose) (*corev1.Secret, error) {
+	s, err := getGeneratedCA(ctx, c, clusterName, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := s.Data[NextTLSKeyDataName]; !ok {
+		return nil, errors.Errorf("secret %s has no next CA, the CA rotation has not been started", s.Name)
+	}
+	return s, nil
+}
+
+// bundle concatenates PEM encoded certificates.
+func bundle(certificates ...[]byte) []byte {
+	out := []byte{}
+	for _, cert := range certificates {
+		out = append(out, bytes.TrimSpace(cert)...)
+		out = append(out, '\n')
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCARotation(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	clusterName := client.ObjectKey{Namespace: "example", Name: "test"}
+	owner := *metav1.NewControllerRef(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "owner"}}, corev1.SchemeGroupVersion.WithKind("Secret"))
+
+	ca := &Certificate{Purpose: ClusterCA}
+	g.Expect(ca.Generate()).To(Succeed())
+	c := fake.NewClientBuilder().WithObjects(ca.AsSecret(clusterName, owner)).Build()
+
+	get := func() *corev1.Secret {
+		s, err := GetFromNamespacedName(ctx, c, clusterName, ClusterCA)
+		g.Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+	bundleCerts := func(s *corev1.Secret) []string {
+		certs, err := certutil.ParseCertsPEM(s.Data[TLSCrtDataName])
+		g.Expect(err).ToNot(HaveOccurred())
+		raw := []string{}
+		for _, cert := range certs {
+			raw = append(raw, string(cert.Raw))
+		}
+		return raw
+	}
+	oldCert := func() string {
+		certs, _ := certutil.ParseCertsPEM(ca.KeyPair.Cert)
+		return string(certs[0].Raw)
+	}()
+
+	g.Expect(SignWithNextCA(ctx, c, clusterName, ClusterCA)).To(MatchError(ContainSubstring("has not been started")))
+
+	// The next CA is trusted, the current one still signs.
+	g.Expect(TrustNextCA(ctx, c, clusterName, ClusterCA)).To(Succeed())
+	s := get()
+	next := s.Data[NextTLSCrtDataName]
+	g.Expect(s.Data[TLSKeyDataName]).To(Equal(ca.KeyPair.Key))
+	g.Expect(bundleCerts(s)).To(HaveLen(2))
+	g.Expect(bundleCerts(s)[0]).To(Equal(oldCert))
+
+	// Steps are idempotent.
+	g.Expect(TrustNextCA(ctx, c, clusterName, ClusterCA)).To(Succeed())
+	g.Expect(get().Data[NextTLSCrtDataName]).To(Equal(next))
+	g.Expect(DropPreviousCA(ctx, c, clusterName, ClusterCA)).To(MatchError(ContainSubstring("still used for signing")))
+
+	// The next CA signs, the previous one is still trusted.
+	g.Expect(SignWithNextCA(ctx, c, clusterName, ClusterCA)).To(Succeed())
+	g.Expect(SignWithNextCA(ctx, c, clusterName, ClusterCA)).To(Succeed())
+	s = get()
+	g.Expect(s.Data[TLSKeyDataName]).To(Equal(s.Data[NextTLSKeyDataName]))
+	g.Expect(bundleCerts(s)).To(HaveLen(2))
+	g.Expect(bundleCerts(s)[1]).To(Equal(oldCert))
+
+	// The previous CA is dropped, only the new one remains.
+	g.Expect(DropPreviousCA(ctx, c, clusterName, ClusterCA)).To(Succeed())
+	g.Expect(DropPreviousCA(ctx, c, clusterName, ClusterCA)).To(Succeed())
+	s = get()
+	g.Expect(bundleCerts(s)).To(HaveLen(1))
+	g.Expect(s.Data[TLSCrtDataName]).To(Equal(next))
+	g.Expect(s.Data).ToNot(HaveKey(NextTLSKeyDataName))
+	g.Expect(s.Data).ToNot(HaveKey(PreviousTLSCrtDataName))
+
+	// The rotated CA is still usable.
+	certificates := Certificates{&Certificate{Purpose: ClusterCA}}
+	g.Expect(certificates.Lookup(ctx, c, clusterName)).To(Succeed())
+}
+
+func TestCARotationExternal(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.Background()
+	clusterName := client.ObjectKey{Namespace: "example", Name: "test"}
+
+	external := &Certificate{Purpose: ClientClusterCA, KeyPair: newIntermediateCA(t, validIntermediateCATemplate())}
+	c := fake.NewClientBuilder().WithObjects(external.AsSecret(clusterName, metav1.OwnerReference{})).Build()
+
+	g.Expect(TrustNextCA(ctx, c, clusterName, ClientClusterCA)).To(MatchError(ContainSubstring("external CA")))
+}
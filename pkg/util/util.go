@@ -21,9 +21,9 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
-	"regexp"
 
 	controlplanev1 "github.com/rancher-sandbox/cluster-api-provider-rke2/controlplane/api/v1alpha1"
+	"github.com/rancher-sandbox/cluster-api-provider-rke2/pkg/util/version"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -87,15 +87,7 @@ func TokenName(clusterName string) string {
 
 // Rke2ToKubeVersion converts an RKE2 version to a Kubernetes version
 func Rke2ToKubeVersion(rk2Version string) (kubeVersion string, err error) {
-	var regexStr string = "v(\\d\\.\\d{2}\\.\\d)\\+rke2r\\d"
-	var regex *regexp.Regexp
-	regex, err = regexp.Compile(regexStr)
-	if err != nil {
-		return "", err
-	}
-	kubeVersion = string(regex.ReplaceAll([]byte(rk2Version), []byte("$1")))
-
-	return kubeVersion, nil
+	return version.Rke2ToKubeVersion(rk2Version)
 }
 
 // AppendIfNotPresent appends a string to a slice only if the value does not already exist
@@ -14,6 +14,15 @@ var _ = Describe("Testing RKE2 to Kubernetes Version conversion", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(cpKubeVersion).To(Equal(machineVersion))
 	})
+	It("Should convert versions with multiple digit patch versions", func() {
+		cpKubeVersion, err := Rke2ToKubeVersion("v1.24.10+rke2r1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cpKubeVersion).To(Equal("1.24.10"))
+	})
+	It("Should fail on malformed RKE2 versions", func() {
+		_, err := Rke2ToKubeVersion("1.24.6")
+		Expect(err).To(HaveOccurred())
+	})
 
 })
 
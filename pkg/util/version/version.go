@@ -0,0 +1,132 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version parses RKE2 versions. It doesn't depend on the API packages, so it can be used by their webhooks.
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+)
+
+var (
+	// rke2VersionRegex matches the RKE2 versions, including the ones of pre-releases, e.g. v1.26.0-rc1+rke2r1.
+	rke2VersionRegex = regexp.MustCompile(`^v(\d+\.\d+\.\d+(?:-[0-9A-Za-z.-]+)?)\+rke2r(\d+)$`)
+
+	// channelRegex matches the names of the RKE2 release channels, e.g. stable, latest or v1.26.
+	channelRegex = regexp.MustCompile(`^([a-z][a-z0-9-]*|v\d+\.\d+)$`)
+
+	// minorChannelRegex matches the names of the release channels of a Kubernetes minor version, e.g. v1.26.
+	minorChannelRegex = regexp.MustCompile(`^v\d+\.\d+$`)
+)
+
+// RKE2Version is a parsed RKE2 version, e.g. v1.24.6+rke2r1 or v1.26.0-rc1+rke2r1.
+type RKE2Version struct {
+	// Kubernetes is the Kubernetes version shipped with the release, e.g. 1.24.6 or 1.26.0-rc1.
+	Kubernetes *utilversion.Version
+
+	// Release is the RKE2 release number for the Kubernetes version, e.g. 1.
+	Release uint64
+}
+
+// ParseRKE2Version parses an RKE2 version, e.g. v1.24.6+rke2r1.
+func ParseRKE2Version(rke2Version string) (*RKE2Version, error) {
+	matches := rke2VersionRegex.FindStringSubmatch(rke2Version)
+	if matches == nil {
+		return nil, errors.Errorf("invalid RKE2 version %q, expected a version like v1.24.6+rke2r1", rke2Version)
+	}
+
+	kubeVersion, err := utilversion.ParseSemantic(matches[1])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid Kubernetes version in RKE2 version %q", rke2Version)
+	}
+	release, err := strconv.ParseUint(matches[2], 10, 64)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid release in RKE2 version %q", rke2Version)
+	}
+
+	return &RKE2Version{Kubernetes: kubeVersion, Release: release}, nil
+}
+
+// String returns the RKE2 version, e.g. v1.24.6+rke2r1.
+func (v *RKE2Version) String() string {
+	return fmt.Sprintf("v%s+rke2r%d", v.Kubernetes, v.Release)
+}
+
+// Compare returns -1, 0 or 1 when the version is respectively older than, equal to or newer than the other one.
+func (v *RKE2Version) Compare(other *RKE2Version) int {
+	switch {
+	case v.Kubernetes.LessThan(other.Kubernetes):
+		return -1
+	case other.Kubernetes.LessThan(v.Kubernetes):
+		return 1
+	case v.Release < other.Release:
+		return -1
+	case v.Release > other.Release:
+		return 1
+	}
+	return 0
+}
+
+// CheckUpgrade returns an error when moving from a version to another one is a downgrade, or skips Kubernetes minor versions.
+func CheckUpgrade(from, to *RKE2Version) error {
+	switch {
+	case to.Compare(from) < 0:
+		return errors.Errorf("downgrading from %s to %s is not supported", from, to)
+	case to.Kubernetes.Major() != from.Kubernetes.Major() || to.Kubernetes.Minor() > from.Kubernetes.Minor()+1:
+		return errors.Errorf("upgrading from %s to %s skips Kubernetes minor versions, upgrade one minor version at a time", from, to)
+	}
+	return nil
+}
+
+// CheckChannelUpgrade returns an error when moving from a version to a release channel of a Kubernetes minor version,
+// e.g. v1.26, is a downgrade or skips Kubernetes minor versions. The versions of the other channels, e.g. stable, are
+// only known once resolved.
+func CheckChannelUpgrade(from *RKE2Version, channel string) error {
+	if !minorChannelRegex.MatchString(channel) {
+		return nil
+	}
+	minor, err := utilversion.ParseGeneric(channel)
+	if err != nil {
+		return errors.Wrapf(err, "invalid release channel %q", channel)
+	}
+
+	switch {
+	case minor.Major() < from.Kubernetes.Major() || minor.Major() == from.Kubernetes.Major() && minor.Minor() < from.Kubernetes.Minor():
+		return errors.Errorf("downgrading from %s to channel %s is not supported", from, channel)
+	case minor.Major() != from.Kubernetes.Major() || minor.Minor() > from.Kubernetes.Minor()+1:
+		return errors.Errorf("upgrading from %s to channel %s skips Kubernetes minor versions, upgrade one minor version at a time", from, channel)
+	}
+	return nil
+}
+
+// IsChannel returns whether a version is the name of a release channel, e.g. stable, latest or v1.26, rather than an RKE2 version.
+func IsChannel(version string) bool {
+	return channelRegex.MatchString(version)
+}
+
+// Rke2ToKubeVersion converts an RKE2 version, e.g. v1.24.6+rke2r1, to a Kubernetes version, e.g. 1.24.6.
+func Rke2ToKubeVersion(rke2Version string) (string, error) {
+	v, err := ParseRKE2Version(rke2Version)
+	if err != nil {
+		return "", err
+	}
+	return v.Kubernetes.String(), nil
+}
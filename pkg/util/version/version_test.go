@@ -0,0 +1,130 @@
+/*
+Copyright 2022 SUSE.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseRKE2Version(t *testing.T) {
+	g := NewWithT(t)
+
+	v, err := ParseRKE2Version("v1.25.3+rke2r12")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(v.Kubernetes.String()).To(Equal("1.25.3"))
+	g.Expect(v.Kubernetes.Minor()).To(Equal(uint(25)))
+	g.Expect(v.Release).To(Equal(uint64(12)))
+
+	v, err = ParseRKE2Version("v1.26.0-rc1+rke2r1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(v.Kubernetes.String()).To(Equal("1.26.0-rc1"))
+	g.Expect(v.Release).To(Equal(uint64(1)))
+	g.Expect(v.String()).To(Equal("v1.26.0-rc1+rke2r1"))
+
+	for _, invalid := range []string{"", "1.25.3+rke2r1", "v1.25.3", "v1.25+rke2r1", "v1.25.3+k3s1", "v1.25.3+rke2r1-rc1"} {
+		_, err := ParseRKE2Version(invalid)
+		g.Expect(err).To(HaveOccurred(), invalid)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.25.3+rke2r1", "v1.25.3+rke2r1", 0},
+		{"v1.25.3+rke2r1", "v1.25.3+rke2r2", -1},
+		{"v1.25.3+rke2r2", "v1.25.3+rke2r1", 1},
+		{"v1.25.3+rke2r2", "v1.25.10+rke2r1", -1},
+		{"v1.26.0+rke2r1", "v1.25.10+rke2r3", 1},
+		{"v1.26.0-rc1+rke2r1", "v1.26.0+rke2r1", -1},
+		{"v1.26.0-rc2+rke2r1", "v1.26.0-rc1+rke2r1", 1},
+	}
+	for _, tt := range tests {
+		a, err := ParseRKE2Version(tt.a)
+		g.Expect(err).NotTo(HaveOccurred())
+		b, err := ParseRKE2Version(tt.b)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(a.Compare(b)).To(Equal(tt.want), "%s vs %s", tt.a, tt.b)
+	}
+}
+
+func TestRke2ToKubeVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	kubeVersion, err := Rke2ToKubeVersion("v1.24.10+rke2r1")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(kubeVersion).To(Equal("1.24.10"))
+
+	_, err = Rke2ToKubeVersion("v1.24.10")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		from, to string
+		valid    bool
+	}{
+		{"v1.25.3+rke2r1", "v1.25.3+rke2r2", true},
+		{"v1.25.3+rke2r1", "v1.26.0+rke2r1", true},
+		{"v1.25.3+rke2r2", "v1.25.3+rke2r1", false},
+		{"v1.25.3+rke2r1", "v1.24.9+rke2r1", false},
+		{"v1.25.3+rke2r1", "v1.27.0+rke2r1", false},
+	}
+	for _, tt := range tests {
+		from, err := ParseRKE2Version(tt.from)
+		g.Expect(err).NotTo(HaveOccurred())
+		to, err := ParseRKE2Version(tt.to)
+		g.Expect(err).NotTo(HaveOccurred())
+		if tt.valid {
+			g.Expect(CheckUpgrade(from, to)).To(Succeed(), "%s to %s", tt.from, tt.to)
+		} else {
+			g.Expect(CheckUpgrade(from, to)).NotTo(Succeed(), "%s to %s", tt.from, tt.to)
+		}
+	}
+}
+
+func TestCheckChannelUpgrade(t *testing.T) {
+	g := NewWithT(t)
+
+	from, err := ParseRKE2Version("v1.25.3+rke2r1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	for _, channel := range []string{"v1.25", "v1.26", "stable", "latest"} {
+		g.Expect(CheckChannelUpgrade(from, channel)).To(Succeed(), channel)
+	}
+	for _, channel := range []string{"v1.24", "v1.27", "v2.0", "v0.26"} {
+		g.Expect(CheckChannelUpgrade(from, channel)).NotTo(Succeed(), channel)
+	}
+}
+
+func TestIsChannel(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, channel := range []string{"stable", "latest", "testing", "v1.26"} {
+		g.Expect(IsChannel(channel)).To(BeTrue(), channel)
+	}
+	for _, notChannel := range []string{"", "v1.26.4+rke2r1", "1.26", "Stable", "v1.26.4"} {
+		g.Expect(IsChannel(notChannel)).To(BeFalse(), notChannel)
+	}
+}